@@ -23,10 +23,33 @@ func init() {
 	app.Description = appCmd.Flags().StringP("desc", "d", "", "Application description")
 	app.Icon = appCmd.Flags().StringP("icon", "c", "", "Application icon")
 	app.Urls = appCmd.PersistentFlags().StringSliceP("url", "u", []string{}, "Accepted Redirect urls for this client")
+	app.Scopes = appCmd.Flags().StringSlice("scope", []string{}, "Scopes this client may request with the client_credentials grant")
+	app.ResponseTypes = appCmd.Flags().StringSlice("response-type", []string{}, "Response types this client may use (code, id_token, \"code id_token\", \"code id_token token\"), default is code")
+	app.LogoutUrls = appCmd.Flags().StringSlice("logout-url", []string{}, "Accepted post logout redirect urls for this client")
 	app.Demo = appCmd.Flags().Bool("demo", false, "Create a demo application")
 	app.Alg = appCmd.Flags().StringP("alg", "l", "RS256", "Algorithm to use for signing tokens")
 	app.KeyID = appCmd.Flags().StringP("kid", "k", "", "Key ID to use for signing tokens")
 	app.Admin = appCmd.Flags().Bool("admin", false, "Make this application an admin application")
 	app.CIBAMode = appCmd.Flags().String("ciba", "poll", "CIBA mode for this client (poll, push, ping)")
 	app.CIBANotificationEndpoint = appCmd.Flags().String("notification", "", "Endpoint to send CIBA notifications")
+	app.BackChannelLogoutURI = appCmd.Flags().String("backchannel-logout", "", "Endpoint to send back-channel logout tokens")
+	app.RequirePAR = appCmd.Flags().Bool("require-par", false, "Only accept pushed authorization requests from this client")
+	app.JWKS = appCmd.Flags().String("jwks", "", "JSON Web Key Set with the public keys of this client")
+	app.JWKSURI = appCmd.Flags().String("jwks-uri", "", "URL of the JSON Web Key Set with the public keys of this client")
+	app.TLSSubjectDN = appCmd.Flags().String("tls-subject", "", "Subject DN of the client certificate (tls_client_auth)")
+	app.TLSThumbprint = appCmd.Flags().String("tls-thumbprint", "", "SHA-256 thumbprint (x5t#S256) of the self-signed client certificate")
+	app.SubjectType = appCmd.Flags().String("subject-type", "public", "Subject identifier type for this client (public, pairwise)")
+	app.SectorIdentifier = appCmd.Flags().String("sector-identifier", "", "Sector identifier host of pairwise subject identifiers (Default is the host of the first redirect url)")
+	app.IDTokenEncryptionAlg = appCmd.Flags().String("id-token-enc-alg", "", "Encrypt ID tokens to the client keys with this key management algorithm (RSA-OAEP, RSA-OAEP-256, ECDH-ES, ECDH-ES+A128KW, ECDH-ES+A256KW)")
+	app.IDTokenEncryptionEnc = appCmd.Flags().String("id-token-enc-enc", "", "Content encryption algorithm of encrypted ID tokens (Default is A128CBC-HS256)")
+	app.UserInfoEncryptionAlg = appCmd.Flags().String("userinfo-enc-alg", "", "Encrypt userinfo responses to the client keys with this key management algorithm")
+	app.UserInfoEncryptionEnc = appCmd.Flags().String("userinfo-enc-enc", "", "Content encryption algorithm of encrypted userinfo responses (Default is A128CBC-HS256)")
+	app.AccessTokenLength = appCmd.Flags().Duration("access-token-length", 0, "How long access tokens of this client are valid (Default is accessToken.length)")
+	app.IDTokenLength = appCmd.Flags().Duration("id-token-length", 0, "How long ID tokens of this client are valid (Default is idToken.length)")
+	app.RefreshTokenLength = appCmd.Flags().Duration("refresh-token-length", 0, "How long refresh tokens of this client are valid (Default is refreshToken.length)")
+	app.Audiences = appCmd.Flags().StringSlice("audience", []string{}, "Extra audiences of the access tokens of this client")
+	app.AccessTokenClaims = appCmd.Flags().StringToString("access-token-claim", map[string]string{}, "Custom claims of the access tokens of this client, name=value where value may be a template like {{.Subject}}")
+	app.IDTokenClaims = appCmd.Flags().StringToString("id-token-claim", map[string]string{}, "Custom claims of the ID tokens of this client, name=value where value may be a template like {{.Subject}}")
+	app.FirstParty = appCmd.Flags().Bool("first-party", false, "The client is operated by the server itself, its users are not asked for consent")
+	app.AuthMethod = appCmd.Flags().String("auth-method", "", "Only accept this client authentication method (client_secret_basic, client_secret_post, client_secret_jwt, private_key_jwt, tls_client_auth, self_signed_tls_client_auth)")
 }
@@ -2,7 +2,12 @@ package app
 
 import (
 	"log/slog"
+	"net/url"
 	"os"
+	"time"
+	"uyulala/internal/api"
+	"uyulala/internal/api/token"
+	"uyulala/internal/db/appdb"
 	"uyulala/internal/db/keydb"
 
 	"github.com/spf13/cobra"
@@ -12,6 +17,9 @@ import (
 
 var (
 	Urls                     *[]string
+	LogoutUrls               *[]string
+	Scopes                   *[]string
+	ResponseTypes            *[]string
 	Description              *string
 	Icon                     *string
 	AppID                    *string
@@ -22,6 +30,26 @@ var (
 	Admin                    *bool
 	CIBAMode                 *string
 	CIBANotificationEndpoint *string
+	BackChannelLogoutURI     *string
+	RequirePAR               *bool
+	JWKS                     *string
+	JWKSURI                  *string
+	AuthMethod               *string
+	TLSSubjectDN             *string
+	TLSThumbprint            *string
+	SubjectType              *string
+	SectorIdentifier         *string
+	IDTokenEncryptionAlg     *string
+	IDTokenEncryptionEnc     *string
+	UserInfoEncryptionAlg    *string
+	UserInfoEncryptionEnc    *string
+	AccessTokenLength        *time.Duration
+	IDTokenLength            *time.Duration
+	RefreshTokenLength       *time.Duration
+	Audiences                *[]string
+	AccessTokenClaims        *map[string]string
+	IDTokenClaims            *map[string]string
+	FirstParty               *bool
 )
 
 func Main(_ *cobra.Command, args []string) {
@@ -48,6 +76,7 @@ func Main(_ *cobra.Command, args []string) {
 		*Urls = []string{}
 		*Alg = "RS256"
 		*Admin = true
+		*FirstParty = true
 		*Urls = append(*Urls,
 			"http://localhost:5173/demo",
 			"https://localhost:5173/demo",
@@ -58,6 +87,11 @@ func Main(_ *cobra.Command, args []string) {
 			"https://oauthdebugger.com/debug",
 			"https://oauth.tools/callback/code",
 		)
+		*LogoutUrls = []string{
+			"https://localhost/demo",
+			"https://localhost:8080/demo",
+			"http://localhost:3000/login",
+		}
 	}
 
 	var kid = *KeyID
@@ -80,8 +114,19 @@ func Main(_ *cobra.Command, args []string) {
 		kid = srvKey.ID
 	}
 
-	res, err := tx.Queryx(`call create_app(?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`, *AppID, *Secret, name, *Description, *Icon,
-		*CIBAMode, CIBANotificationEndpoint, *Alg, kid, *Admin)
+	if *SubjectType == "pairwise" && *SectorIdentifier == "" {
+		for _, uri := range *Urls {
+			if parsed, err := url.Parse(uri); err == nil && parsed.Host != "" {
+				*SectorIdentifier = parsed.Host
+				break
+			}
+		}
+	}
+
+	res, err := tx.Queryx(`call create_app(?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`, *AppID, *Secret, name, *Description, *Icon,
+		*CIBAMode, *CIBANotificationEndpoint, *Alg, kid, *Admin, *BackChannelLogoutURI, *RequirePAR, *JWKS, *JWKSURI,
+		*AuthMethod, *TLSSubjectDN, *TLSThumbprint, *SubjectType, *SectorIdentifier,
+		*IDTokenEncryptionAlg, *IDTokenEncryptionEnc, *UserInfoEncryptionAlg, *UserInfoEncryptionEnc)
 	if err != nil {
 		slog.Error("Create app query", "error", err)
 		_ = tx.Rollback()
@@ -103,6 +148,55 @@ func Main(_ *cobra.Command, args []string) {
 			os.Exit(1)
 		}
 	}
+	for _, url := range *LogoutUrls {
+		if _, err := tx.Exec(`call create_app_post_logout_redirect_url(?, ?)`, appID, url); err != nil {
+			slog.Error("Add post logout redirect url to app", "error", err)
+			_ = tx.Rollback()
+			os.Exit(1)
+		}
+	}
+	for _, scope := range *Scopes {
+		if _, err := tx.Exec(`call create_app_scope(?, ?)`, appID, scope); err != nil {
+			slog.Error("Add scope to app", "error", err)
+			_ = tx.Rollback()
+			os.Exit(1)
+		}
+	}
+	for _, responseType := range *ResponseTypes {
+		if _, err := tx.Exec(`call create_app_response_type(?, ?)`, appID, api.ResponseType(responseType)); err != nil {
+			slog.Error("Add response type to app", "error", err)
+			_ = tx.Rollback()
+			os.Exit(1)
+		}
+	}
+	policy := &appdb.Application{
+		ID:                 appID,
+		AccessTokenLength:  int64(AccessTokenLength.Seconds()),
+		IDTokenLength:      int64(IDTokenLength.Seconds()),
+		RefreshTokenLength: int64(RefreshTokenLength.Seconds()),
+		Audiences:          *Audiences,
+	}
+	for tokenType, claims := range map[string]map[string]string{appdb.TokenAccess: *AccessTokenClaims, appdb.TokenID: *IDTokenClaims} {
+		for name, value := range claims {
+			claim := &appdb.TokenClaim{Token: tokenType, Name: name, Value: value}
+			if err := token.ValidateClaim(claim); err != nil {
+				slog.Error("Invalid token claim", "error", err, "claim", name)
+				_ = tx.Rollback()
+				os.Exit(1)
+			}
+			policy.TokenClaims = append(policy.TokenClaims, claim)
+		}
+	}
+	if err := appdb.SetTokenPolicyTx(tx, policy); err != nil {
+		slog.Error("Set token policy of app", "error", err)
+		_ = tx.Rollback()
+		os.Exit(1)
+	}
+	if err := appdb.SetFirstParty(tx, appID, *FirstParty); err != nil {
+		slog.Error("Set first party app", "error", err)
+		_ = tx.Rollback()
+		os.Exit(1)
+	}
 	err = tx.Commit()
 	if err != nil {
 		slog.Error("Create app error", "error", err)
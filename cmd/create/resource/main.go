@@ -0,0 +1,55 @@
+package resource
+
+import (
+	"log/slog"
+	"os"
+	"time"
+	"uyulala/internal/api/token"
+	"uyulala/internal/db/resourcedb"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"gitlab.com/daedaluz/gindb"
+)
+
+var (
+	Name   *string
+	Scopes *[]string
+	Length *time.Duration
+	KeyID  *string
+)
+
+func Main(_ *cobra.Command, args []string) {
+	id := args[0]
+	if !token.ValidResourceIndicator(id) {
+		slog.Error("The resource indicator must be an absolute uri without fragment", "resource", id)
+		os.Exit(1)
+	}
+	db, err := gindb.Connect("mysql", viper.GetString("database.dsn"))
+	if err != nil {
+		slog.Error("Couldn't connect to database", "error", err)
+		os.Exit(1)
+	}
+
+	tx, err := db.Beginx()
+	if err != nil {
+		slog.Error("Create resource begin", "error", err)
+		os.Exit(1)
+	}
+	if err := resourcedb.CreateTx(tx, &resourcedb.ResourceServer{
+		ID:                id,
+		Name:              *Name,
+		AccessTokenLength: int64(Length.Seconds()),
+		KeyID:             *KeyID,
+		Scopes:            *Scopes,
+	}); err != nil {
+		slog.Error("Create resource query", "error", err)
+		_ = tx.Rollback()
+		os.Exit(1)
+	}
+	if err := tx.Commit(); err != nil {
+		slog.Error("Create resource error", "error", err)
+		os.Exit(1)
+	}
+	slog.Info("Created resource", "resource", id)
+}
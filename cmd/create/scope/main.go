@@ -0,0 +1,52 @@
+package scope
+
+import (
+	"log/slog"
+	"os"
+	"strings"
+	"uyulala/internal/db/scopedb"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"gitlab.com/daedaluz/gindb"
+)
+
+var (
+	Description *string
+	Claims      *[]string
+	Apps        *[]string
+)
+
+func Main(_ *cobra.Command, args []string) {
+	name := args[0]
+	if strings.ContainsAny(name, " \t\r\n\"\\") || name == "openid" || name == "offline_access" {
+		slog.Error("Invalid scope name", "scope", name)
+		os.Exit(1)
+	}
+	db, err := gindb.Connect("mysql", viper.GetString("database.dsn"))
+	if err != nil {
+		slog.Error("Couldn't connect to database", "error", err)
+		os.Exit(1)
+	}
+
+	tx, err := db.Beginx()
+	if err != nil {
+		slog.Error("Create scope begin", "error", err)
+		os.Exit(1)
+	}
+	if err := scopedb.CreateTx(tx, &scopedb.Scope{
+		Name:        name,
+		Description: *Description,
+		Claims:      *Claims,
+		Apps:        *Apps,
+	}); err != nil {
+		slog.Error("Create scope query", "error", err)
+		_ = tx.Rollback()
+		os.Exit(1)
+	}
+	if err := tx.Commit(); err != nil {
+		slog.Error("Create scope error", "error", err)
+		os.Exit(1)
+	}
+	slog.Info("Created scope", "scope", name)
+}
@@ -0,0 +1,24 @@
+package cmd
+
+import (
+	"uyulala/cmd/create/resource"
+
+	"github.com/spf13/cobra"
+)
+
+// resourceCmd represents the resource command
+var resourceCmd = &cobra.Command{
+	Use:   "resource",
+	Short: "Register a new resource server",
+	Long:  `Register a resource server access tokens can be restricted to with its resource indicator (RFC 8707)`,
+	Args:  cobra.ExactArgs(1),
+	Run:   resource.Main,
+}
+
+func init() {
+	createCmd.AddCommand(resourceCmd)
+	resource.Name = resourceCmd.Flags().StringP("name", "n", "", "Resource server name")
+	resource.Scopes = resourceCmd.Flags().StringSlice("scope", []string{}, "Scopes access tokens for this resource server may have")
+	resource.Length = resourceCmd.Flags().Duration("length", 0, "How long access tokens for this resource server are valid (Default is the length of the application)")
+	resource.KeyID = resourceCmd.Flags().StringP("kid", "k", "", "Key ID to sign access tokens for this resource server with (Default is the key of the application)")
+}
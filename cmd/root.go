@@ -69,8 +69,14 @@ func initConfig() {
 	viper.SetDefault("http.cacheControl", "no-cache, no-store, must-revalidate")
 	viper.SetDefault("http.refererPolicy", "origin")
 
+	viper.SetDefault("tls.mtlsAddr", ":8443")
+
 	viper.SetDefault("challenge.maxTimeDiff", "5s")
 
+	viper.SetDefault("notification.timeout", "5s")
+	viper.SetDefault("notification.retries", 5)
+	viper.SetDefault("notification.backoff", "1s")
+
 	viper.AutomaticEnv() // read in environment variables that match
 	// If a config file is found, read it in.
 	if err := viper.ReadInConfig(); err == nil {
@@ -0,0 +1,23 @@
+package cmd
+
+import (
+	"uyulala/cmd/create/scope"
+
+	"github.com/spf13/cobra"
+)
+
+// scopeCmd represents the scope command
+var scopeCmd = &cobra.Command{
+	Use:   "scope",
+	Short: "Register a new scope",
+	Long:  `Register a scope users are asked to consent to when a third party application requests it`,
+	Args:  cobra.ExactArgs(1),
+	Run:   scope.Main,
+}
+
+func init() {
+	createCmd.AddCommand(scopeCmd)
+	scope.Description = scopeCmd.Flags().StringP("desc", "d", "", "Scope description shown to the user")
+	scope.Claims = scopeCmd.Flags().StringSlice("claim", []string{}, "User claims granted by this scope")
+	scope.Apps = scopeCmd.Flags().StringSlice("app", []string{}, "Applications allowed to request this scope (Default is all)")
+}
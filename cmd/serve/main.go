@@ -23,6 +23,9 @@ import (
 	"uyulala/internal/api/v1"
 	"uyulala/internal/db/migrations"
 	"uyulala/internal/mds"
+	"uyulala/internal/mtls"
+	"uyulala/internal/notify"
+	"uyulala/internal/scim"
 	"uyulala/internal/trust"
 	wellknown "uyulala/internal/well-known"
 
@@ -107,7 +110,8 @@ func setupGinEngine(db *sqlx.DB) *gin.Engine {
 		logger(slog.Default()),
 		static.Serve("/", static.LocalFile(viper.GetString("http.staticPath"), true)),
 		gindb.MiddlewareDB(db),
-		gindb.MiddlewareTX(nil),
+		notify.Middleware(),
+		gindb.MiddlewareTX(notify.TxErrorHandler),
 	)
 
 	engine.NoRoute(func(c *gin.Context) {
@@ -132,6 +136,7 @@ func setupGinEngine(db *sqlx.DB) *gin.Engine {
 	})
 	r := engine.Group("/api/v1")
 	v1.AddRoutes(r)
+	scim.AddRoutes(engine.Group("/scim/v2"))
 	return engine
 }
 
@@ -151,6 +156,18 @@ func Main(cmd *cobra.Command, args []string) {
 		}
 	}
 
+	if caFile := viper.GetString("tls.clientCA"); caFile != "" {
+		if err := mtls.Configure(caFile); err != nil {
+			slog.Error("Failed to load client CA", "error", err)
+		} else {
+			slog.Info("Client CA loaded")
+		}
+	}
+
+	if viper.GetString("subject.pairwiseSalt") == "" {
+		slog.Warn("subject.pairwiseSalt is not set, pairwise subject identifiers can be correlated by anyone knowing the user ids")
+	}
+
 	go mds.Init()
 
 	server := &http.Server{
@@ -164,22 +181,36 @@ func Main(cmd *cobra.Command, args []string) {
 		ErrorLog:          slog.NewLogLogger(slog.Default().Handler(), slog.LevelError),
 	}
 
+	var mtlsServer *http.Server
+	if viper.GetBool("tls.enable") {
+		loadCerts(server)
+		if viper.GetBool("tls.clientAuth") {
+			// Client certificates are only requested on a listener of their own, browsers would prompt for one
+			// on the frontend otherwise. Certificates are verified per client, self-signed ones are allowed for
+			// self_signed_tls_client_auth.
+			mtlsServer = &http.Server{
+				Addr:              viper.GetString("tls.mtlsAddr"),
+				Handler:           server.Handler,
+				TLSConfig:         server.TLSConfig.Clone(),
+				ReadTimeout:       server.ReadTimeout,
+				ReadHeaderTimeout: server.ReadHeaderTimeout,
+				WriteTimeout:      server.WriteTimeout,
+				IdleTimeout:       server.IdleTimeout,
+				MaxHeaderBytes:    server.MaxHeaderBytes,
+				ErrorLog:          server.ErrorLog,
+			}
+			mtlsServer.TLSConfig.ClientAuth = tls.RequestClientCert
+		}
+	} else if viper.GetBool("tls.clientAuth") {
+		slog.Warn("tls.clientAuth requires tls.enable, client certificates are not requested")
+	}
+
 	sigch := make(chan os.Signal, 1)
 	signal.Notify(sigch, syscall.SIGINT, syscall.SIGQUIT, syscall.SIGTERM)
 	slog.Info("Starting server")
 	go func() {
 		if viper.GetBool("tls.enable") {
-			loadCerts(server)
-			ln, err := net.Listen("tcp", viper.GetString("http.addr"))
-			if err != nil {
-				slog.Error("Couldn't start TLS server", "error", err)
-				return
-			}
-			if err := server.ServeTLS(ln, "", ""); err != nil {
-				slog.Error("TLS server returned error", "error", err)
-			} else {
-				slog.Info("Byte TLS.")
-			}
+			serveTLS(server)
 		} else {
 			if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
 				slog.Error("Server returned error", "error", err)
@@ -189,10 +220,19 @@ func Main(cmd *cobra.Command, args []string) {
 		}
 	}()
 	slog.Info("Server started", "addr", viper.GetString("http.addr"), "tls", viper.GetBool("tls.enable"))
+	if mtlsServer != nil {
+		go serveTLS(mtlsServer)
+		slog.Info("Mutual TLS server started", "addr", mtlsServer.Addr)
+	}
 	<-sigch
 	slog.Info("Shutting down server")
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
+	if mtlsServer != nil {
+		if err := mtlsServer.Shutdown(ctx); err != nil {
+			slog.Error("Mutual TLS server shutdown error", "error", err)
+		}
+	}
 	if err := server.Shutdown(ctx); err != nil {
 		slog.Error("Server shutdown error", "error", err)
 	} else {
@@ -200,6 +240,19 @@ func Main(cmd *cobra.Command, args []string) {
 	}
 }
 
+func serveTLS(server *http.Server) {
+	ln, err := net.Listen("tcp", server.Addr)
+	if err != nil {
+		slog.Error("Couldn't start TLS server", "addr", server.Addr, "error", err)
+		return
+	}
+	if err := server.ServeTLS(ln, "", ""); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		slog.Error("TLS server returned error", "addr", server.Addr, "error", err)
+	} else {
+		slog.Info("Byte TLS.")
+	}
+}
+
 func loadCerts(server *http.Server) {
 	var cert tls.Certificate
 	var err error
@@ -7,6 +7,33 @@ export type ChallengeResponse = {
 
 export type RedirectResponse = {
     redirect: string;
+    form?: Record<string, string>;
+}
+
+// scriptProtocols run code in this origin instead of navigating away, they are never followed.
+const scriptProtocols = ["javascript:", "data:", "vbscript:"];
+
+// followRedirect navigates to the redirect of a response, posting its form with an auto-submitting form if it has one.
+export function followRedirect(response: RedirectResponse) {
+    if (scriptProtocols.includes(new URL(response.redirect, window.location.href).protocol)) {
+        throw new Error("Refusing to follow redirect to " + response.redirect);
+    }
+    if (!response.form) {
+        window.location.href = response.redirect;
+        return;
+    }
+    const form = document.createElement("form");
+    form.method = "POST";
+    form.action = response.redirect;
+    for (const [name, value] of Object.entries(response.form)) {
+        const input = document.createElement("input");
+        input.type = "hidden";
+        input.name = name;
+        input.value = value;
+        form.appendChild(input);
+    }
+    document.body.appendChild(form);
+    form.submit();
 }
 
 export type ApiError = {
@@ -1,8 +1,19 @@
 import {ChallengeResponse, authnEncode, fetchJSON, authnDecode, RedirectResponse} from "./common.ts";
 
+export type AuthorizationDetail = {
+    type: string;
+    [key: string]: unknown;
+}
+
 export type SignData = {
     text: string;
     data: ArrayBuffer;
+    authorizationDetails?: AuthorizationDetail[];
+}
+
+export type ScopeConsent = {
+    name: string;
+    description: string;
 }
 
 export type App = {
@@ -18,6 +29,7 @@ type JSONChallenge = {
     publicKey: any;
     app: App;
     signData?: SignData;
+    consent?: ScopeConsent[];
 }
 
 export class ICredentialCreationOptions implements CredentialCreationOptions {
@@ -37,11 +49,13 @@ export class ICredentialRequestOptions implements CredentialRequestOptions {
     public publicKey: PublicKeyCredentialRequestOptions;
     public app: App;
     public signData?: SignData;
+    public consent?: ScopeConsent[];
 
-    constructor(publicKey: PublicKeyCredentialRequestOptions, app: App, signData?: SignData) {
+    constructor(publicKey: PublicKeyCredentialRequestOptions, app: App, signData?: SignData, consent?: ScopeConsent[]) {
         this.publicKey = publicKey;
         this.app = app;
         this.signData = signData;
+        this.consent = consent;
     }
 }
 
@@ -65,7 +79,7 @@ export class publicApi {
                 case "webauthn.create":
                     return new ICredentialCreationOptions(pubKey.publicKey, challenge.app, challenge.signData);
                 case "webauthn.get":
-                    return new ICredentialRequestOptions(pubKey.publicKey, challenge.app, challenge.signData);
+                    return new ICredentialRequestOptions(pubKey.publicKey, challenge.app, challenge.signData, challenge.consent);
                 default:
                     throw new Error("Invalid challenge type");
             }
@@ -94,7 +108,7 @@ export class publicApi {
     }
 
     createOAuth2Challenge(urlParameters: URLSearchParams) {
-        return fetchJSON<ChallengeResponse>(`${this.url}/api/v1/oauth2`, {
+        return fetchJSON<ChallengeResponse | RedirectResponse>(`${this.url}/api/v1/oauth2`, {
             method: "POST",
             headers: {
                 'Content-Type': 'application/x-www-form-urlencoded'
@@ -102,4 +116,14 @@ export class publicApi {
             body: urlParameters.toString(),
         })
     }
+
+    resolveUserCode(userCode: string) {
+        return fetchJSON<RedirectResponse>(`${this.url}/api/v1/device`, {
+            method: "POST",
+            headers: {
+                'Content-Type': 'application/x-www-form-urlencoded'
+            },
+            body: new URLSearchParams([["user_code", userCode]]).toString(),
+        })
+    }
 }
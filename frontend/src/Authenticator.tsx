@@ -8,7 +8,7 @@ function Authenticator() {
     const [params] = useSearchParams();
     const id = params.get("token");
     console.log("Authenticator:", id);
-    const {assertOptions, createOptions, error, loading, app, signData} = useChallenge(id || "");
+    const {assertOptions, createOptions, error, loading, app, signData, consent} = useChallenge(id || "");
     if (!id) {
         return <h3>Missing id</h3>
     }
@@ -22,7 +22,7 @@ function Authenticator() {
     if (createOptions) {
         component = <CreateKey id={id} challenge={createOptions}/>
     } else if (assertOptions) {
-        component = <Sign id={id} challenge={assertOptions} app={app} signData={signData}/>
+        component = <Sign id={id} challenge={assertOptions} app={app} signData={signData} consent={consent}/>
     } else {
         component = <h3>Unknown challenge</h3>
     }
@@ -1,6 +1,6 @@
 import {useSearchParams} from "react-router-dom";
 import {useEffect, useState} from "react";
-import {ApiError, ChallengeResponse} from "./Api/common.ts";
+import {ApiError, ChallengeResponse, followRedirect} from "./Api/common.ts";
 import {useApi} from "./Context/Api.tsx";
 import {AnimatedQR} from "./Components/AnimatedQR.tsx";
 
@@ -15,6 +15,10 @@ export const Authorize = () => {
 
     useEffect(() => {
         api.createOAuth2Challenge(params).then((response) => {
+            if ("redirect" in response) {
+                followRedirect(response);
+                return;
+            }
             setChallenge(() => response);
             setStartTime(() => new Date());
         }).catch((error) => {
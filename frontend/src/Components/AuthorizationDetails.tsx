@@ -0,0 +1,36 @@
+import {Table, TableBody, TableCell, TableRow, Typography} from "@mui/material";
+import {AuthorizationDetail} from "../Api/public.ts";
+
+const formatValue = (value: unknown): string => {
+    if (typeof value === 'string') {
+        return value;
+    }
+    return JSON.stringify(value, null, 2);
+}
+
+export type AuthorizationDetailsProps = {
+    details: AuthorizationDetail[]
+}
+
+// AuthorizationDetails shows the authorization details (RFC 9396) the user approves by signing the challenge.
+export const AuthorizationDetails = ({details}: AuthorizationDetailsProps) => {
+    return (
+        <>
+            {details.map((detail, i) => (
+                <div key={i}>
+                    <Typography variant={'h6'} component={'h3'}>{detail.type}</Typography>
+                    <Table size={'small'}>
+                        <TableBody>
+                            {Object.entries(detail).filter(([key]) => key !== 'type').map(([key, value]) => (
+                                <TableRow key={key}>
+                                    <TableCell>{key}</TableCell>
+                                    <TableCell><pre>{formatValue(value)}</pre></TableCell>
+                                </TableRow>
+                            ))}
+                        </TableBody>
+                    </Table>
+                </div>
+            ))}
+        </>
+    )
+}
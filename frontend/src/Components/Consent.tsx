@@ -0,0 +1,24 @@
+import {List, ListItem, ListItemText, Typography} from "@mui/material";
+import {App, ScopeConsent} from "../Api/public.ts";
+
+export type ConsentProps = {
+    app: App
+    scopes: ScopeConsent[]
+}
+
+// Consent lists the scopes a third party application requests, signing the challenge grants them.
+export const Consent = ({app, scopes}: ConsentProps) => {
+    return (
+        <>
+            <Typography variant={'h6'} component={'h3'}>{app.name} is requesting access to</Typography>
+            <List dense>
+                {scopes.map((scope) => (
+                    <ListItem key={scope.name}>
+                        <ListItemText primary={scope.description || scope.name}
+                                      secondary={scope.description ? scope.name : undefined}/>
+                    </ListItem>
+                ))}
+            </List>
+        </>
+    )
+}
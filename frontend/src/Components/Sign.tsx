@@ -1,19 +1,23 @@
 import {useMemo} from "react";
-import {App, SignData} from "../Api/public.ts";
+import {App, ScopeConsent, SignData} from "../Api/public.ts";
 import {useApi} from "../Context/Api.tsx";
 import {useAlert} from "../Context/Alert.tsx";
+import {followRedirect} from "../Api/common.ts";
 import {Button, Paper, Typography} from "@mui/material";
 import Markdown from "react-markdown";
 import remarkGfm from "remark-gfm";
 import remarkRehype from "remark-rehype";
+import {AuthorizationDetails} from "./AuthorizationDetails.tsx";
+import {Consent} from "./Consent.tsx";
 
 export type SignProps = {
     id: string
     challenge: CredentialRequestOptions
     app: App
     signData?: SignData
+    consent?: ScopeConsent[]
 }
-export const Sign = ({id, challenge, app, signData}: SignProps) => {
+export const Sign = ({id, challenge, app, signData, consent}: SignProps) => {
     const {publicApi: api} = useApi();
     const {showAlert} = useAlert();
     const signHandler = () => {
@@ -23,7 +27,7 @@ export const Sign = ({id, challenge, app, signData}: SignProps) => {
                     if (response.redirect === '') {
                         window.close();
                     } else {
-                        window.location.href = response.redirect;
+                        followRedirect(response);
                     }
                 });
             }
@@ -37,7 +41,7 @@ export const Sign = ({id, challenge, app, signData}: SignProps) => {
             if (response.redirect === '') {
                 window.close();
             } else {
-                window.location.href = response.redirect;
+                followRedirect(response);
             }
         });
     }
@@ -57,7 +61,9 @@ export const Sign = ({id, challenge, app, signData}: SignProps) => {
             </div>
             <Paper className={'signtext'}>
                 <Markdown
-                    remarkPlugins={[[remarkGfm, {singleTilde: true}], [remarkRehype, {}]]}>{signData?.text ?? defaultText}</Markdown>
+                    remarkPlugins={[[remarkGfm, {singleTilde: true}], [remarkRehype, {}]]}>{signData?.text || defaultText}</Markdown>
+                {signData?.authorizationDetails && <AuthorizationDetails details={signData.authorizationDetails}/>}
+                {consent && consent.length > 0 && <Consent app={app} scopes={consent}/>}
             </Paper>
             <div className={'sign'}>
                 <Button variant={'contained'} color={'success'} size={'large'}
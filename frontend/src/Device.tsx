@@ -0,0 +1,33 @@
+import {useSearchParams} from "react-router-dom";
+import {FormEvent, useState} from "react";
+import {Button, TextField} from "@mui/material";
+import {ApiError} from "./Api/common.ts";
+import {useApi} from "./Context/Api.tsx";
+
+export const Device = () => {
+    const [params] = useSearchParams();
+    const {publicApi: api} = useApi();
+    const [userCode, setUserCode] = useState<string>(params.get("user_code") ?? "");
+    const [error, setError] = useState<ApiError>();
+
+    const submit = (event: FormEvent) => {
+        event.preventDefault();
+        api.resolveUserCode(userCode).then((response) => {
+            window.location.href = response.redirect;
+        }).catch((error) => {
+            setError(error);
+        });
+    }
+
+    return (
+        <>
+            <h1>Connect a device</h1>
+            <form onSubmit={submit}>
+                <TextField label="Code" value={userCode} onChange={(e) => setUserCode(e.target.value)}
+                           autoFocus autoComplete="off"/>
+                <Button type="submit" variant="contained" disabled={userCode === ""}>Continue</Button>
+            </form>
+            {error && <p>{JSON.stringify(error)}</p>}
+        </>
+    )
+}
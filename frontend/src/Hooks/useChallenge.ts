@@ -1,6 +1,6 @@
 import {useEffect, useState} from "react";
 import {ApiError} from "../Api/common.ts";
-import {App, ICredentialRequestOptions, SignData} from "../Api/public.ts";
+import {App, ICredentialRequestOptions, ScopeConsent, SignData} from "../Api/public.ts";
 import {useApi} from "../Context/Api.tsx";
 
 
@@ -11,6 +11,7 @@ export const useChallenge = (token: string) => {
     const [createOptions, setCreateOptions] = useState<CredentialCreationOptions | null>(null);
     const [assertOptions, setAssertOptions] = useState<CredentialRequestOptions | null>(null);
     const [signData, setSignData] = useState<SignData | undefined>(undefined);
+    const [consent, setConsent] = useState<ScopeConsent[] | undefined>(undefined);
     const [app, setApp] = useState<App>({
         admin: false,
         description: "",
@@ -29,6 +30,7 @@ export const useChallenge = (token: string) => {
                 setApp(challenge.app);
                 setSignData(challenge.signData);
                 if (challenge instanceof ICredentialRequestOptions) {
+                    setConsent(challenge.consent);
                     setAssertOptions(challenge);
                 } else {
                     setCreateOptions(challenge);
@@ -44,5 +46,5 @@ export const useChallenge = (token: string) => {
         }
     }, [token, api]);
 
-    return {assertOptions, createOptions, app, signData, loading, error}
+    return {assertOptions, createOptions, app, signData, consent, loading, error}
 }
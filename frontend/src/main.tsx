@@ -8,6 +8,7 @@ import {BrowserRouter, Route, Routes} from "react-router-dom";
 import {RootLayout} from "./Layout/RootLayout.tsx";
 import Authenticator from "./Authenticator.tsx";
 import {Authorize} from "./Authorize.tsx";
+import {Device} from "./Device.tsx";
 import {Home} from "./Home.tsx";
 import {DemoPage} from "./Demo/Demo.tsx";
 import {ApiProvider} from "./Context/Api.tsx";
@@ -68,6 +69,7 @@ ReactDOM.createRoot(document.getElementById('root')!).render(
                             <Route index element={<Home/>}/>
                             <Route path="/authenticator" element={<Authenticator/>}/>
                             <Route path="/authorize" element={<Authorize/>}/>
+                            <Route path="/device" element={<Device/>}/>
                             <Route path="/demo" element={<DemoPage/>}/>
                         </Route>
                     </Routes>
@@ -4,22 +4,32 @@ import (
 	"crypto/sha256"
 	"crypto/subtle"
 	"encoding/base64"
+	"errors"
 	"fmt"
 	"log/slog"
 	"net/http"
 	"strings"
 	"uyulala/internal/api"
+	"uyulala/internal/api/token"
 	"uyulala/internal/db/appdb"
 	"uyulala/internal/db/challengedb"
 	"uyulala/internal/db/keydb"
 	"uyulala/internal/db/sessiondb"
 	"uyulala/internal/db/userdb"
+	"uyulala/internal/mtls"
+	"uyulala/internal/notify"
 	"uyulala/openid/discovery"
 
 	"github.com/gin-gonic/gin"
 	"github.com/lestrrat-go/jwx/jws"
 	"github.com/lestrrat-go/jwx/jwt"
 	"github.com/spf13/viper"
+	"gitlab.com/daedaluz/gindb"
+)
+
+var (
+	errTLSThumbprint = errors.New("certificate thumbprint does not match")
+	errNoTLSClient   = errors.New("client has no certificate registered")
 )
 
 func authOAuthCollect(ctx *gin.Context, app *appdb.Application, challenge *challengedb.Data, codeVerifier string) {
@@ -50,6 +60,10 @@ func authCollect(ctx *gin.Context, app *appdb.Application, password string) {
 		api.AbortError(ctx, http.StatusUnauthorized, "unauthorized", "Invalid credentials", nil)
 		return
 	}
+	if !app.AllowsAuthMethod(discovery.TokenAuthClientSecretBasic) {
+		api.AbortError(ctx, http.StatusUnauthorized, "unauthorized", "Authentication method not allowed for this client", nil)
+		return
+	}
 	ctx.Set("application", app)
 }
 
@@ -65,7 +79,7 @@ func authOAuthRefresh(ctx *gin.Context, app *appdb.Application) {
 		api.AbortError(ctx, http.StatusInternalServerError, "key_error", "Unable to get server key set", err)
 		return
 	}
-	token, err := jwt.ParseString(refreshToken, jwt.WithValidate(true),
+	refresh, err := jwt.ParseString(refreshToken, jwt.WithValidate(true),
 		jwt.WithIssuer(viper.GetString("issuer")),
 		jwt.WithKeySet(keySet), jwt.WithAudience(viper.GetString("issuer")))
 	if err != nil {
@@ -82,7 +96,11 @@ func authOAuthRefresh(ctx *gin.Context, app *appdb.Application) {
 		api.AbortError(ctx, http.StatusBadRequest, "invalid_token", "This isn't a refresh token", err)
 		return
 	}
-	tid := token.JwtID()
+	if err := token.VerifyConfirmation(ctx, refresh); err != nil {
+		api.AbortError(ctx, http.StatusBadRequest, "invalid_grant", "Refresh token is bound to another client", err)
+		return
+	}
+	tid := refresh.JwtID()
 
 	sess, err := sessiondb.Get(ctx, tid)
 	if err != nil {
@@ -90,11 +108,11 @@ func authOAuthRefresh(ctx *gin.Context, app *appdb.Application) {
 		return
 	}
 
-	if c, ok := token.Get("counter"); ok {
+	if c, ok := refresh.Get("counter"); ok {
 		if sess.Counter != uint32(c.(float64)) {
-			slog.Warn("Cloned refresh token", "token", tid, "counter", sess.Counter, "expected", uint32(c.(float32)))
+			slog.Warn("Cloned refresh token", "token", tid, "counter", uint32(c.(float64)), "expected", sess.Counter)
+			endReusedSession(ctx, sess)
 			api.AbortError(ctx, http.StatusBadRequest, "reused_refresh_token", "Reused refresh token", nil)
-			_ = sessiondb.Delete(ctx, tid)
 			return
 		}
 	} else {
@@ -125,21 +143,37 @@ func authOAuthRefresh(ctx *gin.Context, app *appdb.Application) {
 	ctx.Set("session", sess)
 }
 
+// endReusedSession deletes a session whose refresh token has been reused and notifies the application.
+// The request is aborted afterward, so the transaction is committed here for the removal to stick.
+func endReusedSession(ctx *gin.Context, sess *sessiondb.Session) {
+	if err := sessiondb.Delete(ctx, sess.ID); err != nil {
+		slog.Error("Failed to delete session", "session", sess.ID, "error", err)
+		return
+	}
+	if err := token.BackChannelLogout(ctx, sess); err != nil {
+		slog.Warn("Failed to create back-channel logout", "session", sess.ID, "error", err)
+	}
+	if err := gindb.Commit(ctx); err != nil {
+		slog.Error("Failed to commit session removal", "session", sess.ID, "error", err)
+		return
+	}
+	notify.Flush(ctx)
+}
+
 func ClientMiddleware() gin.HandlerFunc {
 	return func(ctx *gin.Context) {
 		username, password, ok := ctx.Request.BasicAuth()
 		if ctx.ContentType() == "application/x-www-form-urlencoded" {
-			if !ok {
-				username = ctx.PostForm("client_id")
-				password = ctx.PostForm("client_secret")
-			}
-			app, err := appdb.GetApplication(ctx, username)
-			if err != nil {
-				api.AbortError(ctx, http.StatusUnauthorized, "unauthorized", "Invalid credentials", err)
-				return
+			if proof := ctx.GetHeader("DPoP"); proof != "" {
+				jkt, err := token.VerifyDPoPProof(ctx, proof, "")
+				if err != nil {
+					api.AbortError(ctx, http.StatusBadRequest, "invalid_dpop_proof", "Invalid DPoP proof", err)
+					return
+				}
+				token.SetDPoPKey(ctx, jkt)
 			}
-			if subtle.ConstantTimeCompare([]byte(password), []byte(app.Secret)) == 0 {
-				api.AbortError(ctx, http.StatusUnauthorized, "unauthorized", "Invalid credentials", nil)
+			app := authFormClient(ctx, username, password, ok)
+			if app == nil {
 				return
 			}
 			grantType := ctx.PostForm("grant_type")
@@ -163,6 +197,10 @@ func ClientMiddleware() gin.HandlerFunc {
 				authOAuthCollect(ctx, app, ch, codeVerifier)
 			case discovery.GrantTypeRefresh:
 				authOAuthRefresh(ctx, app)
+			case discovery.GrantTypeClientCredentials:
+				ctx.Set("application", app)
+			case discovery.GrantTypeDeviceCode:
+				authDeviceFlow(ctx, app, ctx.PostForm("device_code"))
 			case discovery.GrantTypeCIBA, "":
 				authRequestID := ctx.PostForm("auth_req_id")
 				authCIBAFlow(ctx, app, authRequestID)
@@ -184,6 +222,77 @@ func ClientMiddleware() gin.HandlerFunc {
 	}
 }
 
+// authFormClient authenticates the client of a form request, either by a client assertion, by the client
+// certificate of the connection, or by the client secret in the Authorization header or the form.
+func authFormClient(ctx *gin.Context, username, password string, basic bool) *appdb.Application {
+	var app *appdb.Application
+	var method string
+	if assertionType := ctx.PostForm("client_assertion_type"); assertionType != "" {
+		if assertionType != token.ClientAssertionTypeJWT {
+			api.AbortError(ctx, http.StatusUnauthorized, "unauthorized", "Unsupported client_assertion_type", nil)
+			return nil
+		}
+		var err error
+		app, method, err = token.VerifyClientAssertion(ctx, ctx.PostForm("client_assertion"), ctx.PostForm("client_id"))
+		if err != nil {
+			api.AbortError(ctx, http.StatusUnauthorized, "unauthorized", "Invalid client assertion", err)
+			return nil
+		}
+	} else if !basic && !ctx.Request.PostForm.Has("client_secret") && mtls.Certificate(ctx.Request) != nil {
+		var err error
+		app, method, err = authTLSClient(ctx, ctx.PostForm("client_id"))
+		if err != nil {
+			api.AbortError(ctx, http.StatusUnauthorized, "unauthorized", "Invalid client certificate", err)
+			return nil
+		}
+	} else {
+		method = discovery.TokenAuthClientSecretBasic
+		if !basic {
+			username = ctx.PostForm("client_id")
+			password = ctx.PostForm("client_secret")
+			method = discovery.TokenAuthClientSecretPost
+		}
+		var err error
+		app, err = appdb.GetApplication(ctx, username)
+		if err != nil {
+			api.AbortError(ctx, http.StatusUnauthorized, "unauthorized", "Invalid credentials", err)
+			return nil
+		}
+		if subtle.ConstantTimeCompare([]byte(password), []byte(app.Secret)) == 0 {
+			api.AbortError(ctx, http.StatusUnauthorized, "unauthorized", "Invalid credentials", nil)
+			return nil
+		}
+	}
+	if !app.AllowsAuthMethod(method) {
+		api.AbortError(ctx, http.StatusUnauthorized, "unauthorized", "Authentication method not allowed for this client", nil)
+		return nil
+	}
+	return app
+}
+
+// authTLSClient authenticates a client by its certificate (RFC 8705), either by the subject of a certificate issued
+// by a trusted CA (tls_client_auth) or by the thumbprint of a self-signed one (self_signed_tls_client_auth).
+func authTLSClient(ctx *gin.Context, clientID string) (*appdb.Application, string, error) {
+	app, err := appdb.GetApplication(ctx, clientID)
+	if err != nil {
+		return nil, "", err
+	}
+	switch {
+	case app.TLSClientSubjectDN != "":
+		if err := mtls.VerifySubject(ctx.Request, app.TLSClientSubjectDN); err != nil {
+			return nil, "", err
+		}
+		return app, discovery.TokenAuthTLSClient, nil
+	case app.TLSClientThumbprint != "":
+		cert := mtls.Certificate(ctx.Request)
+		if subtle.ConstantTimeCompare([]byte(mtls.Thumbprint(cert)), []byte(app.TLSClientThumbprint)) == 0 {
+			return nil, "", errTLSThumbprint
+		}
+		return app, discovery.TokenAuthSelfSignedTLSClient, nil
+	}
+	return nil, "", errNoTLSClient
+}
+
 func authCIBAFlow(ctx *gin.Context, app *appdb.Application, requestID string) {
 	if requestID != "" {
 		ch, err := challengedb.GetChallengeByCIBARequestID(ctx, requestID)
@@ -203,6 +312,47 @@ func authCIBAFlow(ctx *gin.Context, app *appdb.Application, requestID string) {
 	ctx.Set("application", app)
 }
 
+func authDeviceFlow(ctx *gin.Context, app *appdb.Application, deviceCode string) {
+	code, err := challengedb.GetDeviceCode(ctx, deviceCode)
+	if err != nil {
+		api.AbortError(ctx, http.StatusBadRequest, "invalid_grant", "No such device_code", err)
+		return
+	}
+	ch, err := challengedb.GetChallenge(ctx, code.ChallengeID)
+	if err != nil {
+		api.AbortError(ctx, http.StatusBadRequest, "invalid_grant", "No such device_code", err)
+		return
+	}
+	if ch.AppID != app.ID {
+		api.AbortError(ctx, http.StatusBadRequest, "invalid_grant", "This device_code was not issued for this client", nil)
+		return
+	}
+	interval := code.Interval
+	tooEarly := code.TooEarly()
+	if tooEarly {
+		interval += 5
+	}
+	if err := challengedb.PollDeviceCode(ctx, deviceCode, interval); err != nil {
+		api.AbortError(ctx, http.StatusInternalServerError, "internal_error", "Unexpected error", err)
+		return
+	}
+	// The poll must be recorded even though most polls are answered with an error, which rolls back the transaction.
+	if err := gindb.Commit(ctx); err != nil {
+		api.AbortError(ctx, http.StatusInternalServerError, "internal_error", "Unexpected error", err)
+		return
+	}
+	if err := gindb.BeginTx(ctx); err != nil {
+		api.AbortError(ctx, http.StatusInternalServerError, "internal_error", "Unexpected error", err)
+		return
+	}
+	if tooEarly {
+		api.OAuth2ErrorResponse(ctx, http.StatusBadRequest, "slow_down", "Polling too frequently")
+		return
+	}
+	ctx.Set("application", app)
+	ctx.Set("challenge", ch)
+}
+
 func AdminMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		app := GetCurrentApplication(c)
@@ -6,6 +6,7 @@ import (
 	"strings"
 	"time"
 	"uyulala/internal/api"
+	apitoken "uyulala/internal/api/token"
 	"uyulala/internal/db/keydb"
 
 	"github.com/gin-gonic/gin"
@@ -22,7 +23,7 @@ func IssuerMiddleware() gin.HandlerFunc {
 			api.AbortError(c, http.StatusUnauthorized, "", "unauthorized", nil)
 			return
 		}
-		if !strings.EqualFold(fields[0], "bearer") {
+		if !strings.EqualFold(fields[0], "bearer") && !strings.EqualFold(fields[0], "dpop") {
 			api.AbortError(c, http.StatusUnauthorized, "unsupported_auth_method",
 				fmt.Sprintf("%s authorization is not supported", fields[0]), nil)
 			return
@@ -60,6 +61,10 @@ func IssuerMiddleware() gin.HandlerFunc {
 			api.AbortError(c, http.StatusUnauthorized, "bad_token_type", "Provided token type is not at+jwt", err)
 			return
 		}
+		if err := apitoken.VerifyResourceAccess(c, fields[0], fields[1], token); err != nil {
+			api.AbortError(c, http.StatusUnauthorized, "unauthorized", "Unauthorized", err)
+			return
+		}
 		c.Set("jwt", token)
 		c.Next()
 	}
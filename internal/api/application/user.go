@@ -7,6 +7,7 @@ import (
 	"strings"
 	"time"
 	"uyulala/internal/api"
+	apitoken "uyulala/internal/api/token"
 	"uyulala/internal/db/keydb"
 	"uyulala/internal/trust"
 
@@ -60,7 +61,7 @@ func JWTMiddleware() gin.HandlerFunc {
 			c.Next()
 			return
 		}
-		if !strings.EqualFold(fields[0], "bearer") {
+		if !strings.EqualFold(fields[0], "bearer") && !strings.EqualFold(fields[0], "dpop") {
 			slog.Warn("JWTMiddleware", "auth_type", fields[0])
 			c.Next()
 			return
@@ -87,6 +88,11 @@ func JWTMiddleware() gin.HandlerFunc {
 			c.Next()
 			return
 		}
+		if err := apitoken.VerifyResourceAccess(c, fields[0], fields[1], token); err != nil {
+			slog.Warn("JWTMiddleware", "confirmation_error", err)
+			c.Next()
+			return
+		}
 		c.Set("jwt", token)
 	}
 }
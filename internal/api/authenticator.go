@@ -0,0 +1,22 @@
+package api
+
+import (
+	"net/url"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/spf13/viper"
+)
+
+// AuthenticatorURL returns a link to the authenticator page for a challenge.
+// The token is marked persistent, so it isn't bound to the time the challenge was created like the animated QR codes are.
+func AuthenticatorURL(challengeID, secret string) (string, error) {
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"challenge_id": challengeID,
+		"persistent":   true,
+	})
+	tokenString, err := token.SignedString([]byte(secret))
+	if err != nil {
+		return "", err
+	}
+	return viper.GetString("issuer") + "/authenticator?" + url.Values{"token": {tokenString}}.Encode(), nil
+}
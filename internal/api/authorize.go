@@ -0,0 +1,193 @@
+package api
+
+import (
+	"encoding/base64"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+	"uyulala/internal/db/appdb"
+	"uyulala/openid/discovery"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ResponseModes are the supported response modes of the authorization endpoint.
+var ResponseModes = []string{
+	discovery.ResponseModeQuery,
+	discovery.ResponseModeFragment,
+	discovery.ResponseModeFormPost,
+	discovery.ResponseModeJWT,
+	discovery.ResponseModeQueryJWT,
+	discovery.ResponseModeFragmentJWT,
+	discovery.ResponseModeFormPostJWT,
+}
+
+// ResponseTypes are the supported response types of the authorization endpoint, normalized with ResponseType.
+var ResponseTypes = []string{
+	discovery.ResponseTypeCode,
+	discovery.ResponseTypeIDToken,
+	discovery.ResponseTypeCode + " " + discovery.ResponseTypeIDToken,
+	discovery.ResponseTypeCode + " " + discovery.ResponseTypeIDToken + " " + discovery.ResponseTypeToken,
+}
+
+// ResponseType normalizes the order of the values of a response_type parameter to code, id_token, token.
+func ResponseType(responseType string) string {
+	values := strings.FieldsFunc(responseType, func(r rune) bool { return r == ' ' || r == ',' || r == ';' || r == '\t' })
+	var res []string
+	for _, value := range []string{discovery.ResponseTypeCode, discovery.ResponseTypeIDToken, discovery.ResponseTypeToken} {
+		if slices.Contains(values, value) {
+			res = append(res, value)
+		}
+	}
+	if len(res) != len(values) {
+		return responseType
+	}
+	return strings.Join(res, " ")
+}
+
+type AuthorizationRequest struct {
+	ResponseType   string
+	RedirectURI    *url.URL
+	BindingMessage string
+	// SignatureData is either the decoded data parameter or the canonical authorization details.
+	SignatureData []byte
+}
+
+// ValidateAuthorizationRequest validates the parameters of an authorization request made by client, both when received
+// by the authorization endpoint and when pushed by the client.
+func ValidateAuthorizationRequest(ctx *gin.Context, client *appdb.Application, form url.Values) (*AuthorizationRequest, bool) {
+	responseType := ResponseType(form.Get("response_type"))
+	if !slices.Contains(ResponseTypes, responseType) {
+		slog.Info("Unknown response type", "response_type", form.Get("response_type"))
+		AbortError(ctx, http.StatusBadRequest, "bad_response_type", "Unknown response type", nil)
+		return nil, false
+	}
+	if !client.AllowsResponseType(responseType) {
+		AbortError(ctx, http.StatusBadRequest, "unauthorized_client", "Response type not allowed for this client", nil)
+		return nil, false
+	}
+	responseMode := form.Get("response_mode")
+	if responseMode != "" && !slices.Contains(ResponseModes, responseMode) {
+		AbortError(ctx, http.StatusBadRequest, "invalid_request", "Unsupported response_mode", nil)
+		return nil, false
+	}
+	if responseType != discovery.ResponseTypeCode {
+		// Tokens must not be returned in the query (OAuth 2.0 Multiple Response Type Encoding Practices 3.0).
+		if responseMode == discovery.ResponseModeQuery || responseMode == discovery.ResponseModeQueryJWT {
+			AbortError(ctx, http.StatusBadRequest, "invalid_request", "response_mode query is not allowed for this response type", nil)
+			return nil, false
+		}
+		if form.Get("nonce") == "" {
+			AbortError(ctx, http.StatusBadRequest, "invalid_request", "nonce is required for this response type", nil)
+			return nil, false
+		}
+	}
+	redirectURI, err := parseRedirectURI(form)
+	if err != nil {
+		if errors.Is(err, &url.Error{}) {
+			AbortError(ctx, http.StatusBadRequest, "invalid_request", "Invalid redirect_uri", err)
+		} else {
+			AbortError(ctx, http.StatusBadRequest, "invalid_request", err.Error(), nil)
+		}
+		return nil, false
+	}
+	if !AllowedRedirect(client, redirectURI.String()) {
+		AbortError(ctx, http.StatusBadRequest, "invalid_request", "Redirect not allowed", nil)
+		return nil, false
+	}
+
+	pkceMethod := form.Get("code_challenge_method")
+	if pkceMethod != "" && pkceMethod != "S256" && pkceMethod != "plain" {
+		AbortError(ctx, http.StatusBadRequest, "invalid_request", "Invalid code_challenge_method; \"S256\" or \"plain\" is supported", nil)
+		return nil, false
+	}
+	if pkceMethod != "" && form.Get("code_challenge") == "" {
+		AbortError(ctx, http.StatusBadRequest, "invalid_request", "code_challenge_method given, but no code_challenge", nil)
+		return nil, false
+	}
+	if pkceMethod == "" && form.Get("code_challenge") != "" {
+		AbortError(ctx, http.StatusBadRequest, "invalid_request", "code_challenge given, but no code_challenge_method", nil)
+		return nil, false
+	}
+
+	prompts := strings.Fields(form.Get("prompt"))
+	for _, prompt := range prompts {
+		if !slices.Contains([]string{"none", "login", "consent", "select_account"}, prompt) {
+			AbortError(ctx, http.StatusBadRequest, "invalid_request", "Unsupported prompt "+prompt, nil)
+			return nil, false
+		}
+	}
+	if slices.Contains(prompts, "none") && len(prompts) > 1 {
+		AbortError(ctx, http.StatusBadRequest, "invalid_request", "prompt=none can't be combined with other values", nil)
+		return nil, false
+	}
+	if maxAge := form.Get("max_age"); maxAge != "" {
+		if seconds, err := strconv.Atoi(maxAge); err != nil || seconds < 0 {
+			AbortError(ctx, http.StatusBadRequest, "invalid_request", "Invalid max_age", err)
+			return nil, false
+		}
+	}
+
+	if form.Get("state") == "" {
+		AbortError(ctx, http.StatusBadRequest, "invalid_request", "Missing state", nil)
+		return nil, false
+	}
+	if !ValidateScopes(ctx, client, SpaceDelimited(form.Get("scope"))) {
+		return nil, false
+	}
+	if !ValidateClaimsRequest(ctx, form) {
+		return nil, false
+	}
+
+	bindingMessage := form.Get("binding_message")
+	if bindingMessage != "" && !utf8.ValidString(bindingMessage) {
+		AbortError(ctx, http.StatusBadRequest, "invalid_request", "Invalid binding_message, must be utf8", nil)
+		return nil, false
+	}
+	var signatureData []byte
+	if signatureDataText := form.Get("data"); signatureDataText != "" {
+		signatureData, err = base64.StdEncoding.DecodeString(signatureDataText)
+		if err != nil {
+			AbortError(ctx, http.StatusBadRequest, "invalid_request", "Bad signature data encoding", err)
+			return nil, false
+		}
+	}
+	if form.Has("authorization_details") {
+		if signatureData != nil {
+			AbortError(ctx, http.StatusBadRequest, "invalid_request", "data can't be combined with authorization_details", nil)
+			return nil, false
+		}
+		if signatureData, err = ParseAuthorizationDetails(form.Get("authorization_details")); err != nil {
+			AbortError(ctx, http.StatusBadRequest, "invalid_authorization_details", err.Error(), err)
+			return nil, false
+		}
+		// The canonical form is what the user signs and what the tokens are issued with.
+		form.Set("authorization_details", string(signatureData))
+	}
+	return &AuthorizationRequest{
+		ResponseType:   responseType,
+		RedirectURI:    redirectURI,
+		BindingMessage: bindingMessage,
+		SignatureData:  signatureData,
+	}, true
+}
+
+func parseRedirectURI(vars url.Values) (*url.URL, error) {
+	uri := vars.Get("redirect_uri")
+	if uri == "" {
+		return nil, errors.New("missing redirect_uri")
+	}
+	parsed, err := url.Parse(uri)
+	if err != nil {
+		return nil, err
+	}
+	if parsed.Fragment != "" {
+		return nil, errors.New("invalid redirect_uri (Must not contain fragment)")
+	}
+	return parsed, nil
+}
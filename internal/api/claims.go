@@ -0,0 +1,90 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"slices"
+
+	"github.com/gin-gonic/gin"
+)
+
+// StandardScopes are the scopes of OpenID Connect Core 5.4 that release profile attributes of the user.
+var StandardScopes = []string{"profile", "email"}
+
+// StandardClaims are the profile attributes released by each of the StandardScopes.
+var StandardClaims = map[string][]string{
+	"profile": {"name", "given_name", "preferred_username", "locale", "picture"},
+	"email":   {"email", "email_verified"},
+}
+
+// scopeDescriptions describe the scopes of OpenID Connect Core to the user when they aren't registered.
+var scopeDescriptions = map[string]string{
+	"profile":        "Your name, username, picture and language",
+	"email":          "Your email address",
+	"offline_access": "Access while you aren't signed in",
+}
+
+// IsStandardClaim reports whether name is released by one of the StandardScopes.
+func IsStandardClaim(name string) bool {
+	for _, claims := range StandardClaims {
+		if slices.Contains(claims, name) {
+			return true
+		}
+	}
+	return false
+}
+
+var ErrInvalidClaimsRequest = errors.New("claims must be a JSON object with userinfo and id_token members of requested claims")
+
+// ClaimsRequest is the claims parameter of an authorization request (OpenID Connect Core 5.5), the names of the
+// individual claims requested in the userinfo response and the ID token.
+type ClaimsRequest struct {
+	UserInfo map[string]json.RawMessage `json:"userinfo,omitempty"`
+	IDToken  map[string]json.RawMessage `json:"id_token,omitempty"`
+}
+
+// Names returns the names of the claims requested in the userinfo response or the ID token, sorted.
+func (r *ClaimsRequest) Names() []string {
+	var names []string
+	for _, requested := range []map[string]json.RawMessage{r.UserInfo, r.IDToken} {
+		for name := range requested {
+			if !slices.Contains(names, name) {
+				names = append(names, name)
+			}
+		}
+	}
+	slices.Sort(names)
+	return names
+}
+
+// ParseClaimsRequest parses a claims parameter, an empty parameter requests no claims.
+func ParseClaimsRequest(claims string) (*ClaimsRequest, error) {
+	req := &ClaimsRequest{}
+	if claims == "" {
+		return req, nil
+	}
+	if err := json.Unmarshal([]byte(claims), req); err != nil {
+		return nil, ErrInvalidClaimsRequest
+	}
+	for _, requested := range []map[string]json.RawMessage{req.UserInfo, req.IDToken} {
+		for _, value := range requested {
+			// Each claim is either null or an object with essential, value or values.
+			var options map[string]any
+			if err := json.Unmarshal(value, &options); err != nil {
+				return nil, ErrInvalidClaimsRequest
+			}
+		}
+	}
+	return req, nil
+}
+
+// ValidateClaimsRequest aborts with invalid_request when form has an invalid claims parameter.
+func ValidateClaimsRequest(ctx *gin.Context, form url.Values) bool {
+	if _, err := ParseClaimsRequest(form.Get("claims")); err != nil {
+		AbortError(ctx, http.StatusBadRequest, "invalid_request", err.Error(), err)
+		return false
+	}
+	return true
+}
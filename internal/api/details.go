@@ -0,0 +1,46 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"slices"
+
+	"github.com/spf13/viper"
+)
+
+var (
+	ErrInvalidAuthorizationDetails     = errors.New("authorization_details must be a JSON array of objects with a type")
+	ErrUnsupportedAuthorizationDetails = errors.New("unsupported authorization details type")
+)
+
+// AuthorizationDetailsTypes returns the supported authorization details types, any type is accepted when empty.
+func AuthorizationDetailsTypes() []string {
+	return viper.GetStringSlice("authorizationDetails.types")
+}
+
+// ParseAuthorizationDetails validates an authorization_details parameter (RFC 9396) and returns it in a canonical
+// form, since it's part of the challenge hash the user signs. The canonical form is the parameter without insignificant
+// whitespace: key order and numbers are kept as the client sent them, so the signed details are exactly the requested
+// ones.
+func ParseAuthorizationDetails(details string) (json.RawMessage, error) {
+	var parsed []map[string]json.RawMessage
+	if err := json.Unmarshal([]byte(details), &parsed); err != nil || len(parsed) == 0 {
+		return nil, ErrInvalidAuthorizationDetails
+	}
+	types := AuthorizationDetailsTypes()
+	for _, detail := range parsed {
+		var typ string
+		if err := json.Unmarshal(detail["type"], &typ); err != nil || typ == "" {
+			return nil, ErrInvalidAuthorizationDetails
+		}
+		if len(types) > 0 && !slices.Contains(types, typ) {
+			return nil, ErrUnsupportedAuthorizationDetails
+		}
+	}
+	canonical := &bytes.Buffer{}
+	if err := json.Compact(canonical, []byte(details)); err != nil {
+		return nil, ErrInvalidAuthorizationDetails
+	}
+	return canonical.Bytes(), nil
+}
@@ -0,0 +1,65 @@
+package api
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+func TestParseAuthorizationDetails(t *testing.T) {
+	tests := []struct {
+		name    string
+		details string
+		want    string
+	}{
+		{"compact", `[{"type":"payment_initiation"}]`, `[{"type":"payment_initiation"}]`},
+		{"whitespace", "[ {\n\t\"type\" : \"payment_initiation\" ,\"amount\": \"1 EUR\" } ]",
+			`[{"type":"payment_initiation","amount":"1 EUR"}]`},
+		{"key order", `[{"type":"a","z":1,"b":2,"m":{"y":true,"c":null}}]`,
+			`[{"type":"a","z":1,"b":2,"m":{"y":true,"c":null}}]`},
+		{"numbers", `[{"type":"a","amount":123.50,"id":12345678901234567890,"e":1e3,"n":-0.0}]`,
+			`[{"type":"a","amount":123.50,"id":12345678901234567890,"e":1e3,"n":-0.0}]`},
+		{"strings", `[{"type":"a","text":"a \"quoted\" é <b>"}]`, `[{"type":"a","text":"a \"quoted\" é <b>"}]`},
+		{"several", `[{"type":"a"}, {"type":"b","locations":["https://a.example"]}]`,
+			`[{"type":"a"},{"type":"b","locations":["https://a.example"]}]`},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := ParseAuthorizationDetails(test.details)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != test.want {
+				t.Errorf("canonical = %s, want %s", got, test.want)
+			}
+		})
+	}
+}
+
+func TestParseAuthorizationDetailsInvalid(t *testing.T) {
+	viper.Set("authorizationDetails.types", []string{"payment_initiation"})
+	defer viper.Set("authorizationDetails.types", nil)
+	tests := []struct {
+		name    string
+		details string
+		want    error
+	}{
+		{"empty", ``, ErrInvalidAuthorizationDetails},
+		{"empty array", `[]`, ErrInvalidAuthorizationDetails},
+		{"object", `{"type":"payment_initiation"}`, ErrInvalidAuthorizationDetails},
+		{"no type", `[{"amount":1}]`, ErrInvalidAuthorizationDetails},
+		{"empty type", `[{"type":""}]`, ErrInvalidAuthorizationDetails},
+		{"type not a string", `[{"type":1}]`, ErrInvalidAuthorizationDetails},
+		{"not an object", `[{"type":"payment_initiation"}, "a"]`, ErrInvalidAuthorizationDetails},
+		{"trailing data", `[{"type":"payment_initiation"}] []`, ErrInvalidAuthorizationDetails},
+		{"unsupported type", `[{"type":"account_information"}]`, ErrUnsupportedAuthorizationDetails},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if _, err := ParseAuthorizationDetails(test.details); !errors.Is(err, test.want) {
+				t.Errorf("err = %v, want %v", err, test.want)
+			}
+		})
+	}
+}
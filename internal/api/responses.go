@@ -2,6 +2,7 @@ package api
 
 import (
 	"net/http"
+	"net/url"
 
 	"github.com/gin-gonic/gin"
 )
@@ -37,6 +38,18 @@ func RedirectResponse(ctx *gin.Context, url string) {
 	})
 }
 
+// FormPostResponse tells the front-end to post form to url with an auto-submitting form.
+func FormPostResponse(ctx *gin.Context, url string, form url.Values) {
+	values := make(map[string]string, len(form))
+	for k := range form {
+		values[k] = form.Get(k)
+	}
+	ctx.JSON(http.StatusOK, gin.H{
+		"redirect": url,
+		"form":     values,
+	})
+}
+
 func DeletedResponse(ctx *gin.Context) {
 	ctx.JSON(http.StatusOK, gin.H{
 		"status": "deleted",
@@ -0,0 +1,135 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"uyulala/internal/db/appdb"
+	"uyulala/internal/db/scopedb"
+	"uyulala/internal/db/userdb"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SpaceDelimited splits a space delimited parameter like scope or acr_values, tolerating other whitespace.
+func SpaceDelimited(value string) []string {
+	return strings.FieldsFunc(value, func(r rune) bool {
+		switch r {
+		case ' ', '\t', '\r', '\n':
+			return true
+		}
+		return false
+	})
+}
+
+// ValidateScopes aborts with invalid_scope when client requests a registered scope it isn't allowed to.
+// Scopes missing from the registry are allowed, but put to the user for consent like the registered ones.
+func ValidateScopes(ctx *gin.Context, client *appdb.Application, scopes []string) bool {
+	registered, err := scopedb.Registered(ctx, scopes)
+	if err != nil {
+		AbortError(ctx, http.StatusInternalServerError, "internal_error", "Unexpected error", err)
+		return false
+	}
+	for _, scope := range registered {
+		if !scope.Allowed(client.ID) {
+			err := fmt.Errorf("scope %s is not allowed", scope.Name)
+			AbortError(ctx, http.StatusBadRequest, "invalid_scope", "Scope not allowed for this client", err)
+			return false
+		}
+	}
+	return true
+}
+
+// RegisteredScopes returns the registered scopes among the requested ones, in the requested order.
+func RegisteredScopes(ctx *gin.Context, client *appdb.Application, scopes []string) ([]*scopedb.Scope, error) {
+	registered, err := scopedb.Registered(ctx, scopes)
+	if err != nil {
+		return nil, err
+	}
+	var res []*scopedb.Scope
+	for _, name := range scopes {
+		if scope, ok := registered[name]; ok && scope.Allowed(client.ID) && !slices.Contains(res, scope) {
+			res = append(res, scope)
+		}
+	}
+	return res, nil
+}
+
+// RequestedScopes returns the scopes an authorization request asks the user to grant: every requested scope except
+// openid, which only authenticates the user, and the scopes releasing the claims requested individually with the
+// claims parameter. Scopes missing from the registry are returned with only a name, the standard scopes with their
+// claims.
+func RequestedScopes(ctx *gin.Context, client *appdb.Application, request url.Values) ([]*scopedb.Scope, error) {
+	all, err := scopedb.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	registered := make(map[string]*scopedb.Scope, len(all))
+	for _, scope := range all {
+		registered[scope.Name] = scope
+	}
+	names := slices.DeleteFunc(SpaceDelimited(request.Get("scope")), func(name string) bool {
+		return name == "openid"
+	})
+	// The claims parameter was validated with the request.
+	if claims, err := ParseClaimsRequest(request.Get("claims")); err == nil {
+		for _, claim := range claims.Names() {
+			for _, scope := range StandardScopes {
+				if slices.Contains(StandardClaims[scope], claim) {
+					names = append(names, scope)
+				}
+			}
+			for _, scope := range all {
+				if scope.Allowed(client.ID) && slices.Contains(scope.Claims, claim) {
+					names = append(names, scope.Name)
+				}
+			}
+		}
+	}
+
+	var res []*scopedb.Scope
+	for _, name := range names {
+		if slices.ContainsFunc(res, func(scope *scopedb.Scope) bool { return scope.Name == name }) {
+			continue
+		}
+		if scope, ok := registered[name]; ok {
+			if scope.Allowed(client.ID) {
+				res = append(res, scope)
+			}
+			continue
+		}
+		res = append(res, &scopedb.Scope{Name: name, Description: scopeDescriptions[name], Claims: StandardClaims[name]})
+	}
+	return res, nil
+}
+
+// ConsentScopes returns the scopes of an authorization request the user has to consent to, see RequestedScopes.
+// First party applications never ask for consent, and scopes the user has already granted are skipped unless the
+// request has prompt=consent. userID is empty when the user isn't known before signing.
+func ConsentScopes(ctx *gin.Context, client *appdb.Application, request url.Values, userID string) ([]*scopedb.Scope, error) {
+	if client.FirstParty {
+		return nil, nil
+	}
+	scopes, err := RequestedScopes(ctx, client, request)
+	if err != nil || userID == "" || slices.Contains(strings.Fields(request.Get("prompt")), "consent") {
+		return scopes, err
+	}
+	consented, err := userdb.ConsentedScopes(ctx, userID, client.ID)
+	if err != nil {
+		return nil, err
+	}
+	return slices.DeleteFunc(scopes, func(scope *scopedb.Scope) bool {
+		return slices.Contains(consented, scope.Name)
+	}), nil
+}
+
+// ScopeNames returns the names of scopes.
+func ScopeNames(scopes []*scopedb.Scope) []string {
+	names := make([]string, 0, len(scopes))
+	for _, scope := range scopes {
+		names = append(names, scope.Name)
+	}
+	return names
+}
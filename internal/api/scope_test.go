@@ -0,0 +1,98 @@
+package api
+
+import (
+	"database/sql/driver"
+	"net/http"
+	"net/url"
+	"slices"
+	"testing"
+	"time"
+	"uyulala/internal/db/appdb"
+	"uyulala/internal/db/dbtest"
+	"uyulala/internal/db/scopedb"
+)
+
+// registry implements the scope and consent procedures over scopes and the scopes user has granted to client.
+func registry(db *dbtest.DB, scopes []*scopedb.Scope, consented []string) {
+	db.Scopes(scopes)
+	db.Procedure("list_consents_for_user", func(args []driver.Value) ([]string, [][]driver.Value, error) {
+		var rows [][]driver.Value
+		for _, scope := range consented {
+			rows = append(rows, []driver.Value{args[0], "client", scope, time.Now()})
+		}
+		return []string{"user_id", "application_id", "scope", "granted"}, rows, nil
+	})
+}
+
+func TestConsentScopes(t *testing.T) {
+	scopes := []*scopedb.Scope{
+		{Name: "calendar", Description: "Your calendar", Claims: []string{"timezone"}},
+		{Name: "billing", Description: "Your invoices", Claims: []string{"invoice"}, Apps: []string{"other"}},
+	}
+	client := &appdb.Application{ID: "client"}
+	tests := []struct {
+		name      string
+		client    *appdb.Application
+		request   url.Values
+		userID    string
+		consented []string
+		want      []string
+	}{
+		{"openid needs no consent", client, url.Values{"scope": {"openid"}}, "", nil, nil},
+		{"registered scope", client, url.Values{"scope": {"openid calendar"}}, "", nil, []string{"calendar"}},
+		{"standard and unregistered scopes", client, url.Values{"scope": {"openid profile email offline_access custom"}}, "", nil,
+			[]string{"profile", "email", "offline_access", "custom"}},
+		{"duplicates", client, url.Values{"scope": {"custom calendar custom"}}, "", nil, []string{"custom", "calendar"}},
+		{"scope of other applications", client, url.Values{"scope": {"billing"}}, "", nil, nil},
+		{"already consented", client, url.Values{"scope": {"openid calendar custom"}}, "user", []string{"custom"}, []string{"calendar"}},
+		{"prompt=consent", client, url.Values{"scope": {"calendar custom"}, "prompt": {"consent"}}, "user", []string{"custom"},
+			[]string{"calendar", "custom"}},
+		{"claims parameter", client, url.Values{"scope": {"openid"},
+			"claims": {`{"userinfo": {"email": null, "timezone": null}, "id_token": {"name": {"essential": true}}}`}}, "", nil,
+			[]string{"email", "profile", "calendar"}},
+		{"claim of a scope of other applications", client, url.Values{"claims": {`{"userinfo": {"invoice": null}}`}}, "", nil, nil},
+		{"first party", &appdb.Application{ID: "client", FirstParty: true}, url.Values{"scope": {"calendar custom"}}, "", nil, nil},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			db := dbtest.Open()
+			registry(db, scopes, test.consented)
+			got, err := ConsentScopes(dbtest.Context(db, http.MethodGet, "/"), test.client, test.request, test.userID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if names := ScopeNames(got); !slices.Equal(names, test.want) && len(names)+len(test.want) > 0 {
+				t.Fatalf("got %v, want %v", names, test.want)
+			}
+		})
+	}
+}
+
+func TestScopesLoadedOncePerRequest(t *testing.T) {
+	db := dbtest.Open()
+	loads := 0
+	db.Procedure("list_scopes_full", func([]driver.Value) ([]string, [][]driver.Value, error) {
+		loads++
+		return []string{"name", "description", "created", "claim", "application_id"}, [][]driver.Value{
+			{"calendar", "Your calendar", time.Now(), "timezone", nil},
+			{"calendar", "Your calendar", time.Now(), "locale", nil},
+			{"calendar", "Your calendar", time.Now(), nil, "client"},
+		}, nil
+	})
+	ctx := dbtest.Context(db, http.MethodGet, "/authorize")
+	client := &appdb.Application{ID: "client"}
+	if !ValidateScopes(ctx, client, []string{"calendar"}) {
+		t.Fatal("calendar not allowed")
+	}
+	scopes, err := RegisteredScopes(ctx, client, []string{"calendar"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(scopes) != 1 || !slices.Equal(scopes[0].Claims, []string{"timezone", "locale"}) ||
+		!slices.Equal(scopes[0].Apps, []string{"client"}) {
+		t.Errorf("scopes = %+v", scopes)
+	}
+	if loads != 1 {
+		t.Errorf("registry loaded %d times, want once", loads)
+	}
+}
@@ -0,0 +1,204 @@
+package token
+
+import (
+	"errors"
+	"log/slog"
+	"slices"
+	"strings"
+	"uyulala/internal/db/sessiondb"
+	"uyulala/internal/mds"
+	"uyulala/openid/discovery"
+
+	"github.com/go-webauthn/webauthn/metadata"
+	"github.com/go-webauthn/webauthn/protocol"
+	"github.com/google/uuid"
+)
+
+// Authentication method references (RFC 8176).
+const (
+	AMRHardwareKey = "hwk"
+	AMRSoftwareKey = "swk"
+	AMRUser        = "user"
+	AMRPin         = "pin"
+	AMRFingerprint = "fpt"
+	AMRFace        = "face"
+	AMRVoice       = "vbm"
+	AMRIris        = "iris"
+	AMRLocation    = "geo"
+	AMRPattern     = "pattern"
+	AMRMultiFactor = "mfa"
+)
+
+const fido2ACRPrefix = "urn:fido2:"
+
+var ErrUnmetACR = errors.New("none of the requested acr values could be satisfied")
+
+// userVerificationAMR maps the FIDO user verification methods to authentication method references.
+var userVerificationAMR = map[string]string{
+	"presence_internal":    AMRUser,
+	"fingerprint_internal": AMRFingerprint,
+	"passcode_internal":    AMRPin,
+	"voiceprint_internal":  AMRVoice,
+	"faceprint_internal":   AMRFace,
+	"location_internal":    AMRLocation,
+	"eyeprint_internal":    AMRIris,
+	"pattern_internal":     AMRPattern,
+	"handprint_internal":   AMRFingerprint,
+	"passcode_external":    AMRPin,
+	"pattern_external":     AMRPattern,
+}
+
+// ACRValues are the authentication context class references uyulala can satisfy.
+var ACRValues = []string{
+	discovery.ACRUserPresence,
+	discovery.ACRPreferUserVerification,
+	discovery.ACRUserVerification,
+	discovery.ACRPresenceInternal,
+	discovery.ACRFingerPrintInternal,
+	discovery.ACRPasscodeInternal,
+	discovery.ACRVoiceprintInternal,
+	discovery.ACRFaceprintInternal,
+	discovery.ACRLocationInternal,
+	discovery.ACREyeprintInternal,
+	discovery.ACRPatternInternal,
+	discovery.ACRHandprintInternal,
+	discovery.ACRPasscodeExternal,
+	discovery.ACRPatternExternal,
+}
+
+// UserVerification returns the WebAuthn user verification requirement for the requested acr values.
+func UserVerification(acrValues []string) protocol.UserVerificationRequirement {
+	for _, acr := range acrValues {
+		if acr == discovery.ACRUserVerification ||
+			(strings.HasPrefix(acr, fido2ACRPrefix) && acr != discovery.ACRPresenceInternal) {
+			return protocol.VerificationRequired
+		}
+	}
+	if slices.Contains(acrValues, discovery.ACRPreferUserVerification) {
+		return protocol.VerificationPreferred
+	}
+	if slices.Contains(acrValues, discovery.ACRUserPresence) {
+		return protocol.VerificationDiscouraged
+	}
+	return protocol.VerificationPreferred
+}
+
+// userVerificationMethods returns the alternative combinations of user verification methods of the authenticator
+// according to the FIDO metadata service, or nil if the authenticator is unknown.
+func userVerificationMethods(assertion *Assertion) (*metadata.Entry, [][]string) {
+	if assertion.Credential == nil {
+		return nil, nil
+	}
+	aaguid, err := uuid.FromBytes(assertion.Credential.Authenticator.AAGUID)
+	if err != nil || aaguid == uuid.Nil {
+		return nil, nil
+	}
+	entry, err := mds.Get(aaguid)
+	if err != nil {
+		slog.Warn("Couldn't get authenticator metadata", "aaguid", aaguid, "err", err)
+		return nil, nil
+	}
+	if entry == nil {
+		return nil, nil
+	}
+	var methods [][]string
+	for _, combination := range entry.MetadataStatement.UserVerificationDetails {
+		var tmp []string
+		for _, descriptor := range combination {
+			tmp = append(tmp, descriptor.UserVerificationMethod)
+		}
+		methods = append(methods, tmp)
+	}
+	return entry, methods
+}
+
+// SetAuthenticationContext sets the acr and amr of a signed assertion. The acr is the first of acrValues that the
+// assertion satisfies, ErrUnmetACR is returned if there is none. The fido2 acr values and the amr are based on the
+// user verification methods the FIDO metadata service reports for the authenticator.
+func SetAuthenticationContext(assertion *Assertion, acrValues []string) error {
+	flags := assertion.Signature.Response.AuthenticatorData.Flags
+	entry, methods := userVerificationMethods(assertion)
+
+	satisfies := func(acr string) bool {
+		switch acr {
+		case discovery.ACRUserPresence, discovery.ACRPreferUserVerification:
+			return flags.UserPresent() || flags.UserVerified()
+		case discovery.ACRUserVerification:
+			return flags.UserVerified()
+		}
+		method, ok := strings.CutPrefix(acr, fido2ACRPrefix)
+		if !ok {
+			return false
+		}
+		if method == "presence_internal" && !flags.UserPresent() {
+			return false
+		}
+		if method != "presence_internal" && !flags.UserVerified() {
+			return false
+		}
+		for _, combination := range methods {
+			if slices.Contains(combination, method) {
+				return true
+			}
+		}
+		return false
+	}
+
+	assertion.ACR = ""
+	if len(acrValues) == 0 {
+		assertion.ACR = discovery.ACRUserPresence
+		if flags.UserVerified() {
+			assertion.ACR = discovery.ACRUserVerification
+		}
+	}
+	for _, acr := range acrValues {
+		if satisfies(acr) {
+			assertion.ACR = acr
+			break
+		}
+	}
+	if assertion.ACR == "" {
+		return ErrUnmetACR
+	}
+
+	amr := []string{discovery.AMRProofOfPossessionKey}
+	if entry != nil {
+		keyProtection := entry.MetadataStatement.KeyProtection
+		if slices.Contains(keyProtection, "hardware") || slices.Contains(keyProtection, "secure_element") ||
+			slices.Contains(keyProtection, "tee") {
+			amr = append(amr, AMRHardwareKey)
+		} else if slices.Contains(keyProtection, "software") {
+			amr = append(amr, AMRSoftwareKey)
+		}
+	}
+	if flags.UserPresent() {
+		amr = append(amr, AMRUser)
+	}
+	if flags.UserVerified() {
+		// The assertion doesn't tell which of several alternatives verified the user, only a single one is certain.
+		if len(methods) == 1 {
+			for _, method := range methods[0] {
+				if value, ok := userVerificationAMR[method]; ok && !slices.Contains(amr, value) {
+					amr = append(amr, value)
+				}
+			}
+		}
+		amr = append(amr, AMRMultiFactor)
+	}
+	assertion.AMR = amr
+	return nil
+}
+
+// SessionAuthentication returns the claims of the authentication the session started with, for the ID tokens of
+// refreshes, which have no assertion of their own (OpenID Connect Core 12.2).
+func SessionAuthentication(session *sessiondb.Session) map[string]any {
+	claims := map[string]any{}
+	if session.ACR != "" {
+		claims["acr"] = session.ACR
+		claims["amr"] = strings.Fields(session.AMR)
+	}
+	if session.AuthTime.Valid {
+		claims["auth_time"] = session.AuthTime.Time.Unix()
+	}
+	return claims
+}
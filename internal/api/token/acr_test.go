@@ -0,0 +1,39 @@
+package token
+
+import (
+	"database/sql"
+	"reflect"
+	"testing"
+	"time"
+	"uyulala/internal/db/sessiondb"
+	"uyulala/openid/discovery"
+)
+
+func TestSessionAuthentication(t *testing.T) {
+	authTime := time.Unix(1790000000, 0)
+	tests := []struct {
+		name    string
+		session *sessiondb.Session
+		want    map[string]any
+	}{
+		{"authentication context", &sessiondb.Session{
+			ACR:      discovery.ACRUserVerification,
+			AMR:      "hwk user mfa",
+			AuthTime: sql.NullTime{Time: authTime, Valid: true},
+		}, map[string]any{
+			"acr":       discovery.ACRUserVerification,
+			"amr":       []string{AMRHardwareKey, AMRUser, AMRMultiFactor},
+			"auth_time": authTime.Unix(),
+		}},
+		{"no acr", &sessiondb.Session{AuthTime: sql.NullTime{Time: authTime, Valid: true}},
+			map[string]any{"auth_time": authTime.Unix()}},
+		{"started before the context was stored", &sessiondb.Session{}, map[string]any{}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := SessionAuthentication(test.session); !reflect.DeepEqual(got, test.want) {
+				t.Errorf("claims = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
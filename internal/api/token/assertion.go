@@ -0,0 +1,100 @@
+package token
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"time"
+	"uyulala/internal/db/appdb"
+	"uyulala/openid/discovery"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lestrrat-go/jwx/jwa"
+	"github.com/lestrrat-go/jwx/jws"
+	"github.com/lestrrat-go/jwx/jwt"
+	"github.com/spf13/viper"
+)
+
+const ClientAssertionTypeJWT = "urn:ietf:params:oauth:client-assertion-type:jwt-bearer"
+
+// ClientAssertionAlgorithms are the algorithms accepted for client assertions.
+// The HMAC algorithms are used for client_secret_jwt, the others for private_key_jwt.
+var ClientAssertionAlgorithms = append([]string{"HS256", "HS384", "HS512"}, RequestObjectAlgorithms...)
+
+var (
+	ErrAssertionSubject  = errors.New("assertion iss and sub must be the client id")
+	ErrAssertionAudience = errors.New("assertion audience is not this server")
+	ErrAssertionClaims   = errors.New("assertion must have exp and jti")
+	ErrAssertionReplay   = errors.New("assertion has already been used")
+)
+
+// VerifyClientAssertion authenticates a client by a JWT assertion (RFC 7523), signed either with the client secret
+// (client_secret_jwt) or with one of the client keys (private_key_jwt).
+// The authenticated application is returned along with the authentication method used.
+func VerifyClientAssertion(ctx *gin.Context, assertion, clientID string) (*appdb.Application, string, error) {
+	unverified, err := jwt.ParseString(assertion)
+	if err != nil {
+		return nil, "", err
+	}
+	if unverified.Issuer() == "" || unverified.Issuer() != unverified.Subject() ||
+		(clientID != "" && clientID != unverified.Subject()) {
+		return nil, "", ErrAssertionSubject
+	}
+	app, err := appdb.GetApplication(ctx, unverified.Subject())
+	if err != nil {
+		return nil, "", err
+	}
+
+	msg, err := jws.ParseString(assertion)
+	if err != nil {
+		return nil, "", err
+	}
+	if len(msg.Signatures()) != 1 {
+		return nil, "", ErrUnsupportedAlg
+	}
+	alg := msg.Signatures()[0].ProtectedHeaders().Algorithm()
+	if !slices.Contains(ClientAssertionAlgorithms, alg.String()) {
+		return nil, "", ErrUnsupportedAlg
+	}
+	options := []jwt.ParseOption{
+		jwt.WithValidate(true),
+		jwt.WithIssuer(app.ID),
+		jwt.WithSubject(app.ID),
+		jwt.WithAcceptableSkew(time.Minute),
+	}
+	var method string
+	if strings.HasPrefix(alg.String(), "HS") {
+		method = discovery.TokenAuthClientSecretJWT
+		options = append(options, jwt.WithVerify(jwa.SignatureAlgorithm(alg), []byte(app.Secret)))
+	} else {
+		method = discovery.TokenAuthPrivateKeyJWT
+		keySet, err := ClientKeySet(ctx, app)
+		if err != nil {
+			return nil, "", err
+		}
+		options = append(options, jwt.WithKeySet(keySet), jwt.InferAlgorithmFromKey(true), jwt.UseDefaultKey(true))
+	}
+	token, err := jwt.ParseString(assertion, options...)
+	if err != nil {
+		return nil, "", err
+	}
+
+	// The audience is either the issuer or the endpoint the assertion is presented to.
+	issuer := viper.GetString("issuer")
+	if !slices.ContainsFunc(token.Audience(), func(aud string) bool {
+		return aud == issuer || slices.Contains(endpointURLs(ctx), aud)
+	}) {
+		return nil, "", ErrAssertionAudience
+	}
+	if token.Expiration().IsZero() || token.JwtID() == "" {
+		return nil, "", ErrAssertionClaims
+	}
+	unused, err := appdb.UseAssertionID(ctx, app.ID, token.JwtID(), token.Expiration())
+	if err != nil {
+		return nil, "", err
+	}
+	if !unused {
+		return nil, "", ErrAssertionReplay
+	}
+	return app, method, nil
+}
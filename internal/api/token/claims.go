@@ -0,0 +1,133 @@
+package token
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"slices"
+	"uyulala/internal/api"
+	"uyulala/internal/db/appdb"
+	"uyulala/internal/db/scopedb"
+	"uyulala/internal/db/userdb"
+
+	"github.com/gin-gonic/gin"
+)
+
+var (
+	ErrReservedAttribute = errors.New("attribute name is reserved")
+	ErrInvalidAttribute  = errors.New("email_verified must be a boolean and the other standard claims strings")
+)
+
+// ClaimScopes returns the requested scopes that release profile attributes of the user: the standard scopes and the
+// registered scopes the application may request.
+func ClaimScopes(ctx *gin.Context, app *appdb.Application, scopes []string) ([]string, error) {
+	registered, err := api.RegisteredScopes(ctx, app, scopes)
+	if err != nil {
+		api.AbortError(ctx, http.StatusInternalServerError, "internal_error", "Unexpected error", err)
+		return nil, err
+	}
+	var res []string
+	for _, scope := range scopes {
+		if slices.Contains(api.StandardScopes, scope) && !slices.Contains(res, scope) {
+			res = append(res, scope)
+		}
+	}
+	for _, name := range api.ScopeNames(registered) {
+		if !slices.Contains(res, name) {
+			res = append(res, name)
+		}
+	}
+	return res, nil
+}
+
+// UserClaims returns the profile attributes of the user released to the application by scopes, together with the
+// individually requested claims (see api.ClaimsRequest) that are standard claims or granted by a scope the
+// application may request. Third party applications only get the claims of the scopes the user has consented to.
+func UserClaims(ctx *gin.Context, app *appdb.Application, userID string, scopes []string, requested map[string]json.RawMessage) (map[string]any, error) {
+	claims, err := userClaims(ctx, app, userID, scopes, requested)
+	if err != nil {
+		api.AbortError(ctx, http.StatusInternalServerError, "internal_error", "Unexpected error", err)
+		return nil, err
+	}
+	return claims, nil
+}
+
+func userClaims(ctx *gin.Context, app *appdb.Application, userID string, scopes []string, requested map[string]json.RawMessage) (map[string]any, error) {
+	granted := func(string) bool { return true }
+	if !app.FirstParty {
+		consented, err := userdb.ConsentedScopes(ctx, userID, app.ID)
+		if err != nil {
+			return nil, err
+		}
+		granted = func(scope string) bool { return slices.Contains(consented, scope) }
+	}
+
+	var released []string
+	for _, scope := range scopes {
+		if granted(scope) {
+			released = append(released, api.StandardClaims[scope]...)
+		}
+	}
+	registered, err := scopedb.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, scope := range registered {
+		if !scope.Allowed(app.ID) || !granted(scope.Name) {
+			continue
+		}
+		if slices.Contains(scopes, scope.Name) {
+			released = append(released, scope.Claims...)
+		}
+		for name := range requested {
+			if slices.Contains(scope.Claims, name) {
+				released = append(released, name)
+			}
+		}
+	}
+	for name := range requested {
+		for scope, claims := range api.StandardClaims {
+			if granted(scope) && slices.Contains(claims, name) {
+				released = append(released, name)
+			}
+		}
+	}
+	claims := map[string]any{}
+	if len(released) == 0 {
+		return claims, nil
+	}
+	attributes, err := userdb.GetAttributes(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	for name, value := range attributes {
+		if slices.Contains(released, name) {
+			claims[name] = value
+		}
+	}
+	return claims, nil
+}
+
+// ValidateAttributes checks that profile attributes don't collide with the claims set by the server, and that the
+// standard claims have the type OpenID Connect specifies. A nil value removes the attribute.
+func ValidateAttributes(attributes map[string]any) error {
+	for name, value := range attributes {
+		if name == "" || slices.Contains(ReservedClaims, name) || name == "authorization_details" {
+			return ErrReservedAttribute
+		}
+		if value == nil {
+			continue
+		}
+		switch {
+		case name == "email_verified":
+			if _, ok := value.(bool); !ok {
+				return ErrInvalidAttribute
+			}
+		case api.IsStandardClaim(name):
+			if _, ok := value.(string); !ok {
+				return ErrInvalidAttribute
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,81 @@
+package token
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+	"uyulala/internal/db/appdb"
+	"uyulala/internal/db/dbtest"
+	"uyulala/internal/db/scopedb"
+)
+
+// profileDB returns a database with the registered scope calendar releasing timezone, a user with attributes and the
+// scopes the user has consented to.
+func profileDB(attributes map[string]any, consented []string) *dbtest.DB {
+	db := dbtest.Open()
+	db.Scopes([]*scopedb.Scope{{Name: "calendar", Description: "Your calendar", Claims: []string{"timezone"}}})
+	db.Procedure("list_consents_for_user", func(args []driver.Value) ([]string, [][]driver.Value, error) {
+		var rows [][]driver.Value
+		for _, scope := range consented {
+			rows = append(rows, []driver.Value{args[0], "client", scope, time.Now()})
+		}
+		return []string{"user_id", "application_id", "scope", "granted"}, rows, nil
+	})
+	db.Procedure("get_user_attributes", func([]driver.Value) ([]string, [][]driver.Value, error) {
+		var rows [][]driver.Value
+		for name, value := range attributes {
+			data, _ := json.Marshal(value)
+			rows = append(rows, []driver.Value{name, string(data)})
+		}
+		return []string{"name", "value"}, rows, nil
+	})
+	return db
+}
+
+func TestUserClaims(t *testing.T) {
+	attributes := map[string]any{
+		"name":           "Kalle Anka",
+		"email":          "kalle@example.com",
+		"email_verified": true,
+		"timezone":       "Europe/Stockholm",
+		"department":     "Ducks",
+	}
+	thirdParty := &appdb.Application{ID: "client"}
+	firstParty := &appdb.Application{ID: "client", FirstParty: true}
+	tests := []struct {
+		name      string
+		app       *appdb.Application
+		scopes    []string
+		requested map[string]json.RawMessage
+		consented []string
+		want      map[string]any
+	}{
+		{"consented scopes", thirdParty, []string{"openid", "email", "calendar"}, nil, []string{"email", "calendar"},
+			map[string]any{"email": "kalle@example.com", "email_verified": true, "timezone": "Europe/Stockholm"}},
+		{"scopes without consent", thirdParty, []string{"openid", "profile", "email", "calendar"}, nil, []string{"email"},
+			map[string]any{"email": "kalle@example.com", "email_verified": true}},
+		{"requested claim with consent", thirdParty, []string{"openid"}, map[string]json.RawMessage{"name": nil, "timezone": nil},
+			[]string{"profile"}, map[string]any{"name": "Kalle Anka"}},
+		{"requested claim without consent", thirdParty, []string{"openid"}, map[string]json.RawMessage{"email": nil}, nil,
+			map[string]any{}},
+		{"unreleased attribute", thirdParty, []string{"openid"}, map[string]json.RawMessage{"department": nil},
+			[]string{"profile", "email", "calendar"}, map[string]any{}},
+		{"first party", firstParty, []string{"openid", "profile"}, map[string]json.RawMessage{"timezone": nil}, nil,
+			map[string]any{"name": "Kalle Anka", "timezone": "Europe/Stockholm"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ctx := dbtest.Context(profileDB(attributes, test.consented), http.MethodGet, "/")
+			got, err := UserClaims(ctx, test.app, "user", test.scopes, test.requested)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Fatalf("got %v, want %v", got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,65 @@
+package token
+
+import (
+	"errors"
+	"uyulala/internal/mtls"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lestrrat-go/jwx/jwt"
+	"github.com/spf13/viper"
+)
+
+var (
+	ErrConfirmation     = errors.New("token is bound to another client certificate")
+	ErrDPoPConfirmation = errors.New("token is bound to another DPoP key")
+)
+
+// Confirmation returns the cnf claim binding the tokens issued for this request to the client certificate of the
+// connection (RFC 8705) and the DPoP key of the request (RFC 9449). nil is returned when there is nothing to bind to.
+func Confirmation(ctx *gin.Context) map[string]any {
+	cnf := map[string]any{}
+	if cert := mtls.Certificate(ctx.Request); cert != nil {
+		cnf[mtls.ConfirmationKey] = mtls.Thumbprint(cert)
+	}
+	if jkt := dpopKey(ctx); jkt != "" {
+		cnf[DPoPConfirmationKey] = jkt
+	}
+	if len(cnf) == 0 {
+		return nil
+	}
+	return cnf
+}
+
+// VerifyConfirmation checks that a token with a cnf claim is presented by the client it is bound to.
+// Tokens without a cnf claim are bearer tokens and always pass.
+func VerifyConfirmation(ctx *gin.Context, token jwt.Token) error {
+	if thumbprint := confirmationMember(token, mtls.ConfirmationKey); thumbprint != "" {
+		cert := mtls.Certificate(ctx.Request)
+		if cert == nil || mtls.Thumbprint(cert) != thumbprint {
+			return ErrConfirmation
+		}
+	}
+	if jkt := confirmationMember(token, DPoPConfirmationKey); jkt != "" && jkt != dpopKey(ctx) {
+		return ErrDPoPConfirmation
+	}
+	return nil
+}
+
+func confirmationMember(token jwt.Token, name string) string {
+	value, ok := token.Get("cnf")
+	if !ok {
+		return ""
+	}
+	cnf, _ := value.(map[string]any)
+	member, _ := cnf[name].(string)
+	return member
+}
+
+// endpointURLs returns the URLs of the requested endpoint on the issuer and on the mutual-TLS listener.
+func endpointURLs(ctx *gin.Context) []string {
+	urls := []string{viper.GetString("issuer") + ctx.Request.URL.Path}
+	if issuer := mtls.Issuer(); issuer != "" {
+		urls = append(urls, issuer+ctx.Request.URL.Path)
+	}
+	return urls
+}
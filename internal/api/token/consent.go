@@ -0,0 +1,29 @@
+package token
+
+import (
+	"uyulala/internal/db/sessiondb"
+	"uyulala/internal/db/userdb"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RevokeConsent removes the consents the user has granted to the application, and ends the sessions of the
+// application for the user so its refresh tokens stop working.
+func RevokeConsent(ctx *gin.Context, userID, appID string) error {
+	sessions, err := sessiondb.ListForUser(ctx, userID)
+	if err != nil {
+		return err
+	}
+	for _, sess := range sessions {
+		if sess.AppID != appID {
+			continue
+		}
+		if err := sessiondb.Delete(ctx, sess.ID); err != nil {
+			return err
+		}
+		if err := BackChannelLogout(ctx, sess); err != nil {
+			return err
+		}
+	}
+	return userdb.RevokeConsent(ctx, userID, appID)
+}
@@ -0,0 +1,12 @@
+package token
+
+import "encoding/json"
+
+// AuthorizationDetailsClaims returns the claims of the access token for the canonical authorization details
+// (RFC 9396 section 9.1) the user signed, if there are any.
+func AuthorizationDetailsClaims(details string) map[string]any {
+	if details == "" {
+		return nil
+	}
+	return map[string]any{"authorization_details": json.RawMessage(details)}
+}
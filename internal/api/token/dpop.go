@@ -0,0 +1,147 @@
+package token
+
+import (
+	"crypto"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+	"uyulala/internal/db/keydb"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lestrrat-go/jwx/jwk"
+	"github.com/lestrrat-go/jwx/jws"
+	"github.com/lestrrat-go/jwx/jwt"
+)
+
+const (
+	TypeDPoP = "dpop+jwt"
+
+	// DPoPConfirmationKey is the cnf member binding a token to a DPoP key (RFC 9449 section 6).
+	DPoPConfirmationKey = "jkt"
+
+	dpopKeyContext = "dpop_jkt"
+	dpopMaxAge     = 5 * time.Minute
+)
+
+var (
+	ErrDPoPMissing    = errors.New("missing DPoP proof")
+	ErrDPoPType       = errors.New("DPoP proof has the wrong typ")
+	ErrDPoPKey        = errors.New("DPoP proof must contain a public jwk")
+	ErrDPoPClaims     = errors.New("DPoP proof must have jti, iat, htm and htu")
+	ErrDPoPExpired    = errors.New("DPoP proof is too old")
+	ErrDPoPRequest    = errors.New("DPoP proof was made for another request")
+	ErrDPoPTokenHash  = errors.New("DPoP proof was made for another access token")
+	ErrDPoPReplay     = errors.New("DPoP proof has already been used")
+	ErrDPoPUnboundKey = errors.New("access token is not bound to a DPoP key")
+)
+
+// VerifyDPoPProof verifies a DPoP proof (RFC 9449) for the current request and returns the thumbprint of its key.
+// accessToken is the token the proof is presented with at a resource, and empty at the token endpoint.
+func VerifyDPoPProof(ctx *gin.Context, proof, accessToken string) (string, error) {
+	if proof == "" {
+		return "", ErrDPoPMissing
+	}
+	msg, err := jws.ParseString(proof)
+	if err != nil {
+		return "", err
+	}
+	if len(msg.Signatures()) != 1 {
+		return "", ErrUnsupportedAlg
+	}
+	headers := msg.Signatures()[0].ProtectedHeaders()
+	if !strings.EqualFold(headers.Type(), TypeDPoP) {
+		return "", ErrDPoPType
+	}
+	if !slices.Contains(RequestObjectAlgorithms, headers.Algorithm().String()) {
+		return "", ErrUnsupportedAlg
+	}
+	key := headers.JWK()
+	switch key.(type) {
+	case nil, jwk.RSAPrivateKey, jwk.ECDSAPrivateKey, jwk.OKPPrivateKey, jwk.SymmetricKey:
+		return "", ErrDPoPKey
+	}
+	token, err := jwt.ParseString(proof,
+		jwt.WithVerify(headers.Algorithm(), key),
+		jwt.WithValidate(true),
+		jwt.WithAcceptableSkew(time.Minute))
+	if err != nil {
+		return "", err
+	}
+	htm, _ := token.Get("htm")
+	htu, _ := token.Get("htu")
+	htmString, _ := htm.(string)
+	htuString, _ := htu.(string)
+	if token.JwtID() == "" || token.IssuedAt().IsZero() || htmString == "" || htuString == "" {
+		return "", ErrDPoPClaims
+	}
+	if time.Since(token.IssuedAt()).Abs() > dpopMaxAge {
+		return "", ErrDPoPExpired
+	}
+	target, err := url.Parse(htuString)
+	if err != nil {
+		return "", err
+	}
+	target.RawQuery = ""
+	target.Fragment = ""
+	if htmString != ctx.Request.Method || !slices.Contains(endpointURLs(ctx), target.String()) {
+		return "", ErrDPoPRequest
+	}
+	if accessToken != "" {
+		ath, _ := token.Get("ath")
+		sum := sha256.Sum256([]byte(accessToken))
+		if ath != base64.RawURLEncoding.EncodeToString(sum[:]) {
+			return "", ErrDPoPTokenHash
+		}
+	}
+	thumbprint, err := key.Thumbprint(crypto.SHA256)
+	if err != nil {
+		return "", err
+	}
+	jkt := base64.RawURLEncoding.EncodeToString(thumbprint)
+	unused, err := keydb.UseProofID(ctx, jkt, token.JwtID(), token.IssuedAt().Add(dpopMaxAge))
+	if err != nil {
+		return "", err
+	}
+	if !unused {
+		return "", ErrDPoPReplay
+	}
+	return jkt, nil
+}
+
+// SetDPoPKey records the DPoP key the request was made with, see Confirmation.
+func SetDPoPKey(ctx *gin.Context, jkt string) {
+	ctx.Set(dpopKeyContext, jkt)
+}
+
+func dpopKey(ctx *gin.Context) string {
+	return ctx.GetString(dpopKeyContext)
+}
+
+// Type returns the token_type of the access tokens issued for this request.
+func Type(ctx *gin.Context) string {
+	if dpopKey(ctx) != "" {
+		return "DPoP"
+	}
+	return "Bearer"
+}
+
+// VerifyResourceAccess checks the proof of possession an access token requires at a resource.
+// A token presented with the DPoP authorization scheme needs a DPoP proof for its key, and a token bound to a client
+// certificate needs that certificate.
+func VerifyResourceAccess(ctx *gin.Context, scheme, accessToken string, token jwt.Token) error {
+	if strings.EqualFold(scheme, "dpop") {
+		jkt, err := VerifyDPoPProof(ctx, ctx.GetHeader("DPoP"), accessToken)
+		if err != nil {
+			return err
+		}
+		if confirmationMember(token, DPoPConfirmationKey) == "" {
+			return ErrDPoPUnboundKey
+		}
+		SetDPoPKey(ctx, jkt)
+	}
+	return VerifyConfirmation(ctx, token)
+}
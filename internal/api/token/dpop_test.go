@@ -0,0 +1,93 @@
+package token
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+	"uyulala/internal/db/dbtest"
+
+	"github.com/lestrrat-go/jwx/jwa"
+	"github.com/lestrrat-go/jwx/jwk"
+	"github.com/lestrrat-go/jwx/jws"
+	"github.com/lestrrat-go/jwx/jwt"
+	"github.com/spf13/viper"
+	"gitlab.com/daedaluz/gindb"
+)
+
+func dpopProof(t *testing.T, method, htu, jti string) string {
+	t.Helper()
+	private, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	public, err := jwk.New(private.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	proof := jwt.New()
+	_ = proof.Set(jwt.JwtIDKey, jti)
+	_ = proof.Set(jwt.IssuedAtKey, time.Now())
+	_ = proof.Set("htm", method)
+	_ = proof.Set("htu", htu)
+	headers := jws.NewHeaders()
+	_ = headers.Set(jws.TypeKey, TypeDPoP)
+	_ = headers.Set(jws.JWKKey, public)
+	data, err := jwt.Sign(proof, jwa.ES256, private, jwt.WithJwsHeaders(headers))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestDPoPProofReplayAfterFailedRequest(t *testing.T) {
+	viper.Set("issuer", "https://issuer.example")
+	db := dbtest.Open()
+	proof := dpopProof(t, http.MethodPost, "https://issuer.example/api/v1/oauth2/token", "proof-1")
+
+	ctx := dbtest.Context(db, http.MethodPost, "/api/v1/oauth2/token")
+	if _, err := VerifyDPoPProof(ctx, proof, ""); err != nil {
+		t.Fatalf("first use: %v", err)
+	}
+	// The token request fails after the proof was verified.
+	if err := gindb.Rollback(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx = dbtest.Context(db, http.MethodPost, "/api/v1/oauth2/token")
+	if _, err := VerifyDPoPProof(ctx, proof, ""); !errors.Is(err, ErrDPoPReplay) {
+		t.Fatalf("replay after a failed request: got %v, want %v", err, ErrDPoPReplay)
+	}
+}
+
+func TestDPoPProofRequest(t *testing.T) {
+	viper.Set("issuer", "https://issuer.example")
+	viper.Set("tls.clientAuth", true)
+	viper.Set("tls.mtlsAddr", ":8443")
+	defer viper.Set("tls.clientAuth", false)
+	db := dbtest.Open()
+	tests := []struct {
+		name   string
+		method string
+		htu    string
+		want   error
+	}{
+		{"other method", http.MethodGet, "https://issuer.example/api/v1/oauth2/token", ErrDPoPRequest},
+		{"other endpoint", http.MethodPost, "https://issuer.example/api/v1/oauth2/userinfo", ErrDPoPRequest},
+		{"query ignored", http.MethodPost, "https://issuer.example/api/v1/oauth2/token?x=1", nil},
+		{"mutual-TLS alias", http.MethodPost, "https://issuer.example:8443/api/v1/oauth2/token", nil},
+		{"other port", http.MethodPost, "https://issuer.example:9443/api/v1/oauth2/token", ErrDPoPRequest},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ctx := dbtest.Context(db, http.MethodPost, "/api/v1/oauth2/token")
+			_, err := VerifyDPoPProof(ctx, dpopProof(t, test.method, test.htu, test.name), "")
+			if !errors.Is(err, test.want) {
+				t.Fatalf("got %v, want %v", err, test.want)
+			}
+		})
+	}
+}
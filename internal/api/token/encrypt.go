@@ -0,0 +1,86 @@
+package token
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"uyulala/internal/db/appdb"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lestrrat-go/jwx/jwa"
+	"github.com/lestrrat-go/jwx/jwe"
+	"github.com/lestrrat-go/jwx/jwk"
+)
+
+// EncryptionAlgorithms are the key management algorithms tokens can be encrypted to the client keys with.
+var EncryptionAlgorithms = []string{
+	"RSA-OAEP", "RSA-OAEP-256",
+	"ECDH-ES", "ECDH-ES+A128KW", "ECDH-ES+A256KW",
+}
+
+// EncryptionEncodings are the content encryption algorithms of encrypted tokens.
+var EncryptionEncodings = []string{
+	"A128CBC-HS256", "A256CBC-HS512",
+	"A128GCM", "A256GCM",
+}
+
+// DefaultEncryptionEncoding is used when a client registers an encryption algorithm without an encoding.
+const DefaultEncryptionEncoding = "A128CBC-HS256"
+
+var ErrNoEncryptionKey = errors.New("client has no key for the encryption algorithm")
+
+// Encrypt encrypts payload to the key of app for alg. contentType is set as cty header, "JWT" for nested tokens.
+func Encrypt(ctx *gin.Context, app *appdb.Application, payload []byte, alg, enc, contentType string) (string, error) {
+	if enc == "" {
+		enc = DefaultEncryptionEncoding
+	}
+	keySet, err := ClientKeySet(ctx, app)
+	if err != nil {
+		return "", err
+	}
+	key, err := encryptionKey(ctx, keySet, alg)
+	if err != nil {
+		return "", err
+	}
+	hdrs := jwe.NewHeaders()
+	if contentType != "" {
+		_ = hdrs.Set(jwe.ContentTypeKey, contentType)
+	}
+	data, err := jwe.Encrypt(payload, jwa.KeyEncryptionAlgorithm(alg), key, jwa.ContentEncryptionAlgorithm(enc),
+		jwa.NoCompress, jwe.WithProtectedHeaders(hdrs))
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// encryptionKey returns the first key of the set that can be used to encrypt with alg.
+func encryptionKey(ctx context.Context, keySet jwk.Set, alg string) (jwk.Key, error) {
+	kty := jwa.RSA
+	if strings.HasPrefix(alg, "ECDH-ES") {
+		kty = jwa.EC
+	}
+	for iter := keySet.Iterate(ctx); iter.Next(ctx); {
+		key := iter.Pair().Value.(jwk.Key)
+		if key.KeyType() != kty && !(kty == jwa.EC && key.KeyType() == jwa.OKP) {
+			continue
+		}
+		if use := key.KeyUsage(); use != "" && use != string(jwk.ForEncryption) {
+			continue
+		}
+		if a := key.Algorithm(); a != "" && a != alg {
+			continue
+		}
+		return key, nil
+	}
+	return nil, ErrNoEncryptionKey
+}
+
+// ValidEncryption checks a registered pair of encryption algorithm and encoding.
+func ValidEncryption(alg, enc string) bool {
+	if alg == "" {
+		return enc == ""
+	}
+	return slices.Contains(EncryptionAlgorithms, alg) && (enc == "" || slices.Contains(EncryptionEncodings, enc))
+}
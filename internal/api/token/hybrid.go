@@ -0,0 +1,87 @@
+package token
+
+import (
+	"crypto"
+	"encoding/base64"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"uyulala/internal/api"
+	"uyulala/internal/db/appdb"
+	"uyulala/openid/discovery"
+
+	"github.com/gin-gonic/gin"
+)
+
+// tokenHash returns the base64url encoded left half of the hash of value, using the hash of the ID token signing
+// algorithm, as used for c_hash and at_hash (OpenID Connect Core 3.3.2.11). EdDSA hashes with SHA-512, the hash of
+// Ed25519.
+func tokenHash(alg, value string) string {
+	hash := crypto.SHA256
+	switch {
+	case alg == "EdDSA":
+		hash = crypto.SHA512
+	case strings.HasSuffix(alg, "384"):
+		hash = crypto.SHA384
+	case strings.HasSuffix(alg, "512"):
+		hash = crypto.SHA512
+	}
+	h := hash.New()
+	h.Write([]byte(value))
+	sum := h.Sum(nil)
+	return base64.RawURLEncoding.EncodeToString(sum[:len(sum)/2])
+}
+
+// FrontChannelTokens issues the tokens returned on the redirect by the implicit and hybrid flows. The ID token
+// contains c_hash for code and at_hash for the access token, which is a bearer token since no client authenticated.
+// The authentication context of the assertion must have been set with SetAuthenticationContext.
+func FrontChannelTokens(ctx *gin.Context, app *appdb.Application, userID string, assertion *Assertion,
+	request url.Values, code string) (url.Values, error) {
+	responseTypes := strings.Fields(api.ResponseType(request.Get("response_type")))
+	appKey, err := SigningKey(ctx, app)
+	if err != nil {
+		return nil, err
+	}
+	params := url.Values{}
+	grantedScopes, err := ClaimScopes(ctx, app, api.SpaceDelimited(request.Get("scope")))
+	if err != nil {
+		return nil, err
+	}
+	claimsRequest, err := api.ParseClaimsRequest(request.Get("claims"))
+	if err != nil {
+		api.AbortError(ctx, http.StatusBadRequest, "invalid_request", err.Error(), err)
+		return nil, err
+	}
+	claims, err := UserClaims(ctx, app, userID, grantedScopes, claimsRequest.IDToken)
+	if err != nil {
+		return nil, err
+	}
+	if code != "" {
+		claims["c_hash"] = tokenHash(appKey.Algorithm(), code)
+	}
+	if slices.Contains(responseTypes, discovery.ResponseTypeToken) {
+		subject, err := Subject(ctx, app, userID)
+		if err != nil {
+			api.AbortError(ctx, http.StatusInternalServerError, "internal_error", "Unexpected error", err)
+			return nil, err
+		}
+		accessToken, err := bindAccessToken(ctx, "", userID, subject, strings.Join(grantedScopes, " "), appKey, app, assertion, nil, nil, nil)
+		if err != nil {
+			return nil, err
+		}
+		params.Set("access_token", accessToken)
+		params.Set("token_type", "Bearer")
+		params.Set("expires_in", strconv.Itoa(int(AccessTokenLength(app).Seconds())))
+		claims["at_hash"] = tokenHash(appKey.Algorithm(), accessToken)
+	}
+	if slices.Contains(responseTypes, discovery.ResponseTypeIDToken) {
+		idToken, err := IDToken(ctx, "", userID, request.Get("nonce"), app, appKey, assertion, claims)
+		if err != nil {
+			return nil, err
+		}
+		params.Set("id_token", idToken)
+	}
+	return params, nil
+}
@@ -0,0 +1,43 @@
+package token
+
+import (
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"testing"
+)
+
+func TestTokenHash(t *testing.T) {
+	const value = "jHkWEdUXMU1BwAsC4vtUsZwnNvTIxEl0z9K3vx5KF0Y"
+	sum256 := sha256.Sum256([]byte(value))
+	sum384 := sha512.Sum384([]byte(value))
+	sum512 := sha512.Sum512([]byte(value))
+	half := func(sum []byte) string {
+		return base64.RawURLEncoding.EncodeToString(sum[:len(sum)/2])
+	}
+	tests := []struct {
+		alg  string
+		want string
+	}{
+		// The at_hash of the example in OpenID Connect Core A.4.
+		{"RS256", "77QmUPtjPfzWtF2AnpK9RQ"},
+		{"PS256", half(sum256[:])},
+		{"ES256", half(sum256[:])},
+		{"HS256", half(sum256[:])},
+		{"RS384", half(sum384[:])},
+		{"PS384", half(sum384[:])},
+		{"ES384", half(sum384[:])},
+		{"RS512", half(sum512[:])},
+		{"PS512", half(sum512[:])},
+		{"ES512", half(sum512[:])},
+		{"EdDSA", half(sum512[:])},
+	}
+	for _, test := range tests {
+		t.Run(test.alg, func(t *testing.T) {
+			got := tokenHash(test.alg, value)
+			if got != test.want {
+				t.Errorf("tokenHash = %s, want %s", got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,159 @@
+package token
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"uyulala/internal/api"
+	"uyulala/internal/db/appdb"
+	"uyulala/internal/db/challengedb"
+	"uyulala/internal/db/sessiondb"
+	"uyulala/internal/db/userdb"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Issue marks a signed OAuth2 challenge as collected and creates the tokens for the scopes it was requested with.
+// It is shared by the authorization code and CIBA grants, and used for CIBA push delivery. resources are the resource
+// parameters of the token request, see GrantResources.
+func Issue(ctx *gin.Context, app *appdb.Application, challenge *challengedb.Data, resources []string) (*Response, error) {
+	return issue(ctx, app, challenge, resources, "")
+}
+
+// IssuePush issues the tokens of a signed CIBA request for push delivery to the client. The ID token identifies the
+// request with its auth_req_id and binds the delivered tokens with at_hash and rt_hash (CIBA Core 10.3.1).
+// Push delivery happens during the request of the user signing, so the tokens are bearer tokens: the certificate or
+// DPoP key of that request isn't the client's.
+func IssuePush(ctx *gin.Context, app *appdb.Application, challenge *challengedb.Data, requestID string) (*Response, error) {
+	return issue(ctx, app, challenge, nil, requestID)
+}
+
+func issue(ctx *gin.Context, app *appdb.Application, challenge *challengedb.Data, resources []string,
+	requestID string) (*Response, error) {
+	var (
+		idToken      string
+		accessToken  string
+		refreshToken string
+		sessionID    string
+		resultScopes []string
+	)
+	appKey, err := SigningKey(ctx, app)
+	if err != nil {
+		return nil, err
+	}
+	oauth2Ctx := challenge.GetOAuth2Context()
+	resourceServers, err := GrantResources(ctx, resources, oauth2Ctx["resource"])
+	if err != nil {
+		return nil, err
+	}
+	cnf, tokenType := Confirmation(ctx), Type(ctx)
+	if requestID != "" {
+		cnf, tokenType = nil, "Bearer"
+	}
+	if err := challengedb.SetChallengeStatus(ctx, challenge.ID, challengedb.StatusCollected); err != nil {
+		api.AbortError(ctx, http.StatusInternalServerError, "internal_error", "Unexpected error", err)
+		return nil, err
+	}
+	assertion := AssertionFromChallenge(challenge)
+	scopes := api.SpaceDelimited(oauth2Ctx.Get("scope"))
+	if err := SetAuthenticationContext(assertion, strings.Fields(oauth2Ctx.Get("acr_values"))); err != nil {
+		api.AbortError(ctx, http.StatusBadRequest, "unmet_authentication_requirements", "The requested acr_values could not be satisfied", err)
+		return nil, err
+	}
+	userKey, err := userdb.GetKey(ctx, assertion.Signature.RawID)
+	if err != nil {
+		api.AbortError(ctx, http.StatusInternalServerError, "internal_error", "Unexpected error", err)
+		return nil, err
+	}
+	authTime, err := userdb.GetAuthTime(ctx, userKey.UserID, app.ID)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		api.AbortError(ctx, http.StatusInternalServerError, "internal_error", "Unexpected error", err)
+		return nil, err
+	}
+	if maxAge := oauth2Ctx.Get("max_age"); maxAge != "" {
+		seconds, _ := strconv.Atoi(maxAge)
+		if authTime.Before(assertion.Signed.Add(-time.Duration(seconds) * time.Second)) {
+			err := errors.New("max_age exceeded")
+			api.AbortError(ctx, http.StatusBadRequest, "invalid_grant", "The user did not authenticate within max_age", err)
+			return nil, err
+		}
+	}
+
+	grantedScopes, err := ClaimScopes(ctx, app, scopes)
+	if err != nil {
+		return nil, err
+	}
+	claimsRequest, err := api.ParseClaimsRequest(oauth2Ctx.Get("claims"))
+	if err != nil {
+		api.AbortError(ctx, http.StatusBadRequest, "invalid_request", err.Error(), err)
+		return nil, err
+	}
+
+	if slices.Contains(scopes, "offline_access") {
+		sess, err := sessiondb.Create(ctx, userKey.UserID, app.ID, oauth2Ctx.Get("scope"),
+			strings.Join(oauth2Ctx["resource"], " "), oauth2Ctx.Get("authorization_details"), oauth2Ctx.Get("claims"),
+			assertion.ACR, assertion.AMR, authTime, RefreshTokenLength(app))
+		if err != nil {
+			api.AbortError(ctx, http.StatusInternalServerError, "internal_error", "Unexpected error", err)
+			return nil, err
+		}
+		resultScopes = append(resultScopes, "offline_access")
+		sessionID = sess.ID
+		refreshToken, err = sess.CreateRefreshToken(appKey, cnf)
+		if err != nil {
+			api.AbortError(ctx, http.StatusInternalServerError, "internal_error", "Unexpected error", err)
+			return nil, err
+		}
+		subject, err := Subject(ctx, app, userKey.UserID)
+		if err != nil {
+			api.AbortError(ctx, http.StatusInternalServerError, "internal_error", "Unexpected error", err)
+			return nil, err
+		}
+		scope := strings.Join(grantedScopes, " ")
+		if len(resourceServers) > 0 {
+			scope = oauth2Ctx.Get("scope")
+		}
+		accessToken, err = bindAccessToken(ctx, sessionID, userKey.UserID, subject, scope, appKey, app, assertion, cnf,
+			resourceServers, AuthorizationDetailsClaims(oauth2Ctx.Get("authorization_details")))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if slices.Contains(scopes, "openid") {
+		resultScopes = append(resultScopes, "openid")
+		claims, err := UserClaims(ctx, app, userKey.UserID, grantedScopes, claimsRequest.IDToken)
+		if err != nil {
+			return nil, err
+		}
+		if requestID != "" {
+			claims[AuthReqIDClaim] = requestID
+			if accessToken != "" {
+				claims["at_hash"] = tokenHash(appKey.Algorithm(), accessToken)
+			}
+			if refreshToken != "" {
+				claims["rt_hash"] = tokenHash(appKey.Algorithm(), refreshToken)
+			}
+		}
+		idToken, err = IDToken(ctx, sessionID, userKey.UserID, oauth2Ctx.Get("nonce"), app, appKey, assertion, claims)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if accessToken != "" || idToken != "" {
+		resultScopes = append(resultScopes, grantedScopes...)
+	}
+	return &Response{
+		AccessToken:          accessToken,
+		Scope:                strings.Join(resultScopes, " "),
+		IDToken:              idToken,
+		RefreshToken:         refreshToken,
+		TokenType:            tokenType,
+		AuthorizationDetails: json.RawMessage(oauth2Ctx.Get("authorization_details")),
+	}, nil
+}
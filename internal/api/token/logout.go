@@ -0,0 +1,87 @@
+package token
+
+import (
+	"net/url"
+	"time"
+	"uyulala/internal/db"
+	"uyulala/internal/db/appdb"
+	"uyulala/internal/db/keydb"
+	"uyulala/internal/db/sessiondb"
+	"uyulala/internal/notify"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lestrrat-go/jwx/jwa"
+	"github.com/lestrrat-go/jwx/jws"
+	"github.com/lestrrat-go/jwx/jwt"
+	"github.com/spf13/viper"
+)
+
+const (
+	TypeLogout = "logout+jwt"
+
+	backChannelLogoutEvent = "http://schemas.openid.net/event/backchannel-logout"
+	logoutTokenLength      = 2 * time.Minute
+)
+
+// BackChannelLogout queues a signed logout token for the application that owns sess,
+// as described in OpenID Connect Back-Channel Logout 1.0.
+// Nothing is sent if the application has no back-channel logout uri registered.
+func BackChannelLogout(ctx *gin.Context, sess *sessiondb.Session) error {
+	app, err := appdb.GetApplication(ctx, sess.AppID)
+	if err != nil {
+		return err
+	}
+	if app.BackChannelLogoutURI == "" {
+		return nil
+	}
+	key, err := keydb.GetKey(ctx, app.KeyID)
+	if err != nil {
+		return err
+	}
+	appKey, err := key.GetPrivateJWK()
+	if err != nil {
+		return err
+	}
+
+	subject, err := Subject(ctx, app, sess.UserID)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	token := jwt.New()
+	_ = token.Set(jwt.IssuerKey, viper.GetString("issuer"))
+	_ = token.Set(jwt.AudienceKey, app.ID)
+	_ = token.Set(jwt.IssuedAtKey, now.Unix())
+	_ = token.Set(jwt.ExpirationKey, now.Add(logoutTokenLength).Unix())
+	_ = token.Set(jwt.JwtIDKey, db.GenerateID(16))
+	_ = token.Set(jwt.SubjectKey, subject)
+	_ = token.Set("sid", sess.ID)
+	_ = token.Set("events", map[string]any{backChannelLogoutEvent: map[string]any{}})
+
+	hdrs := jws.NewHeaders()
+	_ = hdrs.Set(jws.TypeKey, TypeLogout)
+	data, err := jwt.Sign(token, jwa.SignatureAlgorithm(appKey.Algorithm()), appKey, jwt.WithJwsHeaders(hdrs))
+	if err != nil {
+		return err
+	}
+	notify.Queue(ctx, notify.Form(app.BackChannelLogoutURI, url.Values{"logout_token": {string(data)}}))
+	return nil
+}
+
+// EndUserSessions ends every session of the user, with a back-channel logout to the applications holding them.
+func EndUserSessions(ctx *gin.Context, userID string) error {
+	sessions, err := sessiondb.ListForUser(ctx, userID)
+	if err != nil {
+		return err
+	}
+	for _, sess := range sessions {
+		if err := sessiondb.Delete(ctx, sess.ID); err != nil {
+			return err
+		}
+		if err := BackChannelLogout(ctx, sess); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,158 @@
+package token
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"slices"
+	"strings"
+	"text/template"
+	"time"
+	"uyulala/internal/db/appdb"
+	"uyulala/internal/db/userdb"
+
+	"github.com/gin-gonic/gin"
+	"github.com/spf13/viper"
+)
+
+// ReservedClaims are set by the server and can't be custom claims of an application.
+var ReservedClaims = []string{
+	"iss", "sub", "aud", "exp", "nbf", "iat", "jti",
+	"sid", "cnf", "client_id", "scope", "nonce", "azp",
+	"auth_time", "acr", "amr", "uv", "up", "at_hash", "c_hash",
+	"rt_hash", AuthReqIDClaim,
+}
+
+// AuthReqIDClaim identifies the CIBA request in the ID token of a push delivery.
+const AuthReqIDClaim = "urn:openid:params:jwt:claim:auth_req_id"
+
+var (
+	ErrReservedClaim    = errors.New("claim is reserved")
+	ErrInvalidTokenType = errors.New("claims can only be added to access_token or id_token")
+)
+
+// ClaimData is what custom claim templates are executed with, e.g. {{.Subject}}.
+type ClaimData struct {
+	Subject  string
+	ClientID string
+	Scope    string
+	// Attributes are the profile attributes of the user (see userdb.GetAttributes) released to the application,
+	// e.g. {{.Attributes.email}}. They are empty for tokens issued to the client itself.
+	Attributes map[string]any
+}
+
+// NewClaimData returns the data the custom claims of app for the token type are executed with. The attributes of the
+// user are only loaded when the application has custom claims of that type and userID isn't empty. Third party
+// applications only get the attributes released by the scopes the user has consented to, like in UserClaims.
+func NewClaimData(ctx *gin.Context, app *appdb.Application, tokenType, userID, subject, scope string) (*ClaimData, error) {
+	data := &ClaimData{Subject: subject, ClientID: app.ID, Scope: scope, Attributes: map[string]any{}}
+	if userID == "" || len(app.Claims(tokenType)) == 0 {
+		return data, nil
+	}
+	if app.FirstParty {
+		attributes, err := userdb.GetAttributes(ctx, userID)
+		if err != nil {
+			return nil, err
+		}
+		data.Attributes = attributes
+		return data, nil
+	}
+	consented, err := userdb.ConsentedScopes(ctx, userID, app.ID)
+	if err != nil {
+		return nil, err
+	}
+	attributes, err := userClaims(ctx, app, userID, consented, nil)
+	if err != nil {
+		return nil, err
+	}
+	data.Attributes = attributes
+	return data, nil
+}
+
+// AccessTokenLength returns how long access tokens of app are valid, accessToken.length unless the app has its own.
+func AccessTokenLength(app *appdb.Application) time.Duration {
+	return length(app.AccessTokenLength, "accessToken.length")
+}
+
+// IDTokenLength returns how long ID tokens of app are valid, idToken.length unless the app has its own.
+func IDTokenLength(app *appdb.Application) time.Duration {
+	return length(app.IDTokenLength, "idToken.length")
+}
+
+// RefreshTokenLength returns how long refresh tokens of app are valid, refreshToken.length unless the app has its own.
+func RefreshTokenLength(app *appdb.Application) time.Duration {
+	return length(app.RefreshTokenLength, "refreshToken.length")
+}
+
+func length(seconds int64, key string) time.Duration {
+	if seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	return viper.GetDuration(key)
+}
+
+// ValidateClaim checks that a custom claim can be stored for an application.
+func ValidateClaim(claim *appdb.TokenClaim) error {
+	if claim == nil || claim.Token != appdb.TokenAccess && claim.Token != appdb.TokenID {
+		return ErrInvalidTokenType
+	}
+	if claim.Name == "" || slices.Contains(ReservedClaims, claim.Name) {
+		return ErrReservedClaim
+	}
+	tmpl, err := claimTemplate(claim)
+	if err != nil {
+		return err
+	}
+	// Attributes differ per user, only the rest of the template can be checked.
+	if err := tmpl.Execute(io.Discard, &ClaimData{Attributes: map[string]any{}}); err != nil && !missingAttribute(err) {
+		return err
+	}
+	return nil
+}
+
+// CustomClaims returns the custom claims of app for the token type. Claim values are templates executed with data.
+// Results that are valid JSON, like numbers, booleans, arrays and objects, are added as such and anything else as a
+// string, so static values don't need to be templates. Claims referring to an attribute the user doesn't have are left
+// out, as are all attribute claims in tokens issued to the client itself.
+// accessToken.extension is the default for access tokens: it is only added when the application has no access token
+// claims of its own.
+func CustomClaims(app *appdb.Application, tokenType string, data *ClaimData) (map[string]any, error) {
+	claims := map[string]any{}
+	if tokenType == appdb.TokenAccess && len(app.Claims(appdb.TokenAccess)) == 0 {
+		for k, v := range viper.GetStringMap("accessToken.extension") {
+			claims[k] = v
+		}
+	}
+	for _, claim := range app.Claims(tokenType) {
+		if slices.Contains(ReservedClaims, claim.Name) {
+			continue
+		}
+		tmpl, err := claimTemplate(claim)
+		if err != nil {
+			return nil, err
+		}
+		value := &strings.Builder{}
+		if err := tmpl.Execute(value, data); missingAttribute(err) {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		var decoded any
+		if err := json.Unmarshal([]byte(value.String()), &decoded); err == nil {
+			claims[claim.Name] = decoded
+		} else {
+			claims[claim.Name] = value.String()
+		}
+	}
+	return claims, nil
+}
+
+func claimTemplate(claim *appdb.TokenClaim) (*template.Template, error) {
+	return template.New(claim.Name).Option("missingkey=error").Parse(claim.Value)
+}
+
+// missingAttribute reports whether err is from a claim template referring to an attribute that isn't there.
+func missingAttribute(err error) bool {
+	var execErr template.ExecError
+	return errors.As(err, &execErr) && strings.Contains(execErr.Err.Error(), "map has no entry for key")
+}
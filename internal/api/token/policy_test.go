@@ -0,0 +1,120 @@
+package token
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+	"uyulala/internal/db/appdb"
+	"uyulala/internal/db/dbtest"
+
+	"github.com/spf13/viper"
+)
+
+func TestCustomClaims(t *testing.T) {
+	viper.Set("accessToken.extension", map[string]any{"tenant": "default", "tier": "free"})
+	defer viper.Set("accessToken.extension", nil)
+
+	plain := &appdb.Application{ID: "client"}
+	idClaims := &appdb.Application{ID: "client", TokenClaims: []*appdb.TokenClaim{
+		{Token: appdb.TokenID, Name: "ref", Value: "{{.ClientID}}:{{.Subject}}"},
+	}}
+	accessClaims := &appdb.Application{ID: "client", TokenClaims: []*appdb.TokenClaim{
+		{Token: appdb.TokenAccess, Name: "tenant", Value: "acme"},
+		{Token: appdb.TokenAccess, Name: "department", Value: "{{.Attributes.department}}"},
+		{Token: appdb.TokenAccess, Name: "level", Value: "{{.Attributes.level}}"},
+		{Token: appdb.TokenAccess, Name: "sub", Value: "reserved"},
+	}}
+	data := &ClaimData{Subject: "user", ClientID: "client", Scope: "openid",
+		Attributes: map[string]any{"department": "Ducks", "level": 3}}
+	tests := []struct {
+		name      string
+		app       *appdb.Application
+		tokenType string
+		want      map[string]any
+	}{
+		{"extension without a policy", plain, appdb.TokenAccess, map[string]any{"tenant": "default", "tier": "free"}},
+		{"extension without access token claims", idClaims, appdb.TokenAccess,
+			map[string]any{"tenant": "default", "tier": "free"}},
+		{"no extension in ID tokens", idClaims, appdb.TokenID, map[string]any{"ref": "client:user"}},
+		{"access token claims replace the extension", accessClaims, appdb.TokenAccess,
+			map[string]any{"tenant": "acme", "department": "Ducks", "level": float64(3)}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := CustomClaims(test.app, test.tokenType, data)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("claims = %v, want %v", got, test.want)
+			}
+		})
+	}
+
+	t.Run("missing attribute", func(t *testing.T) {
+		got, err := CustomClaims(accessClaims, appdb.TokenAccess, &ClaimData{Attributes: map[string]any{"level": 3}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := map[string]any{"tenant": "acme", "level": float64(3)}; !reflect.DeepEqual(got, want) {
+			t.Errorf("claims = %v, want %v", got, want)
+		}
+	})
+}
+
+func TestValidateClaim(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+		valid bool
+	}{
+		{"static", "acme", true},
+		{"attribute", "{{.Attributes.email}}", true},
+		{"fields", "{{.ClientID}}:{{.Subject}}:{{.Scope}}", true},
+		{"unknown field", "{{.Email}}", false},
+		{"syntax", "{{.Subject", false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := ValidateClaim(&appdb.TokenClaim{Token: appdb.TokenAccess, Name: "claim", Value: test.value})
+			if valid := err == nil; valid != test.valid {
+				t.Errorf("valid = %v (%v), want %v", valid, err, test.valid)
+			}
+		})
+	}
+}
+
+func TestNewClaimData(t *testing.T) {
+	attributes := map[string]any{"email": "kalle@example.com", "department": "Ducks"}
+	claims := []*appdb.TokenClaim{{Token: appdb.TokenAccess, Name: "mail", Value: "{{.Attributes.email}}"}}
+	firstParty := &appdb.Application{ID: "client", FirstParty: true, TokenClaims: claims}
+	thirdParty := &appdb.Application{ID: "client", TokenClaims: claims}
+	tests := []struct {
+		name      string
+		app       *appdb.Application
+		tokenType string
+		userID    string
+		consented []string
+		want      map[string]any
+	}{
+		{"first party", firstParty, appdb.TokenAccess, "user", nil, attributes},
+		{"third party with consent", thirdParty, appdb.TokenAccess, "user", []string{"email"},
+			map[string]any{"email": "kalle@example.com"}},
+		{"third party without consent", thirdParty, appdb.TokenAccess, "user", nil, map[string]any{}},
+		{"client credentials", firstParty, appdb.TokenAccess, "", nil, map[string]any{}},
+		{"no claims of the token type", firstParty, appdb.TokenID, "user", nil, map[string]any{}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ctx := dbtest.Context(profileDB(attributes, test.consented), http.MethodPost, "/oauth2/token")
+			data, err := NewClaimData(ctx, test.app, test.tokenType, test.userID, "subject", "openid")
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := &ClaimData{Subject: "subject", ClientID: "client", Scope: "openid", Attributes: test.want}
+			if !reflect.DeepEqual(data, want) {
+				t.Errorf("data = %+v, want %+v", data, want)
+			}
+		})
+	}
+}
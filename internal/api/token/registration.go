@@ -0,0 +1,40 @@
+package token
+
+import (
+	"time"
+	"uyulala/internal/db"
+	"uyulala/internal/db/appdb"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lestrrat-go/jwx/jwa"
+	"github.com/lestrrat-go/jwx/jws"
+	"github.com/lestrrat-go/jwx/jwt"
+	"github.com/spf13/viper"
+)
+
+const TypeInitialAccess = "initial-access+jwt"
+
+// InitialAccessToken issues a token that allows the holder to register clients (RFC 7591 section 3),
+// signed with the key of the administrative application issuing it.
+func InitialAccessToken(ctx *gin.Context, app *appdb.Application, lifetime time.Duration) (string, error) {
+	key, err := SigningKey(ctx, app)
+	if err != nil {
+		return "", err
+	}
+	now := time.Now()
+	token := jwt.New()
+	_ = token.Set(jwt.IssuerKey, viper.GetString("issuer"))
+	_ = token.Set(jwt.AudienceKey, viper.GetString("issuer"))
+	_ = token.Set(jwt.SubjectKey, app.ID)
+	_ = token.Set(jwt.IssuedAtKey, now.Unix())
+	_ = token.Set(jwt.ExpirationKey, now.Add(lifetime).Unix())
+	_ = token.Set(jwt.JwtIDKey, db.GenerateID(16))
+
+	hdrs := jws.NewHeaders()
+	_ = hdrs.Set(jws.TypeKey, TypeInitialAccess)
+	data, err := jwt.Sign(token, jwa.SignatureAlgorithm(key.Algorithm()), key, jwt.WithJwsHeaders(hdrs))
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
@@ -0,0 +1,202 @@
+package token
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"time"
+	"uyulala/internal/api"
+	"uyulala/internal/db/appdb"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lestrrat-go/jwx/jwk"
+	"github.com/lestrrat-go/jwx/jws"
+	"github.com/lestrrat-go/jwx/jwt"
+	"github.com/spf13/viper"
+)
+
+// RequestObjectAlgorithms are the algorithms accepted for request objects signed with the client keys.
+var RequestObjectAlgorithms = []string{
+	"RS256", "RS384", "RS512",
+	"PS256", "PS384", "PS512",
+	"ES256", "ES384", "ES512",
+	"EdDSA",
+}
+
+// requestObjectClaims are the JWT claims of a request object that aren't request parameters.
+var requestObjectClaims = []string{"iss", "aud", "exp", "iat", "nbf", "jti", "sub"}
+
+// maxJWKSSize is the largest key set fetched from the jwks_uri of a client.
+const maxJWKSSize = 64 << 10
+
+var (
+	ErrNoClientKeys        = errors.New("client has no keys registered")
+	ErrUnsupportedAlg      = errors.New("unsupported signing algorithm")
+	ErrClientIDMismatch    = errors.New("client_id does not match the client")
+	ErrRequestObjectReplay = errors.New("request object has already been used")
+	ErrNoExpiration        = errors.New("request object has no expiration time")
+)
+
+var (
+	// clientKeys caches the key sets fetched from the jwks_uri of clients and refreshes them in the background.
+	clientKeys = jwk.NewAutoRefresh(context.Background())
+	jwksClient = &http.Client{
+		Timeout:   5 * time.Second,
+		Transport: limitedTransport{http.DefaultTransport, maxJWKSSize},
+	}
+)
+
+// limitedTransport cuts response bodies off after limit bytes.
+type limitedTransport struct {
+	http.RoundTripper
+	limit int64
+}
+
+func (t limitedTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	res, err := t.RoundTripper.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	res.Body = struct {
+		io.Reader
+		io.Closer
+	}{io.LimitReader(res.Body, t.limit), res.Body}
+	return res, nil
+}
+
+// ClientKeySet returns the public keys registered for app, either inline or through its jwks_uri.
+// Key sets of a jwks_uri are cached for up to 15 minutes.
+func ClientKeySet(ctx *gin.Context, app *appdb.Application) (jwk.Set, error) {
+	if app.JWKS != "" {
+		return jwk.ParseString(app.JWKS)
+	}
+	if app.JWKSURI != "" {
+		if !clientKeys.IsRegistered(app.JWKSURI) {
+			clientKeys.Configure(app.JWKSURI, jwk.WithHTTPClient(jwksClient),
+				jwk.WithRefreshInterval(15*time.Minute), jwk.WithMinRefreshInterval(time.Minute))
+		}
+		return clientKeys.Fetch(ctx.Request.Context(), app.JWKSURI)
+	}
+	return nil, ErrNoClientKeys
+}
+
+// VerifyRequestObject verifies a request object (RFC 9101) signed by app and returns its claims as request parameters.
+// The request object must expire, and one with a jti can only be used once.
+func VerifyRequestObject(ctx *gin.Context, app *appdb.Application, request string) (url.Values, error) {
+	msg, err := jws.ParseString(request)
+	if err != nil {
+		return nil, err
+	}
+	if len(msg.Signatures()) != 1 ||
+		!slices.Contains(RequestObjectAlgorithms, msg.Signatures()[0].ProtectedHeaders().Algorithm().String()) {
+		return nil, ErrUnsupportedAlg
+	}
+	keySet, err := ClientKeySet(ctx, app)
+	if err != nil {
+		return nil, err
+	}
+	token, err := jwt.ParseString(request,
+		jwt.WithKeySet(keySet),
+		jwt.InferAlgorithmFromKey(true),
+		jwt.UseDefaultKey(true),
+		jwt.WithValidate(true),
+		jwt.WithIssuer(app.ID),
+		jwt.WithAudience(viper.GetString("issuer")),
+		jwt.WithAcceptableSkew(time.Minute))
+	if err != nil {
+		return nil, err
+	}
+	if token.Expiration().IsZero() {
+		return nil, ErrNoExpiration
+	}
+	if token.JwtID() != "" {
+		unused, err := appdb.UseRequestObjectID(ctx, app.ID, token.JwtID(), token.Expiration())
+		if err != nil {
+			return nil, err
+		}
+		if !unused {
+			return nil, ErrRequestObjectReplay
+		}
+	}
+	claims, err := token.AsMap(ctx)
+	if err != nil {
+		return nil, err
+	}
+	params := url.Values{}
+	for name, value := range claims {
+		if slices.Contains(requestObjectClaims, name) {
+			continue
+		}
+		switch v := value.(type) {
+		case string:
+			params.Set(name, v)
+		case float64:
+			params.Set(name, strconv.FormatFloat(v, 'f', -1, 64))
+		case bool:
+			params.Set(name, strconv.FormatBool(v))
+		case []any:
+			// Arrays of strings are repeated parameters, like resource (RFC 8707), other arrays are JSON.
+			if values, ok := stringValues(v); ok {
+				params[name] = values
+				continue
+			}
+			data, err := json.Marshal(v)
+			if err != nil {
+				return nil, err
+			}
+			params.Set(name, string(data))
+		default:
+			data, err := json.Marshal(v)
+			if err != nil {
+				return nil, err
+			}
+			params.Set(name, string(data))
+		}
+	}
+	if clientID := params.Get("client_id"); clientID != "" && clientID != app.ID {
+		return nil, ErrClientIDMismatch
+	}
+	return params, nil
+}
+
+// stringValues returns values as strings, if they all are.
+func stringValues(values []any) ([]string, bool) {
+	res := make([]string, 0, len(values))
+	for _, value := range values {
+		s, ok := value.(string)
+		if !ok {
+			return nil, false
+		}
+		res = append(res, s)
+	}
+	return res, true
+}
+
+// ResolveRequestObject merges the claims of the request parameter into form. Parameters inside the request object
+// take precedence over the ones passed alongside it. form is returned as is if it has no request parameter.
+func ResolveRequestObject(ctx *gin.Context, app *appdb.Application, form url.Values) (url.Values, bool) {
+	request := form.Get("request")
+	if request == "" {
+		return form, true
+	}
+	params, err := VerifyRequestObject(ctx, app, request)
+	if err != nil {
+		api.AbortError(ctx, http.StatusBadRequest, "invalid_request_object", "Invalid request object", err)
+		return nil, false
+	}
+	res := url.Values{}
+	for name, values := range form {
+		if name != "request" {
+			res[name] = values
+		}
+	}
+	for name, values := range params {
+		res[name] = values
+	}
+	return res, true
+}
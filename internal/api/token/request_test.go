@@ -0,0 +1,131 @@
+package token
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+	"uyulala/internal/db/appdb"
+	"uyulala/internal/db/dbtest"
+
+	"github.com/spf13/viper"
+	"gitlab.com/daedaluz/gindb"
+)
+
+func TestVerifyRequestObject(t *testing.T) {
+	viper.Set("issuer", "https://issuer.example")
+	key, set := serverKey(t)
+	jwks, err := json.Marshal(set)
+	if err != nil {
+		t.Fatal(err)
+	}
+	app := &appdb.Application{ID: "client", JWKS: string(jwks)}
+	request := func(extra map[string]any) string {
+		claims := map[string]any{
+			"iss":             "client",
+			"aud":             "https://issuer.example",
+			"exp":             time.Now().Add(time.Minute).Unix(),
+			"binding_message": "Pay 10 EUR",
+		}
+		for name, value := range extra {
+			if value == nil {
+				delete(claims, name)
+			} else {
+				claims[name] = value
+			}
+		}
+		return signToken(t, key, "oauth-authz-req+jwt", claims)
+	}
+	db := dbtest.Open()
+	verify := func(request string) error {
+		ctx := dbtest.Context(db, http.MethodPost, "/api/v1/oauth2")
+		// The authorization request fails after the request object was verified.
+		defer func() { _ = gindb.Rollback(ctx) }()
+		params, err := VerifyRequestObject(ctx, app, request)
+		if err == nil && params.Get("binding_message") != "Pay 10 EUR" {
+			t.Errorf("binding_message = %q", params.Get("binding_message"))
+		}
+		return err
+	}
+
+	withJTI := request(map[string]any{"jti": "request-1"})
+	if err := verify(withJTI); err != nil {
+		t.Fatalf("first use: %v", err)
+	}
+	if err := verify(withJTI); !errors.Is(err, ErrRequestObjectReplay) {
+		t.Errorf("replay after a failed request: %v, want %v", err, ErrRequestObjectReplay)
+	}
+	withoutJTI := request(nil)
+	for i := 0; i < 2; i++ {
+		if err := verify(withoutJTI); err != nil {
+			t.Errorf("request object without jti, use %d: %v", i+1, err)
+		}
+	}
+	if err := verify(request(map[string]any{"exp": nil})); !errors.Is(err, ErrNoExpiration) {
+		t.Errorf("request object without exp: %v, want %v", err, ErrNoExpiration)
+	}
+	if err := verify(request(map[string]any{"client_id": "other"})); !errors.Is(err, ErrClientIDMismatch) {
+		t.Errorf("other client_id: %v, want %v", err, ErrClientIDMismatch)
+	}
+	if err := verify(request(map[string]any{"aud": "https://other.example"})); err == nil {
+		t.Error("request object for another audience was accepted")
+	}
+
+	ctx := dbtest.Context(db, http.MethodPost, "/api/v1/oauth2")
+	params, err := VerifyRequestObject(ctx, app, request(map[string]any{
+		"resource":              []string{"https://a.example", "https://b.example"},
+		"authorization_details": []map[string]any{{"type": "payment"}},
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := params["resource"]; !reflect.DeepEqual(got, []string{"https://a.example", "https://b.example"}) {
+		t.Errorf("resource = %q, want both resources", got)
+	}
+	if got := params.Get("authorization_details"); got != `[{"type":"payment"}]` {
+		t.Errorf("authorization_details = %s", got)
+	}
+}
+
+func TestClientKeySetURI(t *testing.T) {
+	_, set := serverKey(t)
+	jwks, err := json.Marshal(set)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var fetched atomic.Int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetched.Add(1)
+		if r.URL.Path == "/large" {
+			_, _ = w.Write([]byte(`{"keys":[` + strings.Repeat(" ", maxJWKSSize) + `]}`))
+			return
+		}
+		_, _ = w.Write(jwks)
+	}))
+	defer server.Close()
+	ctx := dbtest.Context(dbtest.Open(), http.MethodPost, "/api/v1/oauth2")
+
+	app := &appdb.Application{ID: "client", JWKSURI: server.URL + "/jwks"}
+	for i := 0; i < 3; i++ {
+		keys, err := ClientKeySet(ctx, app)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if keys.Len() != 1 {
+			t.Errorf("keys = %d, want 1", keys.Len())
+		}
+	}
+	if fetched.Load() != 1 {
+		t.Errorf("fetched %d times, want once", fetched.Load())
+	}
+
+	large := &appdb.Application{ID: "client", JWKSURI: server.URL + "/large"}
+	if _, err := ClientKeySet(ctx, large); err == nil {
+		t.Error("oversized key set was accepted")
+	}
+}
@@ -0,0 +1,110 @@
+package token
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"uyulala/internal/api"
+	"uyulala/internal/db/keydb"
+	"uyulala/internal/db/resourcedb"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lestrrat-go/jwx/jwk"
+)
+
+var ErrInvalidTarget = errors.New("resource is not a registered resource server")
+
+// ValidResourceIndicator reports whether resource is an absolute uri without fragment (RFC 8707 section 2).
+func ValidResourceIndicator(resource string) bool {
+	parsed, err := url.Parse(resource)
+	return err == nil && parsed.IsAbs() && parsed.Fragment == "" && len(resource) <= 255
+}
+
+// ValidateResources checks the resource parameters of an authorization request. The response is aborted with
+// invalid_target if one of them isn't a registered resource server.
+func ValidateResources(ctx *gin.Context, form url.Values) bool {
+	for _, resource := range form["resource"] {
+		if !ValidResourceIndicator(resource) {
+			api.AbortError(ctx, http.StatusBadRequest, "invalid_target", "Invalid resource "+resource, ErrInvalidTarget)
+			return false
+		}
+		if _, err := resourcedb.Get(ctx, resource); errors.Is(err, sql.ErrNoRows) {
+			api.AbortError(ctx, http.StatusBadRequest, "invalid_target", "Unknown resource "+resource, err)
+			return false
+		} else if err != nil {
+			api.AbortError(ctx, http.StatusInternalServerError, "internal_error", "Unexpected error", err)
+			return false
+		}
+	}
+	return true
+}
+
+// GrantResources returns the resource servers a token request is for. requested are the resource parameters of the
+// token request and must be a subset of authorized, the resources the grant was authorized for. Without requested
+// resources, all authorized resources are used. The response is aborted with invalid_target otherwise.
+func GrantResources(ctx *gin.Context, requested, authorized []string) ([]*resourcedb.ResourceServer, error) {
+	if len(requested) == 0 {
+		requested = authorized
+	}
+	var resources []*resourcedb.ResourceServer
+	for _, indicator := range requested {
+		if !slices.Contains(authorized, indicator) {
+			api.AbortError(ctx, http.StatusBadRequest, "invalid_target", "Resource "+indicator+" was not authorized", ErrInvalidTarget)
+			return nil, ErrInvalidTarget
+		}
+		resource, err := resourcedb.Get(ctx, indicator)
+		if errors.Is(err, sql.ErrNoRows) {
+			api.AbortError(ctx, http.StatusBadRequest, "invalid_target", "Unknown resource "+indicator, err)
+			return nil, err
+		} else if err != nil {
+			api.AbortError(ctx, http.StatusInternalServerError, "internal_error", "Unexpected error", err)
+			return nil, err
+		}
+		resources = append(resources, resource)
+	}
+	return resources, nil
+}
+
+// ResourceIndicators returns the space separated indicators of resources, as stored with a session.
+func ResourceIndicators(resources []*resourcedb.ResourceServer) string {
+	return strings.Join(resourceIDs(resources), " ")
+}
+
+func resourceIDs(resources []*resourcedb.ResourceServer) []string {
+	ids := make([]string, len(resources))
+	for i, resource := range resources {
+		ids[i] = resource.ID
+	}
+	return ids
+}
+
+// ResourceScope limits scope to the scopes allowed by any of the resources, if there are any.
+func ResourceScope(scope string, resources []*resourcedb.ResourceServer) string {
+	if len(resources) == 0 {
+		return scope
+	}
+	var scopes []string
+	for _, s := range strings.Fields(scope) {
+		if slices.ContainsFunc(resources, func(resource *resourcedb.ResourceServer) bool {
+			return slices.Contains(resource.Scopes, s)
+		}) {
+			scopes = append(scopes, s)
+		}
+	}
+	return strings.Join(scopes, " ")
+}
+
+// resourceKey returns the key tokens for a single resource with its own key are signed with, otherwise key.
+func resourceKey(ctx *gin.Context, resources []*resourcedb.ResourceServer, key jwk.Key) (jwk.Key, error) {
+	if len(resources) != 1 || resources[0].KeyID == "" {
+		return key, nil
+	}
+	serverKey, err := keydb.GetKey(ctx, resources[0].KeyID)
+	if err != nil {
+		return nil, err
+	}
+	return serverKey.GetPrivateJWK()
+}
@@ -0,0 +1,90 @@
+package token
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+	"uyulala/internal/api"
+	"uyulala/internal/db/appdb"
+	"uyulala/openid/discovery"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lestrrat-go/jwx/jwa"
+	"github.com/lestrrat-go/jwx/jwt"
+	"github.com/spf13/viper"
+)
+
+// authorizationResponseLength is how long a JWT-secured authorization response is valid.
+const authorizationResponseLength = 10 * time.Minute
+
+// AuthorizationResponse sends params to the redirect uri of an authorization request with its response_mode.
+// state and iss (RFC 9207) are added to the parameters, and for the jwt response modes (JARM) the parameters are
+// replaced with a response JWT signed with the key of the application.
+func AuthorizationResponse(ctx *gin.Context, app *appdb.Application, redirectURI string, request, params url.Values) {
+	// The default response mode is query for code and fragment for response types returning tokens.
+	fragment := api.ResponseType(request.Get("response_type")) != discovery.ResponseTypeCode
+	mode := request.Get("response_mode")
+	switch {
+	case mode == "" && fragment:
+		mode = discovery.ResponseModeFragment
+	case mode == "":
+		mode = discovery.ResponseModeQuery
+	case mode == discovery.ResponseModeJWT && fragment:
+		mode = discovery.ResponseModeFragmentJWT
+	case mode == discovery.ResponseModeJWT:
+		mode = discovery.ResponseModeQueryJWT
+	}
+	if state := request.Get("state"); state != "" {
+		params.Set("state", state)
+	}
+	params.Set("iss", viper.GetString("issuer"))
+	if base, ok := strings.CutSuffix(mode, ".jwt"); ok {
+		response, err := authorizationResponseJWT(ctx, app, params)
+		if err != nil {
+			return
+		}
+		params = url.Values{"response": {response}}
+		mode = base
+	}
+
+	r, err := url.Parse(redirectURI)
+	if err != nil {
+		api.AbortError(ctx, http.StatusInternalServerError, "internal_error", "Unexpected error", err)
+		return
+	}
+	switch mode {
+	case discovery.ResponseModeFormPost:
+		api.FormPostResponse(ctx, r.String(), params)
+	case discovery.ResponseModeFragment:
+		r.Fragment = ""
+		api.RedirectResponse(ctx, r.String()+"#"+params.Encode())
+	default:
+		q := r.Query()
+		for k, v := range params {
+			q[k] = v
+		}
+		r.RawQuery = q.Encode()
+		api.RedirectResponse(ctx, r.String())
+	}
+}
+
+// authorizationResponseJWT signs the parameters of an authorization response (JARM).
+func authorizationResponseJWT(ctx *gin.Context, app *appdb.Application, params url.Values) (string, error) {
+	key, err := SigningKey(ctx, app)
+	if err != nil {
+		return "", err
+	}
+	token := jwt.New()
+	for k := range params {
+		_ = token.Set(k, params.Get(k))
+	}
+	_ = token.Set(jwt.AudienceKey, app.ID)
+	_ = token.Set(jwt.ExpirationKey, time.Now().Add(authorizationResponseLength).Unix())
+	data, err := jwt.Sign(token, jwa.SignatureAlgorithm(key.Algorithm()), key)
+	if err != nil {
+		api.AbortError(ctx, http.StatusInternalServerError, "internal_error", "Unexpected error", err)
+		return "", err
+	}
+	return string(data), nil
+}
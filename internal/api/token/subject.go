@@ -0,0 +1,40 @@
+package token
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"uyulala/internal/db/appdb"
+	"uyulala/internal/db/userdb"
+	"uyulala/openid/discovery"
+
+	"github.com/gin-gonic/gin"
+	"github.com/spf13/viper"
+)
+
+// Subject returns the subject identifier of the user for the application.
+// Applications with the pairwise subject type get sha256(sector || user id || salt) (OpenID Connect Core 8.1),
+// which is recorded so it can be resolved back to the user.
+func Subject(ctx *gin.Context, app *appdb.Application, userID string) (string, error) {
+	if app.SubjectType != discovery.SubjectTypePairwise {
+		return userID, nil
+	}
+	sector := app.Sector()
+	subject := pairwiseSubject(sector, userID)
+	if err := userdb.CreatePairwiseSubject(ctx, sector, subject, userID); err != nil {
+		return "", err
+	}
+	return subject, nil
+}
+
+func pairwiseSubject(sector, userID string) string {
+	sum := sha256.Sum256([]byte(sector + userID + viper.GetString("subject.pairwiseSalt")))
+	return hex.EncodeToString(sum[:])
+}
+
+// ResolveSubject returns the id of the user a subject identifier issued to the application belongs to.
+func ResolveSubject(ctx *gin.Context, app *appdb.Application, subject string) (string, error) {
+	if app.SubjectType != discovery.SubjectTypePairwise {
+		return subject, nil
+	}
+	return userdb.GetPairwiseSubjectUser(ctx, app.Sector(), subject)
+}
@@ -0,0 +1,39 @@
+package token
+
+import (
+	"testing"
+	"uyulala/internal/db/appdb"
+	"uyulala/internal/db/dbtest"
+	"uyulala/openid/discovery"
+
+	"github.com/spf13/viper"
+)
+
+func TestPairwiseSubject(t *testing.T) {
+	viper.Set("subject.pairwiseSalt", "salt")
+	subject := pairwiseSubject("rp.example", "user")
+	if subject != pairwiseSubject("rp.example", "user") {
+		t.Fatal("pairwise subject is not stable")
+	}
+	if subject == "user" || len(subject) != 64 {
+		t.Fatalf("pairwise subject %q doesn't hide the user id", subject)
+	}
+	if subject == pairwiseSubject("other.example", "user") {
+		t.Fatal("two sectors get the same subject")
+	}
+	if subject == pairwiseSubject("rp.example", "other") {
+		t.Fatal("two users get the same subject")
+	}
+	viper.Set("subject.pairwiseSalt", "pepper")
+	if subject == pairwiseSubject("rp.example", "user") {
+		t.Fatal("the salt doesn't change the subject")
+	}
+}
+
+func TestPublicSubject(t *testing.T) {
+	ctx := dbtest.Context(dbtest.Open(), "GET", "/")
+	app := &appdb.Application{ID: "client", SubjectType: discovery.SubjectTypePublic}
+	if subject, err := Subject(ctx, app, "user"); err != nil || subject != "user" {
+		t.Fatalf("got %q, %v", subject, err)
+	}
+}
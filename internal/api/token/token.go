@@ -0,0 +1,230 @@
+package token
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+	"uyulala/internal/api"
+	"uyulala/internal/db"
+	"uyulala/internal/db/appdb"
+	"uyulala/internal/db/challengedb"
+	"uyulala/internal/db/keydb"
+	"uyulala/internal/db/resourcedb"
+	"uyulala/internal/db/userdb"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-webauthn/webauthn/protocol"
+	"github.com/go-webauthn/webauthn/webauthn"
+	"github.com/lestrrat-go/jwx/jwa"
+	"github.com/lestrrat-go/jwx/jwk"
+	"github.com/lestrrat-go/jwx/jws"
+	"github.com/lestrrat-go/jwx/jwt"
+	"github.com/spf13/viper"
+)
+
+type Response struct {
+	AccessToken          string          `json:"access_token,omitempty"`
+	Scope                string          `json:"scope,omitempty"`
+	IDToken              string          `json:"id_token,omitempty"`
+	TokenType            string          `json:"token_type,omitempty"`
+	RefreshToken         string          `json:"refresh_token,omitempty" `
+	AuthorizationDetails json.RawMessage `json:"authorization_details,omitempty"`
+}
+
+// Assertion is the signed WebAuthn assertion of a challenge.
+type Assertion struct {
+	Signed     time.Time
+	Signature  *protocol.ParsedCredentialAssertionData
+	Credential *webauthn.Credential
+	// ACR and AMR are the authentication context of the assertion, see SetAuthenticationContext.
+	ACR string
+	AMR []string
+}
+
+func AssertionFromChallenge(challenge *challengedb.Data) *Assertion {
+	res := &Assertion{
+		Signed: challenge.Signed.Time,
+	}
+	_ = db.GobDecodeData(challenge.Signature, &res.Signature)
+	_ = db.GobDecodeData(challenge.Credential, &res.Credential)
+	return res
+}
+
+// SigningKey returns the private key the application signs its tokens with.
+func SigningKey(ctx *gin.Context, app *appdb.Application) (jwk.Key, error) {
+	key, err := keydb.GetKey(ctx, app.KeyID)
+	if err != nil {
+		api.AbortError(ctx, http.StatusInternalServerError, "no_key", "This shouldn't happen. couldn't find the signing key.", err)
+		return nil, err
+	}
+	appKey, err := key.GetPrivateJWK()
+	if err != nil {
+		api.AbortError(ctx, http.StatusInternalServerError, "key_parsing_error", "This shouldn't happen. couldn't parse the private key for signing", err)
+		return nil, err
+	}
+	return appKey, nil
+}
+
+// IDToken issues an ID token for the user. claims are added to the token, like the hashes of the hybrid flow.
+func IDToken(ctx *gin.Context, sessionID, userID, nonce string, app *appdb.Application, appKey jwk.Key,
+	assertion *Assertion, claims map[string]any) (string, error) {
+	lastAuth, err := userdb.GetAuthTime(ctx, userID, app.ID)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		api.AbortError(ctx, http.StatusInternalServerError, "internal_error", "Unexpected error", err)
+		return "", err
+	}
+	subject, err := Subject(ctx, app, userID)
+	if err != nil {
+		api.AbortError(ctx, http.StatusInternalServerError, "internal_error", "Unexpected error", err)
+		return "", err
+	}
+
+	startTime := time.Now()
+	if assertion != nil {
+		startTime = assertion.Signed
+	}
+
+	token := jwt.New()
+	_ = token.Set("sub", subject)
+	_ = token.Set("iss", viper.GetString("issuer"))
+	_ = token.Set("aud", app.ID)
+	_ = token.Set("exp", startTime.Add(IDTokenLength(app)).Unix())
+	_ = token.Set("nbf", startTime.Unix())
+	if !lastAuth.IsZero() {
+		_ = token.Set("auth_time", lastAuth.Unix())
+	}
+	_ = token.Set("iat", time.Now().Unix())
+	if assertion != nil {
+		_ = token.Set("uv", assertion.Signature.Response.AuthenticatorData.Flags.UserVerified())
+		_ = token.Set("up", assertion.Signature.Response.AuthenticatorData.Flags.UserPresent())
+		if assertion.ACR != "" {
+			_ = token.Set("acr", assertion.ACR)
+			_ = token.Set("amr", assertion.AMR)
+		}
+	}
+
+	if sessionID != "" {
+		_ = token.Set("sid", sessionID)
+	}
+	if nonce != "" {
+		_ = token.Set("nonce", nonce)
+	}
+	claimData, err := NewClaimData(ctx, app, appdb.TokenID, userID, subject, "")
+	if err != nil {
+		api.AbortError(ctx, http.StatusInternalServerError, "internal_error", "Unexpected error", err)
+		return "", err
+	}
+	custom, err := CustomClaims(app, appdb.TokenID, claimData)
+	if err != nil {
+		api.AbortError(ctx, http.StatusInternalServerError, "claim_error", "Couldn't render the custom claims of the application", err)
+		return "", err
+	}
+	for k, v := range custom {
+		_ = token.Set(k, v)
+	}
+	for k, v := range claims {
+		_ = token.Set(k, v)
+	}
+
+	data, err := jwt.Sign(token, jwa.SignatureAlgorithm(appKey.Algorithm()), appKey)
+	if err != nil {
+		api.AbortError(ctx, http.StatusInternalServerError, "internal_error", "Unexpected error", err)
+		return "", err
+	}
+	tokenString := string(data)
+	if app.IDTokenEncryptionAlg != "" {
+		// The signed token is nested in a JWE for the client.
+		tokenString, err = Encrypt(ctx, app, data, app.IDTokenEncryptionAlg, app.IDTokenEncryptionEnc, "JWT")
+		if err != nil {
+			api.AbortError(ctx, http.StatusInternalServerError, "encryption_error", "Couldn't encrypt the ID token to the client keys", err)
+			return "", err
+		}
+	}
+	return tokenString, nil
+}
+
+// AccessToken issues an access token for subject, which is either the subject identifier of the user userID (see
+// Subject) or the client id for client credentials, where userID is empty. With resources, the token is restricted to those resource servers: they
+// are the audience, scope is limited to their scopes and the shortest of their lengths is used. claims are added to
+// the token, like the authorization details.
+func AccessToken(ctx *gin.Context, sessionID, userID, subject, scope string, key jwk.Key, app *appdb.Application,
+	assertion *Assertion, resources []*resourcedb.ResourceServer, claims map[string]any) (string, error) {
+	return bindAccessToken(ctx, sessionID, userID, subject, scope, key, app, assertion, Confirmation(ctx), resources, claims)
+}
+
+// bindAccessToken is AccessToken bound to the confirmation cnf, a bearer token when nil.
+func bindAccessToken(ctx *gin.Context, sessionID, userID, subject, scope string, key jwk.Key, app *appdb.Application,
+	assertion *Assertion, cnf map[string]any, resources []*resourcedb.ResourceServer, claims map[string]any) (string, error) {
+	startTime := time.Now()
+	if assertion != nil {
+		startTime = assertion.Signed
+	}
+	var audience any = app.ID
+	if len(app.Audiences) > 0 {
+		// The client comes first, introspection reports it as client_id.
+		audience = append([]string{app.ID}, app.Audiences...)
+	}
+	length := AccessTokenLength(app)
+	if len(resources) > 0 {
+		audience = resourceIDs(resources)
+		for _, resource := range resources {
+			if resourceLength := time.Duration(resource.AccessTokenLength) * time.Second; resourceLength > 0 && resourceLength < length {
+				length = resourceLength
+			}
+		}
+		scope = ResourceScope(scope, resources)
+		var err error
+		if key, err = resourceKey(ctx, resources, key); err != nil {
+			api.AbortError(ctx, http.StatusInternalServerError, "no_key", "Couldn't get the signing key of the resource", err)
+			return "", err
+		}
+	}
+
+	claimData, err := NewClaimData(ctx, app, appdb.TokenAccess, userID, subject, scope)
+	if err != nil {
+		api.AbortError(ctx, http.StatusInternalServerError, "internal_error", "Unexpected error", err)
+		return "", err
+	}
+	custom, err := CustomClaims(app, appdb.TokenAccess, claimData)
+	if err != nil {
+		api.AbortError(ctx, http.StatusInternalServerError, "claim_error", "Couldn't render the custom claims of the application", err)
+		return "", err
+	}
+
+	token := jwt.New()
+	for k, v := range custom {
+		_ = token.Set(k, v)
+	}
+	_ = token.Set("sub", subject)
+	_ = token.Set("iss", viper.GetString("issuer"))
+	_ = token.Set("aud", audience)
+	_ = token.Set("exp", startTime.Add(length).Unix())
+	_ = token.Set("nbf", startTime.Unix())
+	_ = token.Set("iat", time.Now().Unix())
+	if sessionID != "" {
+		_ = token.Set("sid", sessionID)
+	}
+	if scope != "" || len(resources) > 0 {
+		_ = token.Set("client_id", app.ID)
+	}
+	if scope != "" {
+		_ = token.Set("scope", scope)
+	}
+	if cnf != nil {
+		_ = token.Set("cnf", cnf)
+	}
+	for k, v := range claims {
+		_ = token.Set(k, v)
+	}
+	hdrs := jws.NewHeaders()
+	_ = hdrs.Set(jws.TypeKey, "at+jwt")
+	data, err := jwt.Sign(token, jwa.SignatureAlgorithm(key.Algorithm()), key, jwt.WithJwsHeaders(hdrs))
+	if err != nil {
+		api.AbortError(ctx, http.StatusInternalServerError, "internal_error", "Unexpected error", err)
+		return "", err
+	}
+	tokenString := string(data)
+	return tokenString, nil
+}
@@ -0,0 +1,99 @@
+package token
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"time"
+	"uyulala/internal/db/keydb"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lestrrat-go/jwx/jwk"
+	"github.com/lestrrat-go/jwx/jws"
+	"github.com/lestrrat-go/jwx/jwt"
+	"github.com/spf13/viper"
+)
+
+const (
+	TypeAccess  = "at+jwt"
+	TypeRefresh = "refresh+jwt"
+)
+
+var (
+	ErrNoTokenType   = errors.New("token has no typ header")
+	ErrWrongIssuer   = errors.New("token was issued by another issuer")
+	ErrNotIDToken    = errors.New("token is not an ID token")
+	ErrWrongAudience = errors.New("token was issued to another client")
+)
+
+// Verify checks the signature of a token issued by this server against the server keys and validates its claims.
+// The lowercase typ header of the token is returned together with the parsed token.
+func Verify(ctx *gin.Context, tokenString string) (jwt.Token, string, error) {
+	keys, err := keydb.GetKeys(ctx)
+	if err != nil {
+		return nil, "", err
+	}
+	keySet, err := keys.Set()
+	if err != nil {
+		return nil, "", err
+	}
+	token, err := jwt.ParseString(tokenString,
+		jwt.WithValidate(true),
+		jwt.WithKeySet(keySet),
+		jwt.WithIssuer(viper.GetString("issuer")),
+		jwt.WithAcceptableSkew(time.Minute))
+	if err != nil {
+		return nil, "", err
+	}
+	msg, err := jws.ParseString(tokenString)
+	if err != nil {
+		return nil, "", err
+	}
+	typ, ok := msg.Signatures()[0].ProtectedHeaders().Get(jws.TypeKey)
+	if !ok {
+		return token, "", ErrNoTokenType
+	}
+	typString, _ := typ.(string)
+	return token, strings.ToLower(typString), nil
+}
+
+// VerifyIDTokenHint checks that an id_token_hint is an ID token signed by one of the server keys and issued by this
+// server, to clientID unless empty. Access, refresh and logout tokens are signed with the same keys and are rejected.
+// The expiry isn't validated, since an expired ID token is still a valid hint.
+func VerifyIDTokenHint(ctx *gin.Context, tokenString, clientID string) (jwt.Token, error) {
+	keys, err := keydb.GetKeys(ctx)
+	if err != nil {
+		return nil, err
+	}
+	keySet, err := keys.Set()
+	if err != nil {
+		return nil, err
+	}
+	return parseIDTokenHint(keySet, tokenString, clientID)
+}
+
+func parseIDTokenHint(keySet jwk.Set, tokenString, clientID string) (jwt.Token, error) {
+	token, err := jwt.ParseString(tokenString, jwt.WithKeySet(keySet))
+	if err != nil {
+		return nil, err
+	}
+	if token.Issuer() != viper.GetString("issuer") {
+		return nil, ErrWrongIssuer
+	}
+	msg, err := jws.ParseString(tokenString)
+	if err != nil {
+		return nil, err
+	}
+	// ID tokens are signed without a typ, or with the generic JWT.
+	typ := strings.ToLower(msg.Signatures()[0].ProtectedHeaders().Type())
+	if typ != "" && typ != "jwt" {
+		return nil, ErrNotIDToken
+	}
+	if _, ok := token.Get("events"); ok {
+		return nil, ErrNotIDToken
+	}
+	if len(token.Audience()) == 0 || clientID != "" && !slices.Contains(token.Audience(), clientID) {
+		return nil, ErrWrongAudience
+	}
+	return token, nil
+}
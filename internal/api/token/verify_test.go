@@ -0,0 +1,104 @@
+package token
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/lestrrat-go/jwx/jwa"
+	"github.com/lestrrat-go/jwx/jwk"
+	"github.com/lestrrat-go/jwx/jws"
+	"github.com/lestrrat-go/jwx/jwt"
+	"github.com/spf13/viper"
+)
+
+func serverKey(t *testing.T) (jwk.Key, jwk.Set) {
+	t.Helper()
+	private, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := jwk.New(private)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = key.Set(jwk.KeyIDKey, "server")
+	_ = key.Set(jwk.AlgorithmKey, jwa.ES256)
+	public, err := key.PublicKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	set := jwk.NewSet()
+	set.Add(public)
+	return key, set
+}
+
+func signToken(t *testing.T, key jwk.Key, typ string, claims map[string]any) string {
+	t.Helper()
+	token := jwt.New()
+	for name, value := range claims {
+		_ = token.Set(name, value)
+	}
+	hdrs := jws.NewHeaders()
+	if typ != "" {
+		_ = hdrs.Set(jws.TypeKey, typ)
+	}
+	data, err := jwt.Sign(token, jwa.ES256, key, jwt.WithJwsHeaders(hdrs))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestParseIDTokenHint(t *testing.T) {
+	viper.Set("issuer", "https://issuer.example")
+	key, set := serverKey(t)
+	otherKey, _ := serverKey(t)
+	claims := func(extra map[string]any) map[string]any {
+		res := map[string]any{
+			"iss": "https://issuer.example",
+			"sub": "user",
+			"aud": "client",
+			"exp": time.Now().Add(-time.Hour).Unix(),
+		}
+		for name, value := range extra {
+			res[name] = value
+		}
+		return res
+	}
+	tests := []struct {
+		name     string
+		token    string
+		clientID string
+		want     error
+	}{
+		{"expired ID token", signToken(t, key, "", claims(nil)), "client", nil},
+		{"ID token with JWT typ", signToken(t, key, "JWT", claims(nil)), "client", nil},
+		{"without client_id", signToken(t, key, "", claims(nil)), "", nil},
+		{"one of several audiences", signToken(t, key, "", claims(map[string]any{"aud": []string{"other", "client"}})), "client", nil},
+		{"other client", signToken(t, key, "", claims(nil)), "other", ErrWrongAudience},
+		{"no audience", signToken(t, key, "", claims(map[string]any{"aud": []string{}})), "", ErrWrongAudience},
+		{"other issuer", signToken(t, key, "", claims(map[string]any{"iss": "https://other.example"})), "client", ErrWrongIssuer},
+		{"access token", signToken(t, key, TypeAccess, claims(nil)), "client", ErrNotIDToken},
+		{"refresh token", signToken(t, key, TypeRefresh, claims(nil)), "client", ErrNotIDToken},
+		{"logout token", signToken(t, key, TypeLogout, claims(nil)), "client", ErrNotIDToken},
+		{"logout token without typ", signToken(t, key, "", claims(map[string]any{
+			"events": map[string]any{backChannelLogoutEvent: map[string]any{}},
+		})), "client", ErrNotIDToken},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := parseIDTokenHint(set, test.token, test.clientID)
+			if !errors.Is(err, test.want) {
+				t.Fatalf("got %v, want %v", err, test.want)
+			}
+		})
+	}
+
+	if _, err := parseIDTokenHint(set, signToken(t, otherKey, "", claims(nil)), "client"); err == nil {
+		t.Fatal("accepted a token signed by another key")
+	}
+}
@@ -7,7 +7,16 @@ import (
 )
 
 func AllowedRedirect(app *appdb.Application, redirect string) bool {
-	for _, r := range app.RedirectURI {
+	return matchRedirect(app, app.RedirectURI, redirect)
+}
+
+// AllowedPostLogoutRedirect checks redirect against the post logout redirect urls registered for the application.
+func AllowedPostLogoutRedirect(app *appdb.Application, redirect string) bool {
+	return matchRedirect(app, app.PostLogoutRedirectURI, redirect)
+}
+
+func matchRedirect(app *appdb.Application, allowed []string, redirect string) bool {
+	for _, r := range allowed {
 		appURL, err := url.Parse(r)
 		if err != nil {
 			slog.Error("allowedRedirect", "error", err, "app", app.ID, "redirect", redirect, "allowed", r)
@@ -1,19 +1,21 @@
 package client
 
 import (
-	"database/sql"
+	"encoding/json"
 	"errors"
+	"fmt"
+	"maps"
 	"net/http"
-	"net/url"
 	"slices"
 	"strings"
 	"time"
 	"uyulala/internal/api"
 	"uyulala/internal/api/application"
+	"uyulala/internal/api/token"
 	"uyulala/internal/db"
 	"uyulala/internal/db/appdb"
 	"uyulala/internal/db/challengedb"
-	"uyulala/internal/db/keydb"
+	"uyulala/internal/db/scopedb"
 	"uyulala/internal/db/sessiondb"
 	"uyulala/internal/db/userdb"
 	"uyulala/openid/discovery"
@@ -21,11 +23,6 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/go-webauthn/webauthn/protocol"
 	"github.com/go-webauthn/webauthn/webauthn"
-	"github.com/lestrrat-go/jwx/jwa"
-	"github.com/lestrrat-go/jwx/jwk"
-	"github.com/lestrrat-go/jwx/jws"
-	"github.com/lestrrat-go/jwx/jwt"
-	"github.com/spf13/viper"
 )
 
 type SignatureData struct {
@@ -158,174 +155,51 @@ func collectBIDFlow(context *gin.Context, app *appdb.Application) {
 	context.JSON(http.StatusOK, response.Response())
 }
 
-func createIDToken(context *gin.Context, sessionID, userID, nonce string, app *appdb.Application, appKey jwk.Key,
-	response *CollectResponseExp) (string, error) {
-	lastAuth, err := userdb.GetAuthTime(context, userID, app.ID)
-	if err != nil && !errors.Is(err, sql.ErrNoRows) {
-		api.AbortError(context, http.StatusInternalServerError, "internal_error", "Unexpected error", err)
-		return "", err
-	}
-
-	startTime := time.Now()
-	if response != nil {
-		startTime = response.Signed
-	}
-
-	token := jwt.New()
-	_ = token.Set("sub", userID)
-	_ = token.Set("iss", viper.GetString("issuer"))
-	_ = token.Set("aud", app.ID)
-	_ = token.Set("exp", startTime.Add(viper.GetDuration("idToken.length")).Unix())
-	_ = token.Set("nbf", startTime.Unix())
-	_ = token.Set("auth_time", lastAuth.Unix())
-	_ = token.Set("iat", time.Now().Unix())
-	if response != nil {
-		_ = token.Set("uv", response.AssertionSignature.Response.AuthenticatorData.Flags.UserVerified())
-		_ = token.Set("up", response.AssertionSignature.Response.AuthenticatorData.Flags.UserPresent())
-	}
-
-	if sessionID != "" {
-		_ = token.Set("sid", sessionID)
-	}
-	if nonce != "" {
-		_ = token.Set("nonce", nonce)
-	}
-
-	data, err := jwt.Sign(token, jwa.SignatureAlgorithm(appKey.Algorithm()), appKey)
-	if err != nil {
-		api.AbortError(context, http.StatusInternalServerError, "internal_error", "Unexpected error", err)
-		return "", err
-	}
-	tokenString := string(data)
-	return tokenString, nil
-}
-
-func createAccessToken(ctx *gin.Context, sessionID, userID string, key jwk.Key, app *appdb.Application,
-	response *CollectResponseExp) (string, error) {
-	startTime := time.Now()
-	if response != nil {
-		startTime = response.Signed
-	}
-
-	token := jwt.New()
-	_ = token.Set("sub", userID)
-	_ = token.Set("iss", viper.GetString("issuer"))
-	_ = token.Set("aud", app.ID)
-	_ = token.Set("exp", startTime.Add(viper.GetDuration("accessToken.length")).Unix())
-	_ = token.Set("nbf", startTime.Unix())
-	_ = token.Set("iat", time.Now().Unix())
-	if sessionID != "" {
-		_ = token.Set("sid", sessionID)
-	}
-	extra := viper.GetStringMap("accessToken.extension")
-	for k, v := range extra {
-		_ = token.Set(k, v)
-	}
-	hdrs := jws.NewHeaders()
-	_ = hdrs.Set(jws.TypeKey, "at+jwt")
-	data, err := jwt.Sign(token, jwa.SignatureAlgorithm(key.Algorithm()), key, jwt.WithJwsHeaders(hdrs))
-	if err != nil {
-		api.AbortError(ctx, http.StatusInternalServerError, "internal_error", "Unexpected error", err)
-		return "", err
-	}
-	tokenString := string(data)
-	return tokenString, nil
-}
-
-type TokenResponse struct {
-	AccessToken  string `json:"access_token,omitempty"`
-	Scope        string `json:"scope,omitempty"`
-	IDToken      string `json:"id_token,omitempty"`
-	TokenType    string `json:"token_type,omitempty"`
-	RefreshToken string `json:"refresh_token,omitempty" `
-}
-
 func collectOAuth2Flow(context *gin.Context, app *appdb.Application) {
-	var (
-		idToken      string
-		accessToken  string
-		refreshToken string
-		sessionID    string
-		resultScopes []string
-	)
-	key, err := keydb.GetKey(context, app.KeyID)
-	if err != nil {
-		api.AbortError(context, http.StatusInternalServerError, "no_key", "This shouldn't happen. couldn't find the signing key.", err)
-		return
-	}
-	appKey, err := key.GetPrivateJWK()
-	if err != nil {
-		api.AbortError(context, http.StatusInternalServerError, "key_parsing_error", "This shouldn't happen. couldn't parse the private key for signing", err)
-		return
-	}
+	var res *token.Response
 	switch context.PostForm("grant_type") {
-	case discovery.GrantTypeAuthorizationCode:
+	case discovery.GrantTypeAuthorizationCode, discovery.GrantTypeCIBA:
 		challenge := application.GetCurrentChallenge(context)
 		if !challenge.ValidateOAuthCollect(context) {
 			return
 		}
-		if err := challengedb.SetChallengeStatus(context, challenge.ID, challengedb.StatusCollected); err != nil {
-			api.AbortError(context, http.StatusInternalServerError, "internal_error", "Unexpected error", err)
+		tmp, err := token.Issue(context, app, challenge, context.PostFormArray("resource"))
+		if err != nil {
 			return
 		}
-		response := collectResponseFromChallenge(challenge)
-		oauth2Ctx, _ := url.ParseQuery(challenge.OAuth2Context)
-		scopes := strings.FieldsFunc(oauth2Ctx.Get("scope"), func(c rune) bool {
-			switch c {
-			case ' ', '\t', '\r', '\n':
-				return true
-			}
-			return false
-		})
-		userKey, err := userdb.GetKey(context, response.AssertionSignature.RawID)
+		res = tmp
+	case discovery.GrantTypeRefresh:
+		var (
+			idToken      string
+			accessToken  string
+			refreshToken string
+			resultScopes []string
+		)
+		appKey, err := token.SigningKey(context, app)
 		if err != nil {
-			api.AbortError(context, http.StatusInternalServerError, "internal_error", "Unexpected error", err)
 			return
 		}
-
-		if slices.Contains(scopes, "offline_access") {
-			sess, err := sessiondb.Create(context, userKey.UserID, app.ID, oauth2Ctx.Get("scope"))
-			if err != nil {
-				api.AbortError(context, http.StatusInternalServerError, "internal_error", "Unexpected error", err)
-				return
-			}
-			resultScopes = append(resultScopes, "offline_access")
-			sessionID = sess.ID
-			refreshToken, err = sess.CreateRefreshToken(appKey)
-			if err != nil {
-				api.AbortError(context, http.StatusInternalServerError, "internal_error", "Unexpected error", err)
-				return
-			}
-			accessToken, err = createAccessToken(context, sessionID, userKey.UserID, appKey, app, response)
-			if err != nil {
-				return
-			}
+		session := application.GetCurrentSession(context)
+		scopes := api.SpaceDelimited(session.RequestedScopes)
+		resources, err := token.GrantResources(context, context.PostFormArray("resource"), strings.Fields(session.Resources))
+		if err != nil {
+			return
 		}
-
-		if slices.Contains(scopes, "openid") {
-			// TODO: add ACR data
-			resultScopes = append(resultScopes, "openid")
-			idToken, err = createIDToken(context, sessionID, userKey.UserID, oauth2Ctx.Get("nonce"), app, appKey, response)
-			if err != nil {
-				return
-			}
+		grantedScopes, err := token.ClaimScopes(context, app, scopes)
+		if err != nil {
+			return
+		}
+		claimsRequest, err := api.ParseClaimsRequest(session.Claims)
+		if err != nil {
+			api.AbortError(context, http.StatusInternalServerError, "internal_error", "Unexpected error", err)
+			return
 		}
 
-	case discovery.GrantTypeRefresh:
-		session := application.GetCurrentSession(context)
-		scopes := strings.FieldsFunc(session.RequestedScopes, func(c rune) bool {
-			switch c {
-			case ' ', '\t', '\r', '\n':
-				return true
-			}
-			return false
-		})
-
-		if err := sessiondb.Rotate(context, session); err != nil {
+		if err := sessiondb.Rotate(context, session, token.RefreshTokenLength(app)); err != nil {
 			api.AbortError(context, http.StatusInternalServerError, "internal_error", "Unexpected error", err)
 			return
 		} else {
-			tmp, err := session.CreateRefreshToken(appKey)
+			tmp, err := session.CreateRefreshToken(appKey, token.Confirmation(context))
 			if err != nil {
 				api.AbortError(context, http.StatusInternalServerError, "internal_error", "Unexpected error", err)
 				return
@@ -333,7 +207,12 @@ func collectOAuth2Flow(context *gin.Context, app *appdb.Application) {
 			refreshToken = tmp
 		}
 		if slices.Contains(scopes, "openid") {
-			idToken, err = createIDToken(context, session.ID, session.UserID, "", app, appKey, nil)
+			claims, err := token.UserClaims(context, app, session.UserID, grantedScopes, claimsRequest.IDToken)
+			if err != nil {
+				return
+			}
+			maps.Copy(claims, token.SessionAuthentication(session))
+			idToken, err = token.IDToken(context, session.ID, session.UserID, "", app, appKey, nil, claims)
 			resultScopes = append(resultScopes, "openid")
 			if err != nil {
 				api.AbortError(context, http.StatusInternalServerError, "internal_error", "Unexpected error", err)
@@ -341,73 +220,104 @@ func collectOAuth2Flow(context *gin.Context, app *appdb.Application) {
 			}
 		}
 		resultScopes = append(resultScopes, "offline_access")
-		accessToken, err = createAccessToken(context, session.ID, session.UserID, appKey, app, nil)
+		resultScopes = append(resultScopes, grantedScopes...)
+		subject, err := token.Subject(context, app, session.UserID)
 		if err != nil {
 			api.AbortError(context, http.StatusInternalServerError, "internal_error", "Unexpected error", err)
 			return
 		}
-	case discovery.GrantTypeCIBA:
-		challenge := application.GetCurrentChallenge(context)
-		if !challenge.ValidateOAuthCollect(context) {
-			return
+		scope := strings.Join(grantedScopes, " ")
+		if len(resources) > 0 {
+			scope = session.RequestedScopes
 		}
-		if err := challengedb.SetChallengeStatus(context, challenge.ID, challengedb.StatusCollected); err != nil {
+		accessToken, err = token.AccessToken(context, session.ID, session.UserID, subject, scope, appKey, app, nil, resources,
+			token.AuthorizationDetailsClaims(session.AuthorizationDetails))
+		if err != nil {
 			api.AbortError(context, http.StatusInternalServerError, "internal_error", "Unexpected error", err)
 			return
 		}
-		response := collectResponseFromChallenge(challenge)
-		oauth2Ctx, _ := url.ParseQuery(challenge.OAuth2Context)
-		scopes := strings.FieldsFunc(oauth2Ctx.Get("scope"), func(c rune) bool {
-			switch c {
-			case ' ', '\t', '\r', '\n':
-				return true
-			}
-			return false
-		})
-
-		userKey, err := userdb.GetKey(context, response.AssertionSignature.RawID)
+		res = &token.Response{
+			AccessToken:          accessToken,
+			Scope:                strings.Join(resultScopes, " "),
+			IDToken:              idToken,
+			RefreshToken:         refreshToken,
+			TokenType:            token.Type(context),
+			AuthorizationDetails: json.RawMessage(session.AuthorizationDetails),
+		}
+	case discovery.GrantTypeDeviceCode:
+		challenge := application.GetCurrentChallenge(context)
+		if !challenge.ValidateDeviceCollect(context) {
+			return
+		}
+		tmp, err := token.Issue(context, app, challenge, context.PostFormArray("resource"))
 		if err != nil {
-			api.AbortError(context, http.StatusInternalServerError, "internal_error", "Unexpected error", err)
 			return
 		}
-
-		if slices.Contains(scopes, "offline_access") {
-			sess, err := sessiondb.Create(context, userKey.UserID, app.ID, oauth2Ctx.Get("scope"))
-			if err != nil {
-				api.AbortError(context, http.StatusInternalServerError, "internal_error", "Unexpected error", err)
-				return
-			}
-			resultScopes = append(resultScopes, "offline_access")
-			sessionID = sess.ID
-			refreshToken, err = sess.CreateRefreshToken(appKey)
-			if err != nil {
-				api.AbortError(context, http.StatusInternalServerError, "internal_error", "Unexpected error", err)
-				return
-			}
-			accessToken, err = createAccessToken(context, sessionID, userKey.UserID, appKey, app, response)
-			if err != nil {
-				return
-			}
+		if err := challengedb.DeleteDeviceCode(context, context.PostForm("device_code")); err != nil {
+			api.AbortError(context, http.StatusInternalServerError, "internal_error", "Unexpected error", err)
+			return
 		}
-
-		if slices.Contains(scopes, "openid") {
-			// TODO: add ACR data
-			resultScopes = append(resultScopes, "openid")
-			idToken, err = createIDToken(context, sessionID, userKey.UserID, oauth2Ctx.Get("nonce"), app, appKey, response)
-			if err != nil {
-				return
-			}
+		res = tmp
+	case discovery.GrantTypeClientCredentials:
+		tmp, err := collectClientCredentials(context, app)
+		if err != nil {
+			return
 		}
+		res = tmp
 	case "":
 		api.AbortError(context, http.StatusBadRequest, "invalid_request", "Missing grant_type", nil)
+		return
 	}
-	context.JSON(http.StatusOK, &TokenResponse{
-		AccessToken:  accessToken,
-		Scope:        strings.Join(resultScopes, " "),
-		IDToken:      idToken,
-		RefreshToken: refreshToken,
-		TokenType:    "Bearer",
+	context.JSON(http.StatusOK, res)
+}
+
+// collectClientCredentials issues an access token for the application itself, limited to its allowed scopes and, for
+// registered scopes, to the applications the scope registry allows.
+func collectClientCredentials(context *gin.Context, app *appdb.Application) (*token.Response, error) {
+	registered, err := scopedb.Registered(context, app.AllowedScopes)
+	if err != nil {
+		api.AbortError(context, http.StatusInternalServerError, "internal_error", "Unexpected error", err)
+		return nil, err
+	}
+	allowed := slices.DeleteFunc(slices.Clone(app.AllowedScopes), func(name string) bool {
+		scope, ok := registered[name]
+		return ok && !scope.Allowed(app.ID)
 	})
+	if len(allowed) == 0 {
+		err := errors.New("client has no allowed scopes")
+		api.AbortError(context, http.StatusBadRequest, "unauthorized_client", "Client is not allowed to use client_credentials", err)
+		return nil, err
+	}
+	scopes := api.SpaceDelimited(context.PostForm("scope"))
+	if len(scopes) == 0 {
+		scopes = allowed
+	}
+	for _, scope := range scopes {
+		if !slices.Contains(allowed, scope) {
+			err := fmt.Errorf("scope %s is not allowed", scope)
+			api.AbortError(context, http.StatusBadRequest, "invalid_scope", "Scope not allowed for this client", err)
+			return nil, err
+		}
+	}
+	// Any registered resource server can be requested with client credentials.
+	resources, err := token.GrantResources(context, context.PostFormArray("resource"), context.PostFormArray("resource"))
+	if err != nil {
+		return nil, err
+	}
+	appKey, err := token.SigningKey(context, app)
+	if err != nil {
+		return nil, err
+	}
+	scope := strings.Join(scopes, " ")
+	accessToken, err := token.AccessToken(context, "", "", app.ID, scope, appKey, app, nil, resources, nil)
+	if err != nil {
+		return nil, err
+	}
+	return &token.Response{
+		AccessToken: accessToken,
+		Scope:       token.ResourceScope(scope, resources),
+		TokenType:   token.Type(context),
+	}, nil
 }
 
 func collectHandler(context *gin.Context) {
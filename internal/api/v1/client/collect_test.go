@@ -0,0 +1,101 @@
+package client
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"database/sql/driver"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+	"uyulala/internal/db/appdb"
+	"uyulala/internal/db/dbtest"
+	"uyulala/internal/db/scopedb"
+
+	"github.com/lestrrat-go/jwx/jwa"
+	"github.com/lestrrat-go/jwx/jwk"
+	"github.com/lestrrat-go/jwx/jwt"
+)
+
+// credentialsDB returns a database with the registered scopes and a signing key for the applications.
+func credentialsDB(t *testing.T, scopes []*scopedb.Scope) *dbtest.DB {
+	t.Helper()
+	private, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := jwk.New(private)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = key.Set(jwk.KeyIDKey, "key")
+	_ = key.Set(jwk.AlgorithmKey, jwa.ES256)
+	data, err := json.Marshal(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	db := dbtest.Open()
+	db.Procedure("get_server_key", func([]driver.Value) ([]string, [][]driver.Value, error) {
+		return []string{"kid", "type", "alg", "created", "private_key", "public_key"},
+			[][]driver.Value{{"key", "EC", "ES256", time.Now(), string(data), ""}}, nil
+	})
+	db.Scopes(scopes)
+	return db
+}
+
+func TestCollectClientCredentials(t *testing.T) {
+	scopes := []*scopedb.Scope{
+		{Name: "reports:read"},
+		{Name: "payments:write", Apps: []string{"payments"}},
+	}
+	app := &appdb.Application{ID: "client", KeyID: "key", AllowedScopes: []string{"reports:read", "payments:write", "jobs"}}
+	limited := &appdb.Application{ID: "client", KeyID: "key", AllowedScopes: []string{"payments:write"}}
+	tests := []struct {
+		name  string
+		app   *appdb.Application
+		scope string
+		// want is the granted scope, or the error code.
+		want string
+		ok   bool
+	}{
+		{"allowed scopes", app, "", "reports:read jobs", true},
+		{"requested scope", app, "jobs", "jobs", true},
+		{"scope the registry limits to another application", app, "reports:read payments:write", "invalid_scope", false},
+		{"scope not allowed", app, "admin", "invalid_scope", false},
+		{"only scopes of other applications", limited, "", "unauthorized_client", false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ctx, recorder := dbtest.Recorded(credentialsDB(t, scopes), http.MethodPost, "/api/v1/collect")
+			ctx.Request.Form = url.Values{"grant_type": {"client_credentials"}}
+			ctx.Request.PostForm = url.Values{"scope": {test.scope}}
+			res, err := collectClientCredentials(ctx, test.app)
+			if !test.ok {
+				var body struct {
+					Error string `json:"error"`
+				}
+				_ = json.Unmarshal(recorder.Body.Bytes(), &body)
+				if err == nil || recorder.Code != http.StatusBadRequest || body.Error != test.want {
+					t.Errorf("granted %+v with %d %s, want %s", res, recorder.Code, recorder.Body, test.want)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if res.Scope != test.want {
+				t.Errorf("scope = %q, want %q", res.Scope, test.want)
+			}
+			token, err := jwt.ParseString(res.AccessToken)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if scope, _ := token.Get("scope"); scope != test.want {
+				t.Errorf("access token scope = %v, want %q", scope, test.want)
+			}
+		})
+	}
+}
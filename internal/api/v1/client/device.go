@@ -0,0 +1,99 @@
+package client
+
+import (
+	"net/http"
+	"time"
+	"uyulala/internal/api"
+	"uyulala/internal/api/application"
+	"uyulala/internal/api/token"
+	"uyulala/internal/authn"
+	"uyulala/internal/db/challengedb"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-webauthn/webauthn/webauthn"
+	"github.com/spf13/viper"
+)
+
+const (
+	deviceCodeTimeout  = int64(5 * 60)
+	deviceCodeInterval = int64(5)
+)
+
+type DeviceAuthorizationResponse struct {
+	DeviceCode              string `json:"device_code"`
+	UserCode                string `json:"user_code"`
+	VerificationURI         string `json:"verification_uri"`
+	VerificationURIComplete string `json:"verification_uri_complete"`
+	ExpiresIn               int64  `json:"expires_in"`
+	Interval                int64  `json:"interval"`
+}
+
+// deviceAuthorizationHandler starts the device authorization grant (RFC 8628).
+// The user signs the challenge on another device, either by entering the user code or by following the complete
+// verification uri.
+func deviceAuthorizationHandler(ctx *gin.Context) {
+	if err := ctx.Request.ParseForm(); err != nil {
+		api.AbortError(ctx, http.StatusBadRequest, "invalid_request", "Invalid request", err)
+		return
+	}
+	app := application.GetCurrentApplication(ctx)
+	form := ctx.Request.PostForm
+	scopes := api.SpaceDelimited(form.Get("scope"))
+	if len(scopes) == 0 {
+		api.AbortError(ctx, http.StatusBadRequest, "invalid_request", "Scope is required", nil)
+		return
+	}
+	if !api.ValidateScopes(ctx, app, scopes) || !api.ValidateClaimsRequest(ctx, form) {
+		return
+	}
+	acrValues := api.SpaceDelimited(form.Get("acr_values"))
+
+	cfg := authn.CreateWebauthnConfig()
+	login, sessionData, err := cfg.BeginDiscoverableLogin(
+		webauthn.WithUserVerification(token.UserVerification(acrValues)))
+	if err != nil {
+		api.AbortError(ctx, http.StatusInternalServerError, "internal_error", "Unexpected error", err)
+		return
+	}
+	challenge, secret, err := challengedb.CreateChallenge(ctx, &challengedb.CreateChallengeData{
+		Type:          "webauthn.get",
+		AppID:         app.ID,
+		Expire:        time.Now().Add(time.Duration(deviceCodeTimeout) * time.Second),
+		PublicData:    login,
+		PrivateData:   sessionData,
+		Nonce:         "",
+		SignatureText: "",
+		SignatureData: nil,
+		RedirectURL:   "",
+	}, "")
+	if err != nil {
+		api.AbortError(ctx, http.StatusInternalServerError, "internal_error", "Unexpected error", err)
+		return
+	}
+
+	oauth2Context := ctx.Request.PostForm
+	oauth2Context.Del("client_secret")
+	if err := challengedb.SetOAuth2Context(ctx, challenge, oauth2Context.Encode()); err != nil {
+		api.AbortError(ctx, http.StatusInternalServerError, "internal_error", "Unexpected error", err)
+		return
+	}
+
+	deviceCode, userCode, err := challengedb.CreateDeviceCode(ctx, challenge, deviceCodeInterval)
+	if err != nil {
+		api.AbortError(ctx, http.StatusInternalServerError, "internal_error", "Unexpected error", err)
+		return
+	}
+	verificationURIComplete, err := api.AuthenticatorURL(challenge, secret)
+	if err != nil {
+		api.AbortError(ctx, http.StatusInternalServerError, "internal_error", "Unexpected error", err)
+		return
+	}
+	api.JSONResponse(ctx, &DeviceAuthorizationResponse{
+		DeviceCode:              deviceCode,
+		UserCode:                challengedb.FormatUserCode(userCode),
+		VerificationURI:         viper.GetString("issuer") + "/device",
+		VerificationURIComplete: verificationURIComplete,
+		ExpiresIn:               deviceCodeTimeout,
+		Interval:                deviceCodeInterval,
+	})
+}
@@ -1,35 +1,48 @@
 package client
 
 import (
+	"database/sql"
+	"errors"
 	"net/http"
 	"uyulala/internal/api"
+	"uyulala/internal/api/token"
+	"uyulala/internal/db/appdb"
 
 	"github.com/gin-gonic/gin"
 	"github.com/lestrrat-go/jwx/jwt"
 )
 
-func getUserHint(ctx *gin.Context) (string, error) {
+func getUserHint(ctx *gin.Context, app *appdb.Application) (string, error) {
 	loginHint := ctx.Request.Form.Get("login_hint")
 	loginHintToken := ctx.Request.Form.Get("login_hint_token")
 	idTokenHint := ctx.Request.Form.Get("id_token_hint")
-	if loginHint != "" {
-		return loginHint, nil
-	}
-	if idTokenHint != "" || loginHintToken != "" {
-		var token jwt.Token
-		var err error
-		if idTokenHint != "" {
-			token, err = jwt.Parse([]byte(idTokenHint), jwt.WithValidate(true))
-		} else {
-			token, err = jwt.Parse([]byte(loginHintToken), jwt.WithValidate(true))
+	if loginHint == "" && idTokenHint != "" {
+		hint, err := token.VerifyIDTokenHint(ctx, idTokenHint, app.ID)
+		if err != nil {
+			api.AbortError(ctx, http.StatusBadRequest, "invalid_request", "Invalid id_token_hint", err)
+			return "", errors.New("invalid id_token_hint")
 		}
+		loginHint = hint.Subject()
+	} else if loginHint == "" && loginHintToken != "" {
+		hint, err := jwt.Parse([]byte(loginHintToken), jwt.WithValidate(true))
 		if err != nil {
 			api.AbortError(ctx, http.StatusBadRequest, "expired_login_hint_token", "Invalid token", err)
 			return "", err
 		}
-		if sub, ok := token.Get("sub"); ok {
-			return sub.(string), nil
+		if sub, ok := hint.Get("sub"); ok {
+			loginHint = sub.(string)
 		}
 	}
-	return "", nil
+	if loginHint == "" {
+		return "", nil
+	}
+	userID, err := token.ResolveSubject(ctx, app, loginHint)
+	if errors.Is(err, sql.ErrNoRows) {
+		api.AbortError(ctx, http.StatusBadRequest, "unknown_user_id", "Couldn't find the hinted user", err)
+		return "", err
+	} else if err != nil {
+		api.AbortError(ctx, http.StatusInternalServerError, "internal_error", "Unexpected error", err)
+		return "", err
+	}
+	return userID, nil
 }
@@ -0,0 +1,122 @@
+package client
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"uyulala/internal/api"
+	"uyulala/internal/api/token"
+	"uyulala/internal/db/appdb"
+	"uyulala/internal/db/sessiondb"
+
+	"github.com/gin-gonic/gin"
+)
+
+type IntrospectionResponse struct {
+	Active       bool           `json:"active"`
+	Subject      string         `json:"sub,omitempty"`
+	ClientID     string         `json:"client_id,omitempty"`
+	Audience     []string       `json:"aud,omitempty"`
+	Scope        string         `json:"scope,omitempty"`
+	Expires      int64          `json:"exp,omitempty"`
+	IssuedAt     int64          `json:"iat,omitempty"`
+	Issuer       string         `json:"iss,omitempty"`
+	SessionID    string         `json:"sid,omitempty"`
+	TokenType    string         `json:"token_type,omitempty"`
+	Confirmation map[string]any `json:"cnf,omitempty"`
+	// AuthorizationDetails are the authorization details (RFC 9396) the token was issued with.
+	AuthorizationDetails any `json:"authorization_details,omitempty"`
+}
+
+func introspectHandler(ctx *gin.Context) {
+	tokenString := ctx.PostForm("token")
+	if tokenString == "" {
+		api.AbortError(ctx, http.StatusBadRequest, "invalid_request", "Missing token", nil)
+		return
+	}
+	inactive := &IntrospectionResponse{Active: false}
+	tok, typ, err := token.Verify(ctx, tokenString)
+	if err != nil {
+		slog.Info("Introspected invalid token", "error", err)
+		api.JSONResponse(ctx, inactive)
+		return
+	}
+	res := &IntrospectionResponse{
+		Active:   true,
+		Issuer:   tok.Issuer(),
+		IssuedAt: tok.IssuedAt().Unix(),
+	}
+	if !tok.Expiration().IsZero() {
+		res.Expires = tok.Expiration().Unix()
+	}
+	switch typ {
+	case token.TypeAccess:
+		res.TokenType = "Bearer"
+		res.Subject = tok.Subject()
+		res.Audience = tok.Audience()
+		if clientID, ok := tok.Get("client_id"); ok {
+			res.ClientID, _ = clientID.(string)
+		} else if aud := tok.Audience(); len(aud) > 0 {
+			res.ClientID = aud[0]
+		}
+		if scope, ok := tok.Get("scope"); ok {
+			res.Scope, _ = scope.(string)
+		}
+		if details, ok := tok.Get("authorization_details"); ok {
+			res.AuthorizationDetails = details
+		}
+		if sid, ok := tok.Get("sid"); ok {
+			res.SessionID, _ = sid.(string)
+		}
+		if cnf, ok := tok.Get("cnf"); ok {
+			res.Confirmation, _ = cnf.(map[string]any)
+			if _, ok := res.Confirmation[token.DPoPConfirmationKey]; ok {
+				res.TokenType = "DPoP"
+			}
+		}
+		if res.SessionID != "" {
+			sess, err := sessiondb.Get(ctx, res.SessionID)
+			if err != nil {
+				api.JSONResponse(ctx, inactive)
+				return
+			}
+			if res.Scope == "" {
+				res.Scope = sess.RequestedScopes
+			}
+		}
+	case token.TypeRefresh:
+		sess, err := sessiondb.Get(ctx, tok.JwtID())
+		if err != nil {
+			api.JSONResponse(ctx, inactive)
+			return
+		}
+		// A rotated refresh token can't be used anymore.
+		if c, ok := tok.Get("counter"); !ok || uint32(c.(float64)) != sess.Counter {
+			api.JSONResponse(ctx, inactive)
+			return
+		}
+		app, err := appdb.GetApplication(ctx, sess.AppID)
+		if err != nil {
+			api.JSONResponse(ctx, inactive)
+			return
+		}
+		// The subject is the one the token was issued with, pairwise for pairwise applications.
+		if res.Subject, err = token.Subject(ctx, app, sess.UserID); err != nil {
+			api.AbortError(ctx, http.StatusInternalServerError, "internal_error", "Unexpected error", err)
+			return
+		}
+		res.ClientID = sess.AppID
+		res.Scope = sess.RequestedScopes
+		res.SessionID = sess.ID
+		if sess.AuthorizationDetails != "" {
+			res.AuthorizationDetails = json.RawMessage(sess.AuthorizationDetails)
+		}
+		if sess.ExpireAt.Valid {
+			res.Expires = sess.ExpireAt.Time.Unix()
+		}
+	default:
+		api.JSONResponse(ctx, inactive)
+		return
+	}
+	api.JSONResponse(ctx, res)
+}
@@ -0,0 +1,59 @@
+package client
+
+import (
+	"net/http"
+	"time"
+	"uyulala/internal/api"
+	"uyulala/internal/api/application"
+	"uyulala/internal/api/token"
+	"uyulala/internal/db/requestdb"
+
+	"github.com/gin-gonic/gin"
+)
+
+const parTimeout = int64(90)
+
+type PushedAuthorizationResponse struct {
+	RequestURI string `json:"request_uri"`
+	ExpiresIn  int64  `json:"expires_in"`
+}
+
+// pushedAuthorizationRequestHandler stores an authorization request pushed by an authenticated client (RFC 9126),
+// keeping parameters such as data and binding_message out of the front channel.
+func pushedAuthorizationRequestHandler(ctx *gin.Context) {
+	if err := ctx.Request.ParseForm(); err != nil {
+		api.AbortError(ctx, http.StatusBadRequest, "invalid_request", "Invalid request", err)
+		return
+	}
+	app := application.GetCurrentApplication(ctx)
+	form := ctx.Request.PostForm
+	if clientID := form.Get("client_id"); clientID != "" && clientID != app.ID {
+		api.AbortError(ctx, http.StatusBadRequest, "invalid_request", "client_id does not match the authenticated client", nil)
+		return
+	}
+	if form.Has("request_uri") {
+		api.AbortError(ctx, http.StatusBadRequest, "invalid_request", "request_uri is not allowed in a pushed authorization request", nil)
+		return
+	}
+	form, ok := token.ResolveRequestObject(ctx, app, form)
+	if !ok {
+		return
+	}
+	if _, ok := api.ValidateAuthorizationRequest(ctx, app, form); !ok {
+		return
+	}
+	if !token.ValidateResources(ctx, form) {
+		return
+	}
+	form.Del("client_secret")
+	form.Set("client_id", app.ID)
+	requestURI, err := requestdb.Create(ctx, app.ID, form, time.Now().Add(time.Duration(parTimeout)*time.Second))
+	if err != nil {
+		api.AbortError(ctx, http.StatusInternalServerError, "internal_error", "Unexpected error", err)
+		return
+	}
+	ctx.JSON(http.StatusCreated, &PushedAuthorizationResponse{
+		RequestURI: requestURI,
+		ExpiresIn:  parTimeout,
+	})
+}
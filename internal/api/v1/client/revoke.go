@@ -0,0 +1,65 @@
+package client
+
+import (
+	"database/sql"
+	"errors"
+	"log/slog"
+	"net/http"
+	"uyulala/internal/api"
+	"uyulala/internal/api/application"
+	"uyulala/internal/api/token"
+	"uyulala/internal/db/sessiondb"
+
+	"github.com/gin-gonic/gin"
+)
+
+// revokeHandler ends the session behind a refresh token or an access token carrying a sid.
+// Invalid and unknown tokens are not an error, as described in RFC 7009 section 2.2.
+func revokeHandler(ctx *gin.Context) {
+	tokenString := ctx.PostForm("token")
+	if tokenString == "" {
+		api.AbortError(ctx, http.StatusBadRequest, "invalid_request", "Missing token", nil)
+		return
+	}
+	app := application.GetCurrentApplication(ctx)
+	tok, typ, err := token.Verify(ctx, tokenString)
+	if err != nil {
+		slog.Info("Revoking invalid token", "error", err)
+		ctx.Status(http.StatusOK)
+		return
+	}
+	var sessionID string
+	switch typ {
+	case token.TypeRefresh:
+		sessionID = tok.JwtID()
+	case token.TypeAccess:
+		if sid, ok := tok.Get("sid"); ok {
+			sessionID, _ = sid.(string)
+		}
+	}
+	if sessionID == "" {
+		api.AbortError(ctx, http.StatusBadRequest, "unsupported_token_type", "Only refresh tokens and access tokens with a session can be revoked", nil)
+		return
+	}
+	sess, err := sessiondb.Get(ctx, sessionID)
+	if errors.Is(err, sql.ErrNoRows) {
+		ctx.Status(http.StatusOK)
+		return
+	} else if err != nil {
+		api.AbortError(ctx, http.StatusInternalServerError, "internal_error", "Unexpected error", err)
+		return
+	}
+	if sess.AppID != app.ID {
+		api.AbortError(ctx, http.StatusBadRequest, "invalid_client", "This token was issued to another client", nil)
+		return
+	}
+	if err := sessiondb.Delete(ctx, sess.ID); err != nil {
+		api.AbortError(ctx, http.StatusInternalServerError, "internal_error", "Unexpected error", err)
+		return
+	}
+	if err := token.BackChannelLogout(ctx, sess); err != nil {
+		api.AbortError(ctx, http.StatusInternalServerError, "internal_error", "Unexpected error", err)
+		return
+	}
+	ctx.Status(http.StatusOK)
+}
@@ -8,6 +8,14 @@ func AddRoutes(g *gin.RouterGroup) {
 	g.POST("/sign", createChallengeHandler)
 	g.POST("/collect", collectHandler)
 	g.OPTIONS("/collect", func(context *gin.Context) {})
+	g.POST("/introspect", introspectHandler)
+	g.OPTIONS("/introspect", func(context *gin.Context) {})
+	g.POST("/revoke", revokeHandler)
+	g.OPTIONS("/revoke", func(context *gin.Context) {})
+	g.POST("/device_authorization", deviceAuthorizationHandler)
+	g.OPTIONS("/device_authorization", func(context *gin.Context) {})
+	g.POST("/par", pushedAuthorizationRequestHandler)
+	g.OPTIONS("/par", func(context *gin.Context) {})
 	g.GET("/mds/:aaguid", aaguidHandler)
 	g.OPTIONS("/mds/:aaguid", func(context *gin.Context) {})
 }
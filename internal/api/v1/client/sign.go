@@ -1,21 +1,18 @@
 package client
 
 import (
-	"bytes"
-	"crypto/sha256"
 	"net/http"
 	"slices"
 	"strconv"
-	"strings"
 	"time"
 	"unicode/utf8"
 	"uyulala/internal/api"
 	"uyulala/internal/api/application"
+	"uyulala/internal/api/token"
 	"uyulala/internal/authn"
 	"uyulala/internal/db"
 	"uyulala/internal/db/challengedb"
 	"uyulala/internal/db/userdb"
-	"uyulala/openid/discovery"
 
 	"github.com/gin-gonic/gin"
 	"github.com/go-webauthn/webauthn/protocol"
@@ -129,25 +126,10 @@ func createBIDChallenge(ctx *gin.Context) {
 	}
 
 	var nonce string
-	var hash [32]byte
 	challengeID := db.GenerateID(8)
 	if req.Text != "" {
 		nonce = db.GenerateID(8)
-		buff := bytes.Buffer{}
-		buff.Write([]byte(req.UserID))
-		buff.WriteByte('\n')
-		buff.Write([]byte(app.ID))
-		buff.WriteByte('\n')
-		buff.Write([]byte(challengeID))
-		buff.WriteByte('\n')
-		buff.Write([]byte(nonce))
-		buff.WriteByte('\n')
-		buff.Write([]byte(req.Text))
-		buff.WriteByte('\n')
-		buff.Write(req.Data)
-		hash = sha256.Sum256(buff.Bytes())
-
-		opts = append(opts, webauthn.WithChallenge(hash[:]))
+		opts = append(opts, webauthn.WithChallenge(authn.ChallengeHash(req.UserID, app.ID, challengeID, nonce, req.Text, req.Data)))
 	}
 
 	cfg := authn.CreateWebauthnConfig()
@@ -188,29 +170,36 @@ func createCIBAChallenge(ctx *gin.Context) {
 		return
 	}
 	app := application.GetCurrentApplication(ctx)
-	form := ctx.Request.Form
-	scopes := strings.FieldsFunc(form.Get("scope"), func(r rune) bool {
-		switch r {
-		case ' ', '\t', '\r', '\n':
-			return true
-		}
-		return false
-	})
+	form, ok := token.ResolveRequestObject(ctx, app, ctx.Request.Form)
+	if !ok {
+		return
+	}
+	ctx.Request.Form = form
+	if !token.ValidateResources(ctx, form) {
+		return
+	}
+	scopes := api.SpaceDelimited(form.Get("scope"))
 	if len(scopes) == 0 {
 		api.AbortError(ctx, http.StatusBadRequest, "invalid_request", "Scope is required", nil)
 		return
 	}
+	if !api.ValidateScopes(ctx, app, scopes) || !api.ValidateClaimsRequest(ctx, form) {
+		return
+	}
 	clientNotificationToken := form.Get("client_notification_token")
-	acrValues := strings.FieldsFunc(form.Get("acr_values"), func(r rune) bool {
-		switch r {
-		case ' ', '\t', '\r', '\n':
-			return true
-		}
-		return false
-	})
+	acrValues := api.SpaceDelimited(form.Get("acr_values"))
 
 	bindingMessage := form.Get("binding_message")
 	requestedExpiry := form.Get("requested_expiry")
+	var authorizationDetails []byte
+	if form.Has("authorization_details") {
+		var err error
+		if authorizationDetails, err = api.ParseAuthorizationDetails(form.Get("authorization_details")); err != nil {
+			api.AbortError(ctx, http.StatusBadRequest, "invalid_authorization_details", err.Error(), err)
+			return
+		}
+		form.Set("authorization_details", string(authorizationDetails))
+	}
 	if !slices.Contains(scopes, "openid") {
 		api.AbortError(ctx, http.StatusBadRequest, "invalid_request", "Scope does not contain openid", nil)
 		return
@@ -219,21 +208,15 @@ func createCIBAChallenge(ctx *gin.Context) {
 		api.AbortError(ctx, http.StatusBadRequest, "invalid_request", "Client notification token is required when client is in push or ping mode", nil)
 		return
 	}
-	var opts []webauthn.LoginOption
-	userVerification := "preferred"
-	if slices.Contains(acrValues, discovery.ACRUserPresence) {
-		userVerification = "discouraged"
-	}
-	if slices.Contains(acrValues, discovery.ACRPreferUserVerification) {
-		userVerification = "preferred"
-	}
-	if slices.Contains(acrValues, discovery.ACRUserVerification) {
-		userVerification = "required"
+	if (app.CIBAMode == "ping" || app.CIBAMode == "push") && app.NotificationEndpoint == "" {
+		api.AbortError(ctx, http.StatusBadRequest, "invalid_client", "Client has no notification endpoint registered", nil)
+		return
 	}
-	opts = append(opts, webauthn.WithUserVerification(protocol.UserVerificationRequirement(userVerification)))
+	var opts []webauthn.LoginOption
+	opts = append(opts, webauthn.WithUserVerification(token.UserVerification(acrValues)))
 	var loginHint string
 	var err error
-	if loginHint, err = getUserHint(ctx); err != nil {
+	if loginHint, err = getUserHint(ctx, app); err != nil {
 		return
 	} else if loginHint != "" {
 		keys, err := userdb.GetUserKeyDescriptors(ctx, loginHint)
@@ -248,6 +231,15 @@ func createCIBAChallenge(ctx *gin.Context) {
 		opts = append(opts, webauthn.WithAllowedCredentials(keys))
 	}
 
+	// The binding message and authorization details are signed by the user, like in the BID flow.
+	var nonce string
+	challengeID := db.GenerateID(8)
+	if bindingMessage != "" || len(authorizationDetails) > 0 {
+		nonce = db.GenerateID(8)
+		opts = append(opts, webauthn.WithChallenge(
+			authn.ChallengeHash(loginHint, app.ID, challengeID, nonce, bindingMessage, authorizationDetails)))
+	}
+
 	cfg := authn.CreateWebauthnConfig()
 	var login *protocol.CredentialAssertion
 	var sessionData *webauthn.SessionData
@@ -281,11 +273,11 @@ func createCIBAChallenge(ctx *gin.Context) {
 		Expire:        time.Now().Add(time.Duration(timeout).Abs() * time.Second),
 		PublicData:    login,
 		PrivateData:   sessionData,
-		Nonce:         "",
+		Nonce:         nonce,
 		SignatureText: bindingMessage,
-		SignatureData: nil,
+		SignatureData: authorizationDetails,
 		RedirectURL:   "",
-	}, "")
+	}, challengeID)
 	if err != nil {
 		api.AbortError(ctx, http.StatusInternalServerError, "internal_error", "Unexpected error", err)
 		return
@@ -1,27 +1,79 @@
 package oidc
 
 import (
-	"fmt"
+	"database/sql"
+	"encoding/json"
+	"errors"
 	"log/slog"
 	"net/http"
 	"uyulala/internal/api"
 	"uyulala/internal/api/application"
+	"uyulala/internal/api/token"
+	"uyulala/internal/db/appdb"
+	"uyulala/internal/db/sessiondb"
 
 	"github.com/gin-gonic/gin"
-	"github.com/spf13/viper"
 )
 
 func userinfo(c *gin.Context) {
-	token := application.GetCurrentJWT(c)
-	if token == nil {
+	accessToken := application.GetCurrentJWT(c)
+	if accessToken == nil {
 		slog.Info("no token provided")
 		api.AbortError(c, http.StatusUnauthorized, "no_jwt", "No JWT provided", nil)
 		return
 	}
-	subj := token.Subject()
-	api.JSONResponse(c, gin.H{
-		"sub":   subj,
-		"name":  subj[:8],
-		"email": fmt.Sprintf("%s@%s", subj[:10], viper.GetString("userInfo.emailSuffix")),
-	})
+	// Access tokens restricted to resources have the client in client_id instead of the audience.
+	clientID, _ := accessToken.Get("client_id")
+	appID, _ := clientID.(string)
+	if aud := accessToken.Audience(); appID == "" && len(aud) > 0 {
+		appID = aud[0]
+	}
+	app, err := appdb.GetApplication(c, appID)
+	if err != nil {
+		api.AbortError(c, http.StatusUnauthorized, "invalid_token", "Unknown application", err)
+		return
+	}
+	subj := accessToken.Subject()
+	userID, err := token.ResolveSubject(c, app, subj)
+	if err != nil {
+		api.AbortError(c, http.StatusUnauthorized, "invalid_token", "Unknown subject", err)
+		return
+	}
+
+	// The claims request of the authorization is kept with the session of the access token.
+	claimsRequest := &api.ClaimsRequest{}
+	if sid, ok := accessToken.Get("sid"); ok {
+		sess, err := sessiondb.Get(c, sid.(string))
+		if errors.Is(err, sql.ErrNoRows) {
+			api.AbortError(c, http.StatusUnauthorized, "invalid_token", "The session of the token has ended", err)
+			return
+		} else if err != nil {
+			api.AbortError(c, http.StatusInternalServerError, "internal_error", "Unexpected error", err)
+			return
+		}
+		if claimsRequest, err = api.ParseClaimsRequest(sess.Claims); err != nil {
+			api.AbortError(c, http.StatusInternalServerError, "internal_error", "Unexpected error", err)
+			return
+		}
+	}
+	scope, _ := accessToken.Get("scope")
+	scopeValue, _ := scope.(string)
+	claims, err := token.UserClaims(c, app, userID, api.SpaceDelimited(scopeValue), claimsRequest.UserInfo)
+	if err != nil {
+		return
+	}
+	claims["sub"] = subj
+
+	// Clients that registered userinfo_encrypted_response_alg get the claims as JWE.
+	if app.UserInfoEncryptionAlg != "" {
+		data, _ := json.Marshal(claims)
+		response, err := token.Encrypt(c, app, data, app.UserInfoEncryptionAlg, app.UserInfoEncryptionEnc, "")
+		if err != nil {
+			api.AbortError(c, http.StatusInternalServerError, "encryption_error", "Couldn't encrypt the userinfo response to the client keys", err)
+			return
+		}
+		c.Data(http.StatusOK, "application/jwt", []byte(response))
+		return
+	}
+	api.JSONResponse(c, claims)
 }
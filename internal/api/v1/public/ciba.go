@@ -0,0 +1,84 @@
+package public
+
+import (
+	"database/sql"
+	"errors"
+	"log/slog"
+	"net/http"
+	"uyulala/internal/api"
+	"uyulala/internal/api/token"
+	"uyulala/internal/db/appdb"
+	"uyulala/internal/db/challengedb"
+	"uyulala/internal/notify"
+
+	"github.com/gin-gonic/gin"
+)
+
+type cibaPingNotification struct {
+	RequestID string `json:"auth_req_id"`
+}
+
+type cibaPushNotification struct {
+	RequestID string `json:"auth_req_id"`
+	*token.Response
+}
+
+type cibaPushErrorNotification struct {
+	RequestID        string `json:"auth_req_id"`
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description"`
+}
+
+// notifyCIBAClient delivers the result of a signed or rejected CIBA challenge to the client notification endpoint
+// when the client is registered in ping or push mode.
+func notifyCIBAClient(ctx *gin.Context, challengeID string) bool {
+	requestID, err := challengedb.GetCIBARequestID(ctx, challengeID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return true
+	} else if err != nil {
+		api.AbortError(ctx, http.StatusInternalServerError, "internal_error", "Unexpected error", err)
+		return false
+	}
+	challenge, err := challengedb.GetChallenge(ctx, challengeID)
+	if err != nil {
+		api.AbortError(ctx, http.StatusInternalServerError, "internal_error", "Unexpected error", err)
+		return false
+	}
+	app, err := appdb.GetApplication(ctx, challenge.AppID)
+	if err != nil {
+		api.AbortError(ctx, http.StatusInternalServerError, "internal_error", "Unexpected error", err)
+		return false
+	}
+	var body any
+	switch app.CIBAMode {
+	case "ping":
+		body = &cibaPingNotification{RequestID: requestID}
+	case "push":
+		if challenge.Status == challengedb.StatusRejected {
+			body = &cibaPushErrorNotification{
+				RequestID:        requestID,
+				Error:            "access_denied",
+				ErrorDescription: "User rejected the request",
+			}
+		} else {
+			res, err := token.IssuePush(ctx, app, challenge, requestID)
+			if err != nil {
+				return false
+			}
+			body = &cibaPushNotification{RequestID: requestID, Response: res}
+		}
+		// The result has been delivered, the client can't poll for it.
+		if err := challengedb.DeleteCIBARequest(ctx, requestID); err != nil {
+			slog.Warn("Failed to delete CIBA request", "requestID", requestID, "err", err)
+		}
+	default:
+		return true
+	}
+	n, err := notify.JSON(app.NotificationEndpoint, challenge.GetOAuth2Context().Get("client_notification_token"), body)
+	if err != nil {
+		api.AbortError(ctx, http.StatusInternalServerError, "internal_error", "Unexpected error", err)
+		return false
+	}
+	notify.Queue(ctx, n)
+	return true
+}
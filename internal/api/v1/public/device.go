@@ -0,0 +1,37 @@
+package public
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"uyulala/internal/api"
+	"uyulala/internal/db/challengedb"
+
+	"github.com/gin-gonic/gin"
+)
+
+// deviceUserCodeHandler resolves the user code of a device authorization to the authenticator page of its challenge.
+func deviceUserCodeHandler(ctx *gin.Context) {
+	userCode := ctx.PostForm("user_code")
+	if userCode == "" {
+		api.AbortError(ctx, http.StatusBadRequest, "invalid_request", "Missing user_code", nil)
+		return
+	}
+	challenge, err := challengedb.GetChallengeByUserCode(ctx, userCode)
+	if errors.Is(err, sql.ErrNoRows) {
+		api.AbortError(ctx, http.StatusNotFound, "not_found", "Unknown user code", nil)
+		return
+	} else if err != nil {
+		api.AbortError(ctx, http.StatusInternalServerError, "internal_error", "Unexpected error", err)
+		return
+	}
+	if !challenge.Validate(ctx) {
+		return
+	}
+	redirect, err := api.AuthenticatorURL(challenge.ID, challenge.Secret)
+	if err != nil {
+		api.AbortError(ctx, http.StatusInternalServerError, "internal_error", "Unexpected error", err)
+		return
+	}
+	api.RedirectResponse(ctx, redirect)
+}
@@ -1,6 +1,7 @@
 package public
 
 import (
+	"encoding/json"
 	"net/http"
 	"uyulala/internal/api"
 	"uyulala/internal/db/appdb"
@@ -8,6 +9,7 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/go-webauthn/webauthn/protocol"
+	"github.com/go-webauthn/webauthn/webauthn"
 	"github.com/golang-jwt/jwt/v5"
 )
 
@@ -72,13 +74,37 @@ func getChallengeHandlerPost(ctx *gin.Context) {
 	if app != nil {
 		res["app"] = app
 	}
+	if request := data.GetOAuth2Context(); app != nil && len(request) > 0 {
+		// The user is only known before signing when the request was hinted.
+		session := &webauthn.SessionData{}
+		if err := data.Expand(nil, session); err != nil {
+			api.AbortError(ctx, http.StatusInternalServerError, "internal_error", "Unexpected error", err)
+			return
+		}
+		scopes, err := api.ConsentScopes(ctx, app, request, string(session.UserID))
+		if err != nil {
+			api.AbortError(ctx, http.StatusInternalServerError, "internal_error", "Unexpected error", err)
+			return
+		}
+		if len(scopes) > 0 {
+			consent := make([]gin.H, 0, len(scopes))
+			for _, scope := range scopes {
+				consent = append(consent, gin.H{"name": scope.Name, "description": scope.Description})
+			}
+			res["consent"] = consent
+		}
+	}
 
-	if data.SignatureText != "" {
-		res["signData"] = gin.H{
+	if data.SignatureText != "" || len(data.SignatureData) > 0 {
+		signData := gin.H{
 			"nonce": data.Nonce,
 			"text":  data.SignatureText,
 			"data":  data.SignatureData,
 		}
+		if details := data.GetOAuth2Context().Get("authorization_details"); details != "" {
+			signData["authorizationDetails"] = json.RawMessage(details)
+		}
+		res["signData"] = signData
 	}
 	ctx.JSON(200, res)
 }
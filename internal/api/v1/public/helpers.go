@@ -5,7 +5,6 @@ import (
 	"errors"
 	"log/slog"
 	"net/http"
-	"net/url"
 	"time"
 	"uyulala/internal/api"
 	"uyulala/internal/db/challengedb"
@@ -15,21 +14,6 @@ import (
 	"github.com/spf13/viper"
 )
 
-func parseRedirectURI(vars url.Values) (*url.URL, error) {
-	uri := vars.Get("redirect_uri")
-	if uri == "" {
-		return nil, errors.New("missing redirect_uri")
-	}
-	parsed, err := url.Parse(uri)
-	if err != nil {
-		return nil, err
-	}
-	if parsed.Fragment != "" {
-		return nil, errors.New("invalid redirect_uri (Must not contain fragment)")
-	}
-	return parsed, nil
-}
-
 func getVerifiedChallenge(ctx *gin.Context, timeSensitive bool) (*challengedb.Data, bool) {
 	tokenString, ok := ctx.GetPostForm("token")
 	if !ok {
@@ -0,0 +1,107 @@
+package public
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"net/url"
+	"uyulala/internal/api"
+	"uyulala/internal/api/token"
+	"uyulala/internal/db/appdb"
+	"uyulala/internal/db/sessiondb"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lestrrat-go/jwx/jwt"
+)
+
+// endSessionHandler implements OpenID Connect RP-Initiated Logout 1.0.
+func endSessionHandler(ctx *gin.Context) {
+	if err := ctx.Request.ParseForm(); err != nil {
+		api.AbortError(ctx, http.StatusBadRequest, "invalid_request", "Invalid request", err)
+		return
+	}
+	form := ctx.Request.Form
+	clientID := form.Get("client_id")
+	postLogoutRedirectURI := form.Get("post_logout_redirect_uri")
+
+	var hint jwt.Token
+	if idTokenHint := form.Get("id_token_hint"); idTokenHint != "" {
+		var err error
+		hint, err = token.VerifyIDTokenHint(ctx, idTokenHint, clientID)
+		if errors.Is(err, token.ErrWrongAudience) {
+			api.AbortError(ctx, http.StatusBadRequest, "invalid_request", "client_id does not match id_token_hint", err)
+			return
+		} else if err != nil {
+			api.AbortError(ctx, http.StatusBadRequest, "invalid_request", "Invalid id_token_hint", err)
+			return
+		}
+		if clientID == "" {
+			clientID = hint.Audience()[0]
+		}
+	}
+
+	var app *appdb.Application
+	if clientID != "" {
+		var err error
+		app, err = appdb.GetApplication(ctx, clientID)
+		if err != nil {
+			api.AbortError(ctx, http.StatusBadRequest, "invalid_client", "Invalid client_id", err)
+			return
+		}
+	}
+
+	if postLogoutRedirectURI != "" {
+		if app == nil {
+			api.AbortError(ctx, http.StatusBadRequest, "invalid_request", "post_logout_redirect_uri requires id_token_hint or client_id", nil)
+			return
+		}
+		if !api.AllowedPostLogoutRedirect(app, postLogoutRedirectURI) {
+			api.AbortError(ctx, http.StatusBadRequest, "invalid_request", "Post logout redirect not allowed", nil)
+			return
+		}
+	}
+
+	if hint != nil {
+		if sid, ok := hint.Get("sid"); ok {
+			sess, err := sessiondb.Get(ctx, sid.(string))
+			if err != nil && !errors.Is(err, sql.ErrNoRows) {
+				api.AbortError(ctx, http.StatusInternalServerError, "internal_error", "Unexpected error", err)
+				return
+			}
+			userID, err := token.ResolveSubject(ctx, app, hint.Subject())
+			if err != nil && !errors.Is(err, sql.ErrNoRows) {
+				api.AbortError(ctx, http.StatusInternalServerError, "internal_error", "Unexpected error", err)
+				return
+			}
+			if sess != nil && sess.AppID == app.ID && sess.UserID == userID {
+				if err := sessiondb.Delete(ctx, sess.ID); err != nil {
+					api.AbortError(ctx, http.StatusInternalServerError, "internal_error", "Unexpected error", err)
+					return
+				}
+				if err := token.BackChannelLogout(ctx, sess); err != nil {
+					api.AbortError(ctx, http.StatusInternalServerError, "internal_error", "Unexpected error", err)
+					return
+				}
+			}
+		}
+	}
+
+	if postLogoutRedirectURI == "" {
+		ctx.JSON(http.StatusOK, gin.H{
+			"status": "logged_out",
+			"msg":    "Session ended",
+		})
+		return
+	}
+	redirect, err := url.Parse(postLogoutRedirectURI)
+	if err != nil {
+		api.AbortError(ctx, http.StatusBadRequest, "invalid_request", "Invalid post_logout_redirect_uri", err)
+		return
+	}
+	if state := form.Get("state"); state != "" {
+		q := redirect.Query()
+		q.Set("state", state)
+		redirect.RawQuery = q.Encode()
+	}
+	ctx.Redirect(http.StatusFound, redirect.String())
+}
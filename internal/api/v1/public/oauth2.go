@@ -1,21 +1,21 @@
 package public
 
 import (
-	"encoding/base64"
+	"database/sql"
 	"errors"
-	"log/slog"
 	"net/http"
 	"net/url"
 	"slices"
 	"strings"
 	"time"
-	"unicode/utf8"
 	"uyulala/internal/api"
+	"uyulala/internal/api/token"
 	"uyulala/internal/authn"
+	"uyulala/internal/db"
 	"uyulala/internal/db/appdb"
 	"uyulala/internal/db/challengedb"
+	"uyulala/internal/db/requestdb"
 	"uyulala/internal/db/userdb"
-	"uyulala/openid/discovery"
 
 	"github.com/gin-gonic/gin"
 	"github.com/go-webauthn/webauthn/protocol"
@@ -28,78 +28,76 @@ func createOAuth2ChallengeHandler(ctx *gin.Context) {
 		return
 	}
 	form := ctx.Request.PostForm
-	responseTypes := strings.FieldsFunc(form.Get("response_type"), func(r rune) bool { return r == ' ' || r == ',' || r == ';' || r == '\t' })
-	if !(slices.Contains(responseTypes, "code")) {
-		slog.Info("Unknown response type", "response_type", form.Get("response_type"))
-		api.AbortError(ctx, http.StatusBadRequest, "bad_response_type", "Unknown response type (only \"code\" supported)", nil)
-		return
-	}
 	clientID := form.Get("client_id")
 	if clientID == "" {
 		api.AbortError(ctx, http.StatusBadRequest, "invalid_request", "Missing client_id", nil)
 		return
 	}
-	redirectURI, err := parseRedirectURI(form)
-	if err != nil {
-		if errors.Is(err, &url.Error{}) {
-			api.AbortError(ctx, http.StatusBadRequest, "invalid_request", "Invalid redirect_uri", err)
-		} else {
-			api.AbortError(ctx, http.StatusBadRequest, "invalid_request", err.Error(), nil)
-		}
-		return
-	}
 	client, err := appdb.GetApplication(ctx, clientID)
 	if err != nil {
 		api.AbortError(ctx, http.StatusBadRequest, "invalid_client", "Invalid client_id", err)
 		return
 	}
-
-	if !api.AllowedRedirect(client, redirectURI.String()) {
-		api.AbortError(ctx, http.StatusBadRequest, "invalid_request", "Redirect not allowed", nil)
+	var ok bool
+	if requestURI := form.Get("request_uri"); requestURI != "" {
+		if form, ok = pushedAuthorizationRequest(ctx, client, requestURI); !ok {
+			return
+		}
+	} else if client.RequirePAR {
+		api.AbortError(ctx, http.StatusBadRequest, "invalid_request", "Pushed authorization request required", nil)
 		return
 	}
-
-	pkceMethod := form.Get("code_challenge_method")
-	if pkceMethod != "" && pkceMethod != "S256" && pkceMethod != "plain" {
-		api.AbortError(ctx, http.StatusBadRequest, "invalid_request", "Invalid code_challenge_method; \"S256\" or \"plain\" is supported", nil)
+	if form, ok = token.ResolveRequestObject(ctx, client, form); !ok {
 		return
 	}
-
-	if pkceMethod != "" && form.Get("code_challenge") == "" {
-		api.AbortError(ctx, http.StatusBadRequest, "invalid_request", "code_challenge_method given, but no code_challenge", nil)
+	req, ok := api.ValidateAuthorizationRequest(ctx, client, form)
+	if !ok {
 		return
 	}
-	if pkceMethod == "" && form.Get("code_challenge") != "" {
-		api.AbortError(ctx, http.StatusBadRequest, "invalid_request", "code_challenge given, but no code_challenge_method", nil)
+	if !token.ValidateResources(ctx, form) {
 		return
 	}
 
-	if form.Get("state") == "" {
-		api.AbortError(ctx, http.StatusBadRequest, "invalid_request", "Missing state", nil)
+	prompts := strings.Fields(form.Get("prompt"))
+	if slices.Contains(prompts, "none") {
+		// Every authorization is signed by the user with an authenticator, there is no session to authorize with.
+		token.AuthorizationResponse(ctx, client, req.RedirectURI.String(), form, url.Values{
+			"error":             {"login_required"},
+			"error_description": {"User interaction is required"},
+		})
 		return
 	}
+
 	var opts []webauthn.LoginOption
-	acrValues := strings.FieldsFunc(form.Get("acr_values"), func(r rune) bool {
-		switch r {
-		case ' ', '\n', '\r', '\t':
-			return true
-		}
-		return false
-	})
-	userVerification := "preferred"
-	if slices.Contains(acrValues, discovery.ACRUserPresence) {
-		userVerification = "discouraged"
-	}
-	if slices.Contains(acrValues, discovery.ACRPreferUserVerification) {
-		userVerification = "preferred"
+	acrValues := api.SpaceDelimited(form.Get("acr_values"))
+	userVerification := token.UserVerification(acrValues)
+	// Only user verification updates the authentication time of the user.
+	if slices.Contains(prompts, "login") || form.Has("max_age") {
+		userVerification = protocol.VerificationRequired
 	}
-	if slices.Contains(acrValues, discovery.ACRUserVerification) {
-		userVerification = "required"
-	}
-	opts = append(opts, webauthn.WithUserVerification(protocol.UserVerificationRequirement(userVerification)))
+	opts = append(opts, webauthn.WithUserVerification(userVerification))
 
 	userID := form.Get("login_hint")
+	if idTokenHint := form.Get("id_token_hint"); idTokenHint != "" {
+		hint, err := token.VerifyIDTokenHint(ctx, idTokenHint, client.ID)
+		if err != nil {
+			api.AbortError(ctx, http.StatusBadRequest, "invalid_request", "Invalid id_token_hint", err)
+			return
+		}
+		if userID != "" && userID != hint.Subject() {
+			api.AbortError(ctx, http.StatusBadRequest, "invalid_request", "login_hint does not match id_token_hint", nil)
+			return
+		}
+		userID = hint.Subject()
+	}
 	if userID != "" {
+		if userID, err = token.ResolveSubject(ctx, client, userID); errors.Is(err, sql.ErrNoRows) {
+			api.AbortError(ctx, http.StatusBadRequest, "unknown_user_id", "Couldn't find the hinted user", err)
+			return
+		} else if err != nil {
+			api.AbortError(ctx, http.StatusInternalServerError, "internal_error", "Unexpected error", err)
+			return
+		}
 		keys, err := userdb.GetUserKeyDescriptors(ctx, userID)
 		if err != nil {
 			api.AbortError(ctx, http.StatusInternalServerError, "internal_error", "Unexpected error", err)
@@ -112,19 +110,13 @@ func createOAuth2ChallengeHandler(ctx *gin.Context) {
 		opts = append(opts, webauthn.WithAllowedCredentials(keys))
 	}
 
-	bindingMessage := form.Get("binding_message")
-	if bindingMessage != "" && !utf8.ValidString(bindingMessage) {
-		api.AbortError(ctx, http.StatusBadRequest, "invalid_request", "Invalid binding_message, must be utf8", nil)
-		return
-	}
-	signatureDataText := form.Get("data")
-	var signatureData []byte
-	if signatureDataText != "" {
-		signatureData, err = base64.StdEncoding.DecodeString(signatureDataText)
-		if err != nil {
-			api.AbortError(ctx, http.StatusBadRequest, "invalid_request", "Bad signature data encoding", err)
-			return
-		}
+	// The binding message and the signature data (or authorization details) are signed by the user, like in the BID flow.
+	var nonce string
+	challengeID := db.GenerateID(8)
+	if req.BindingMessage != "" || len(req.SignatureData) > 0 {
+		nonce = db.GenerateID(8)
+		opts = append(opts, webauthn.WithChallenge(
+			authn.ChallengeHash(userID, client.ID, challengeID, nonce, req.BindingMessage, req.SignatureData)))
 	}
 
 	cfg := authn.CreateWebauthnConfig()
@@ -147,11 +139,11 @@ func createOAuth2ChallengeHandler(ctx *gin.Context) {
 		Expire:        time.Now().Add(time.Minute * 5),
 		PublicData:    login,
 		PrivateData:   session,
-		Nonce:         "",
-		SignatureText: bindingMessage,
-		SignatureData: signatureData,
-		RedirectURL:   redirectURI.String(),
-	}, "")
+		Nonce:         nonce,
+		SignatureText: req.BindingMessage,
+		SignatureData: req.SignatureData,
+		RedirectURL:   req.RedirectURI.String(),
+	}, challengeID)
 	if err != nil {
 		api.AbortError(ctx, http.StatusInternalServerError, "internal_error", "Unexpected error", err)
 		return
@@ -164,3 +156,33 @@ func createOAuth2ChallengeHandler(ctx *gin.Context) {
 
 	api.ChallengeResponse(ctx, challenge, secret)
 }
+
+// pushedAuthorizationRequest replaces the front channel parameters with the ones pushed by the client (RFC 9126).
+func pushedAuthorizationRequest(ctx *gin.Context, client *appdb.Application, requestURI string) (url.Values, bool) {
+	pushed, err := requestdb.Get(ctx, requestURI)
+	if errors.Is(err, sql.ErrNoRows) {
+		api.AbortError(ctx, http.StatusBadRequest, "invalid_request_uri", "Unknown request_uri", nil)
+		return nil, false
+	} else if err != nil {
+		api.AbortError(ctx, http.StatusInternalServerError, "internal_error", "Unexpected error", err)
+		return nil, false
+	}
+	if pushed.AppID != client.ID {
+		api.AbortError(ctx, http.StatusBadRequest, "invalid_request_uri", "request_uri was not pushed by this client", nil)
+		return nil, false
+	}
+	if err := requestdb.Delete(ctx, pushed.ID); err != nil {
+		api.AbortError(ctx, http.StatusInternalServerError, "internal_error", "Unexpected error", err)
+		return nil, false
+	}
+	if pushed.Expired() {
+		api.AbortError(ctx, http.StatusBadRequest, "invalid_request_uri", "request_uri has expired", nil)
+		return nil, false
+	}
+	form, err := pushed.Form()
+	if err != nil {
+		api.AbortError(ctx, http.StatusInternalServerError, "internal_error", "Unexpected error", err)
+		return nil, false
+	}
+	return form, true
+}
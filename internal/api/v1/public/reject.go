@@ -5,6 +5,8 @@ import (
 	"net/http"
 	"net/url"
 	api2 "uyulala/internal/api"
+	"uyulala/internal/api/token"
+	"uyulala/internal/db/appdb"
 	"uyulala/internal/db/challengedb"
 
 	"github.com/gin-gonic/gin"
@@ -20,19 +22,28 @@ func rejectChallengeHandler(context *gin.Context) {
 		api2.AbortError(context, http.StatusInternalServerError, "invalid_challenge", "Unexpected error", err)
 		return
 	}
+	if !notifyCIBAClient(context, challenge.ID) {
+		return
+	}
+	if oauthContext := challenge.GetOAuth2Context(); challenge.RedirectURL != "" && len(oauthContext) > 0 {
+		app, err := appdb.GetApplication(context, challenge.AppID)
+		if err != nil {
+			api2.AbortError(context, http.StatusInternalServerError, "internal_error", "Unexpected error", err)
+			return
+		}
+		token.AuthorizationResponse(context, app, challenge.RedirectURL, oauthContext, url.Values{
+			"code":              {challenge.ID},
+			"error":             {"rejected"},
+			"error_description": {"User rejected the request"},
+		})
+		return
+	}
 	redirectURL := ""
 	if challenge.RedirectURL != "" {
 		redirectURL = challenge.RedirectURL
 		if r, err := url.Parse(challenge.RedirectURL); err == nil {
 			q := r.Query()
-			if oauthContext, err := url.ParseQuery(challenge.OAuth2Context); err == nil && len(oauthContext) > 0 {
-				q.Set("code", challenge.ID)
-				if oauthContext.Get("state") != "" {
-					q.Set("state", oauthContext.Get("state"))
-				}
-			} else {
-				q.Set("challengeId", challenge.ID)
-			}
+			q.Set("challengeId", challenge.ID)
 			q.Set("error", "rejected")
 			q.Set("error_description", "User rejected the request")
 			r.RawQuery = q.Encode()
@@ -10,4 +10,8 @@ func AddRoutes(g *gin.RouterGroup) {
 	g.DELETE("/challenge", rejectChallengeHandler)
 
 	g.POST("/oauth2", createOAuth2ChallengeHandler)
+	g.POST("/device", deviceUserCodeHandler)
+
+	g.GET("/logout", endSessionHandler)
+	g.POST("/logout", endSessionHandler)
 }
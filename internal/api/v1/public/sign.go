@@ -5,10 +5,16 @@ import (
 	"log/slog"
 	"net/http"
 	"net/url"
+	"slices"
+	"strings"
+	"time"
 	"uyulala/internal/api"
+	"uyulala/internal/api/token"
 	"uyulala/internal/authn"
+	"uyulala/internal/db/appdb"
 	"uyulala/internal/db/challengedb"
 	"uyulala/internal/db/userdb"
+	"uyulala/openid/discovery"
 
 	"github.com/gin-gonic/gin"
 	"github.com/go-webauthn/webauthn/protocol"
@@ -90,6 +96,14 @@ func signLogin(context *gin.Context, challenge *challengedb.Data) {
 	if context.IsAborted() {
 		return
 	}
+	if usr, err := userdb.GetUser(context, string(user.userHandle)); err != nil {
+		slog.Error("signLogin GetUser", "error", err)
+		api.AbortError(context, http.StatusBadRequest, "invalid_response", "Invalid response", err)
+		return
+	} else if !usr.Active {
+		api.AbortError(context, http.StatusForbidden, "user_inactive", "The user has been deactivated", nil)
+		return
+	}
 
 	if err := userdb.PingUserKey(context, cred); err != nil {
 		slog.Error("signLogin PingUserKey", "error", err)
@@ -111,32 +125,98 @@ func signLogin(context *gin.Context, challenge *challengedb.Data) {
 		}
 	}
 
+	if oauthContext := challenge.GetOAuth2Context(); len(oauthContext) > 0 {
+		if !grantConsent(context, challenge, oauthContext, string(user.userHandle)) {
+			return
+		}
+	}
+
+	if !notifyCIBAClient(context, challenge.ID) {
+		return
+	}
+
+	if oauthContext := challenge.GetOAuth2Context(); challenge.RedirectURL != "" && len(oauthContext) > 0 {
+		assertion := &token.Assertion{Signed: time.Now(), Signature: parsed, Credential: cred}
+		signOAuth2Response(context, challenge, oauthContext, string(user.userHandle), assertion)
+		return
+	}
+
 	redirectURL := ""
 	if challenge.RedirectURL != "" {
 		redirectURL = challenge.RedirectURL
 		if r, err := url.Parse(challenge.RedirectURL); err == nil {
 			q := r.Query()
-			if oauthContext, err := url.ParseQuery(challenge.OAuth2Context); err == nil && len(oauthContext) > 0 {
-				// TODO: check CIBA ping / push modes
-				code, err := challengedb.CreateCode(context, challenge.ID)
-				if err != nil {
-					slog.Error("signLogin CreateCode", "error", err)
-					api.AbortError(context, http.StatusInternalServerError, "internal_error", "Unexpected error", err)
-					return
-				}
-				q.Set("code", code)
-				if oauthContext.Get("state") != "" {
-					q.Set("state", oauthContext.Get("state"))
-				}
-			} else {
-				q.Set("challengeId", challenge.ID)
-			}
+			q.Set("challengeId", challenge.ID)
 			r.RawQuery = q.Encode()
 			redirectURL = r.String()
 		}
 	}
 	api.RedirectResponse(context, redirectURL)
 }
+
+// signOAuth2Response redirects to the client with the authorization response of a signed challenge: the code, and
+// for the implicit and hybrid flows the tokens.
+func signOAuth2Response(context *gin.Context, challenge *challengedb.Data, request url.Values, userID string,
+	assertion *token.Assertion) {
+	app, err := appdb.GetApplication(context, challenge.AppID)
+	if err != nil {
+		api.AbortError(context, http.StatusInternalServerError, "internal_error", "Unexpected error", err)
+		return
+	}
+	responseType := api.ResponseType(request.Get("response_type"))
+	params := url.Values{}
+	if slices.Contains(strings.Fields(responseType), discovery.ResponseTypeCode) {
+		code, err := challengedb.CreateCode(context, challenge.ID)
+		if err != nil {
+			slog.Error("signLogin CreateCode", "error", err)
+			api.AbortError(context, http.StatusInternalServerError, "internal_error", "Unexpected error", err)
+			return
+		}
+		params.Set("code", code)
+	}
+	if responseType != discovery.ResponseTypeCode {
+		if err := token.SetAuthenticationContext(assertion, strings.Fields(request.Get("acr_values"))); err != nil {
+			token.AuthorizationResponse(context, app, challenge.RedirectURL, request, url.Values{
+				"error":             {"unmet_authentication_requirements"},
+				"error_description": {"The requested acr_values could not be satisfied"},
+			})
+			return
+		}
+		tokens, err := token.FrontChannelTokens(context, app, userID, assertion, request, params.Get("code"))
+		if err != nil {
+			return
+		}
+		for k, v := range tokens {
+			params[k] = v
+		}
+	}
+	token.AuthorizationResponse(context, app, challenge.RedirectURL, request, params)
+}
+
+// grantConsent records the consent of the user to the requested scopes of a signed request, the challenge view
+// lists them to third party applications before the user signs.
+func grantConsent(context *gin.Context, challenge *challengedb.Data, request url.Values, userID string) bool {
+	app, err := appdb.GetApplication(context, challenge.AppID)
+	if err != nil {
+		api.AbortError(context, http.StatusInternalServerError, "internal_error", "Unexpected error", err)
+		return false
+	}
+	if app.FirstParty {
+		return true
+	}
+	scopes, err := api.RequestedScopes(context, app, request)
+	if err != nil {
+		api.AbortError(context, http.StatusInternalServerError, "internal_error", "Unexpected error", err)
+		return false
+	}
+	if err := userdb.GrantConsent(context, userID, app.ID, api.ScopeNames(scopes)); err != nil {
+		slog.Error("signLogin GrantConsent", "error", err)
+		api.AbortError(context, http.StatusInternalServerError, "internal_error", "Unexpected error", err)
+		return false
+	}
+	return true
+}
+
 func signCreate(context *gin.Context, challenge *challengedb.Data) {
 	cfg := authn.CreateWebauthnConfig()
 	session := webauthn.SessionData{}
@@ -0,0 +1,337 @@
+package registration
+
+import (
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+	"uyulala/internal/api"
+	"uyulala/internal/api/token"
+	"uyulala/internal/db/appdb"
+	"uyulala/internal/db/keydb"
+	"uyulala/openid/discovery"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lestrrat-go/jwx/jwk"
+	"github.com/spf13/viper"
+)
+
+// ClientMetadata are the client metadata (RFC 7591 section 2) a client can register.
+type ClientMetadata struct {
+	RedirectURIs                          []string        `json:"redirect_uris"`
+	PostLogoutRedirectURIs                []string        `json:"post_logout_redirect_uris,omitempty"`
+	ClientName                            string          `json:"client_name,omitempty"`
+	LogoURI                               string          `json:"logo_uri,omitempty"`
+	Scope                                 string          `json:"scope,omitempty"`
+	ResponseTypes                         []string        `json:"response_types,omitempty"`
+	TokenEndpointAuthMethod               string          `json:"token_endpoint_auth_method,omitempty"`
+	JWKS                                  json.RawMessage `json:"jwks,omitempty"`
+	JWKSURI                               string          `json:"jwks_uri,omitempty"`
+	IDTokenSignedResponseAlg              string          `json:"id_token_signed_response_alg,omitempty"`
+	IDTokenEncryptedResponseAlg           string          `json:"id_token_encrypted_response_alg,omitempty"`
+	IDTokenEncryptedResponseEnc           string          `json:"id_token_encrypted_response_enc,omitempty"`
+	UserInfoEncryptedResponseAlg          string          `json:"userinfo_encrypted_response_alg,omitempty"`
+	UserInfoEncryptedResponseEnc          string          `json:"userinfo_encrypted_response_enc,omitempty"`
+	BackChannelTokenDeliveryMode          string          `json:"backchannel_token_delivery_mode,omitempty"`
+	BackChannelClientNotificationEndpoint string          `json:"backchannel_client_notification_endpoint,omitempty"`
+	BackChannelLogoutURI                  string          `json:"backchannel_logout_uri,omitempty"`
+	RequirePushedAuthorizationRequests    bool            `json:"require_pushed_authorization_requests,omitempty"`
+	TLSClientAuthSubjectDN                string          `json:"tls_client_auth_subject_dn,omitempty"`
+	SubjectType                           string          `json:"subject_type,omitempty"`
+	SectorIdentifierURI                   string          `json:"sector_identifier_uri,omitempty"`
+}
+
+type RegistrationResponse struct {
+	ClientID                string `json:"client_id"`
+	ClientSecret            string `json:"client_secret,omitempty"`
+	ClientIDIssuedAt        int64  `json:"client_id_issued_at"`
+	ClientSecretExpiresAt   int64  `json:"client_secret_expires_at"`
+	RegistrationAccessToken string `json:"registration_access_token,omitempty"`
+	RegistrationClientURI   string `json:"registration_client_uri"`
+	ClientMetadata
+}
+
+var registrationAuthMethods = []string{
+	discovery.TokenAuthClientSecretBasic,
+	discovery.TokenAuthClientSecretPost,
+	discovery.TokenAuthClientSecretJWT,
+	discovery.TokenAuthPrivateKeyJWT,
+	discovery.TokenAuthTLSClient,
+}
+
+func metadataError(ctx *gin.Context, err, desc string) bool {
+	api.OAuth2ErrorResponse(ctx, http.StatusBadRequest, err, desc)
+	return false
+}
+
+// scriptSchemes are never valid in client metadata, the frontend navigates to redirect uris.
+var scriptSchemes = []string{"javascript", "data", "vbscript"}
+
+func validURL(uri string, maxLength int) bool {
+	parsed, err := url.Parse(uri)
+	return err == nil && len(uri) <= maxLength && parsed.Scheme != "" && parsed.Host != "" && parsed.Fragment == "" &&
+		!slices.Contains(scriptSchemes, strings.ToLower(parsed.Scheme))
+}
+
+// validRedirectURI reports whether uri can be registered as a redirect uri: https, or http for loopback hosts only.
+func validRedirectURI(uri string) bool {
+	if !validURL(uri, 250) {
+		return false
+	}
+	parsed, _ := url.Parse(uri)
+	switch strings.ToLower(parsed.Scheme) {
+	case "https":
+		return true
+	case "http":
+		if parsed.Hostname() == "localhost" {
+			return true
+		}
+		ip := net.ParseIP(parsed.Hostname())
+		return ip != nil && ip.IsLoopback()
+	}
+	return false
+}
+
+// apply validates the metadata and sets them on app, defaults included. The response is aborted if they are invalid.
+func (m *ClientMetadata) apply(ctx *gin.Context, app *appdb.Application) bool {
+	for _, uri := range append(slices.Clone(m.RedirectURIs), m.PostLogoutRedirectURIs...) {
+		if !validRedirectURI(uri) {
+			return metadataError(ctx, "invalid_redirect_uri", "Invalid redirect uri "+uri)
+		}
+	}
+	if len(m.ClientName) > 100 {
+		return metadataError(ctx, "invalid_client_metadata", "client_name is too long")
+	}
+	if m.LogoURI != "" && !validURL(m.LogoURI, 1024) {
+		return metadataError(ctx, "invalid_client_metadata", "Invalid logo_uri")
+	}
+
+	if len(m.ResponseTypes) == 0 {
+		m.ResponseTypes = []string{discovery.ResponseTypeCode}
+	}
+	for i, responseType := range m.ResponseTypes {
+		m.ResponseTypes[i] = api.ResponseType(responseType)
+		if !slices.Contains(api.ResponseTypes, m.ResponseTypes[i]) {
+			return metadataError(ctx, "invalid_client_metadata", "Unsupported response type "+responseType)
+		}
+	}
+
+	if m.TokenEndpointAuthMethod == "" {
+		m.TokenEndpointAuthMethod = discovery.TokenAuthClientSecretBasic
+	}
+	if !slices.Contains(registrationAuthMethods, m.TokenEndpointAuthMethod) {
+		return metadataError(ctx, "invalid_client_metadata", "Unsupported token_endpoint_auth_method")
+	}
+	if len(m.JWKS) > 0 && m.JWKSURI != "" {
+		return metadataError(ctx, "invalid_client_metadata", "jwks and jwks_uri are mutually exclusive")
+	}
+	if len(m.JWKS) > 0 {
+		if _, err := jwk.Parse(m.JWKS); err != nil {
+			return metadataError(ctx, "invalid_client_metadata", "Invalid jwks")
+		}
+	}
+	if m.JWKSURI != "" && !validURL(m.JWKSURI, 2048) {
+		return metadataError(ctx, "invalid_client_metadata", "Invalid jwks_uri")
+	}
+	if m.TokenEndpointAuthMethod == discovery.TokenAuthPrivateKeyJWT && len(m.JWKS) == 0 && m.JWKSURI == "" {
+		return metadataError(ctx, "invalid_client_metadata", "private_key_jwt requires jwks or jwks_uri")
+	}
+	if m.TokenEndpointAuthMethod == discovery.TokenAuthTLSClient && m.TLSClientAuthSubjectDN == "" {
+		return metadataError(ctx, "invalid_client_metadata", "tls_client_auth requires tls_client_auth_subject_dn")
+	}
+
+	if m.IDTokenSignedResponseAlg == "" {
+		m.IDTokenSignedResponseAlg = "RS256"
+	}
+	key, err := keydb.GetFirstWithAlg(ctx, m.IDTokenSignedResponseAlg)
+	if err != nil {
+		return metadataError(ctx, "invalid_client_metadata", "Unsupported id_token_signed_response_alg")
+	}
+	if !token.ValidEncryption(m.IDTokenEncryptedResponseAlg, m.IDTokenEncryptedResponseEnc) {
+		return metadataError(ctx, "invalid_client_metadata", "Unsupported id_token_encrypted_response_alg or id_token_encrypted_response_enc")
+	}
+	if !token.ValidEncryption(m.UserInfoEncryptedResponseAlg, m.UserInfoEncryptedResponseEnc) {
+		return metadataError(ctx, "invalid_client_metadata", "Unsupported userinfo_encrypted_response_alg or userinfo_encrypted_response_enc")
+	}
+	if (m.IDTokenEncryptedResponseAlg != "" || m.UserInfoEncryptedResponseAlg != "") && len(m.JWKS) == 0 && m.JWKSURI == "" {
+		return metadataError(ctx, "invalid_client_metadata", "Encrypted responses require jwks or jwks_uri")
+	}
+	if m.IDTokenEncryptedResponseAlg != "" && m.IDTokenEncryptedResponseEnc == "" {
+		m.IDTokenEncryptedResponseEnc = token.DefaultEncryptionEncoding
+	}
+	if m.UserInfoEncryptedResponseAlg != "" && m.UserInfoEncryptedResponseEnc == "" {
+		m.UserInfoEncryptedResponseEnc = token.DefaultEncryptionEncoding
+	}
+
+	if m.BackChannelTokenDeliveryMode == "" {
+		m.BackChannelTokenDeliveryMode = "poll"
+	}
+	switch m.BackChannelTokenDeliveryMode {
+	case "poll":
+	case "ping", "push":
+		if !validURL(m.BackChannelClientNotificationEndpoint, 2048) {
+			return metadataError(ctx, "invalid_client_metadata", "backchannel_client_notification_endpoint is required for ping and push")
+		}
+	default:
+		return metadataError(ctx, "invalid_client_metadata", "Unsupported backchannel_token_delivery_mode")
+	}
+	if m.BackChannelLogoutURI != "" && !validURL(m.BackChannelLogoutURI, 2048) {
+		return metadataError(ctx, "invalid_client_metadata", "Invalid backchannel_logout_uri")
+	}
+
+	// Updates that omit the subject type or sector keep those registered, the pairwise subject identifiers the
+	// client knows its users by would change otherwise.
+	if m.SubjectType == "" {
+		m.SubjectType = app.SubjectType
+	}
+	if m.SubjectType == "" {
+		m.SubjectType = discovery.SubjectTypePublic
+	}
+	sector := ""
+	switch m.SubjectType {
+	case discovery.SubjectTypePublic:
+	case discovery.SubjectTypePairwise:
+		omitted := m.SectorIdentifierURI == ""
+		if omitted {
+			m.SectorIdentifierURI = app.SectorIdentifierURI
+		}
+		var ok bool
+		if sector, ok = m.sector(ctx); !ok {
+			return false
+		}
+		if omitted && app.SectorIdentifier != "" && sector != app.SectorIdentifier {
+			return metadataError(ctx, "invalid_client_metadata", "sector_identifier_uri is required to change the sector of the redirect uris")
+		}
+	default:
+		return metadataError(ctx, "invalid_client_metadata", "Unsupported subject_type")
+	}
+
+	app.Name = m.ClientName
+	app.Icon = m.LogoURI
+	app.RedirectURI = m.RedirectURIs
+	app.PostLogoutRedirectURI = m.PostLogoutRedirectURIs
+	// The scope is kept as registered, the scopes allowed for client credentials are up to an administrator.
+	app.RegistrationScope = strings.Join(api.SpaceDelimited(m.Scope), " ")
+	app.ResponseTypes = m.ResponseTypes
+	app.AuthMethod = m.TokenEndpointAuthMethod
+	app.JWKS = string(m.JWKS)
+	app.JWKSURI = m.JWKSURI
+	app.IDTokenAlg = key.Algorithm
+	app.KeyID = key.ID
+	app.IDTokenEncryptionAlg = m.IDTokenEncryptedResponseAlg
+	app.IDTokenEncryptionEnc = m.IDTokenEncryptedResponseEnc
+	app.UserInfoEncryptionAlg = m.UserInfoEncryptedResponseAlg
+	app.UserInfoEncryptionEnc = m.UserInfoEncryptedResponseEnc
+	app.CIBAMode = m.BackChannelTokenDeliveryMode
+	app.NotificationEndpoint = m.BackChannelClientNotificationEndpoint
+	app.BackChannelLogoutURI = m.BackChannelLogoutURI
+	app.RequirePAR = m.RequirePushedAuthorizationRequests
+	app.TLSClientSubjectDN = m.TLSClientAuthSubjectDN
+	app.SubjectType = m.SubjectType
+	app.SectorIdentifier = sector
+	app.SectorIdentifierURI = ""
+	if sector != "" {
+		app.SectorIdentifierURI = m.SectorIdentifierURI
+	}
+	return true
+}
+
+// maxSectorSize is the largest redirect uri array fetched from a sector_identifier_uri.
+const maxSectorSize = 64 << 10
+
+// sectorClient fetches sector_identifier_uri documents.
+var sectorClient = &http.Client{
+	Timeout: 5 * time.Second,
+}
+
+// sector returns the sector identifier of a pairwise client (OpenID Connect Registration 5). With a
+// sector_identifier_uri, the JSON array it references must contain all redirect uris. Without one, all redirect uris
+// must share the same host.
+func (m *ClientMetadata) sector(ctx *gin.Context) (string, bool) {
+	if m.SectorIdentifierURI == "" {
+		sector := ""
+		for _, uri := range m.RedirectURIs {
+			parsed, _ := url.Parse(uri)
+			if sector != "" && parsed.Host != sector {
+				return "", metadataError(ctx, "invalid_client_metadata", "sector_identifier_uri is required for redirect uris with different hosts")
+			}
+			sector = parsed.Host
+		}
+		if sector == "" {
+			return "", metadataError(ctx, "invalid_client_metadata", "pairwise requires redirect_uris or sector_identifier_uri")
+		}
+		return sector, true
+	}
+
+	parsed, err := url.Parse(m.SectorIdentifierURI)
+	if err != nil || parsed.Scheme != "https" || parsed.Host == "" || len(m.SectorIdentifierURI) > 2048 {
+		return "", metadataError(ctx, "invalid_client_metadata", "sector_identifier_uri must be an https url")
+	}
+	req, err := http.NewRequestWithContext(ctx.Request.Context(), http.MethodGet, m.SectorIdentifierURI, nil)
+	if err != nil {
+		return "", metadataError(ctx, "invalid_client_metadata", "Invalid sector_identifier_uri")
+	}
+	res, err := sectorClient.Do(req)
+	if err != nil {
+		return "", metadataError(ctx, "invalid_client_metadata", "Couldn't fetch sector_identifier_uri")
+	}
+	defer res.Body.Close()
+	var redirectURIs []string
+	if res.StatusCode != http.StatusOK || json.NewDecoder(io.LimitReader(res.Body, maxSectorSize)).Decode(&redirectURIs) != nil {
+		return "", metadataError(ctx, "invalid_client_metadata", "sector_identifier_uri must return a JSON array of redirect uris")
+	}
+	for _, uri := range m.RedirectURIs {
+		if !slices.Contains(redirectURIs, uri) {
+			return "", metadataError(ctx, "invalid_redirect_uri", "Redirect uri "+uri+" is not included in sector_identifier_uri")
+		}
+	}
+	return parsed.Host, true
+}
+
+func metadataFromApp(app *appdb.Application) ClientMetadata {
+	m := ClientMetadata{
+		RedirectURIs:                          app.RedirectURI,
+		PostLogoutRedirectURIs:                app.PostLogoutRedirectURI,
+		ClientName:                            app.Name,
+		LogoURI:                               app.Icon,
+		Scope:                                 app.RegistrationScope,
+		ResponseTypes:                         app.ResponseTypes,
+		TokenEndpointAuthMethod:               app.AuthMethod,
+		JWKSURI:                               app.JWKSURI,
+		IDTokenSignedResponseAlg:              app.IDTokenAlg,
+		IDTokenEncryptedResponseAlg:           app.IDTokenEncryptionAlg,
+		IDTokenEncryptedResponseEnc:           app.IDTokenEncryptionEnc,
+		UserInfoEncryptedResponseAlg:          app.UserInfoEncryptionAlg,
+		UserInfoEncryptedResponseEnc:          app.UserInfoEncryptionEnc,
+		BackChannelTokenDeliveryMode:          app.CIBAMode,
+		BackChannelClientNotificationEndpoint: app.NotificationEndpoint,
+		BackChannelLogoutURI:                  app.BackChannelLogoutURI,
+		RequirePushedAuthorizationRequests:    app.RequirePAR,
+		TLSClientAuthSubjectDN:                app.TLSClientSubjectDN,
+		SubjectType:                           app.SubjectType,
+		SectorIdentifierURI:                   app.SectorIdentifierURI,
+	}
+	if m.RedirectURIs == nil {
+		m.RedirectURIs = []string{}
+	}
+	if app.JWKS != "" {
+		m.JWKS = json.RawMessage(app.JWKS)
+	}
+	return m
+}
+
+func registrationResponse(app *appdb.Application, registrationToken string) *RegistrationResponse {
+	return &RegistrationResponse{
+		ClientID:                app.ID,
+		ClientSecret:            app.Secret,
+		ClientIDIssuedAt:        app.Created.Unix(),
+		ClientSecretExpiresAt:   0,
+		RegistrationAccessToken: registrationToken,
+		RegistrationClientURI:   viper.GetString("issuer") + "/api/v1/register/" + app.ID,
+		ClientMetadata:          metadataFromApp(app),
+	}
+}
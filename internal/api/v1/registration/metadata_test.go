@@ -0,0 +1,149 @@
+package registration
+
+import (
+	"database/sql/driver"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+	"uyulala/internal/db/appdb"
+	"uyulala/internal/db/dbtest"
+	"uyulala/openid/discovery"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestSector(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/slow":
+			time.Sleep(200 * time.Millisecond)
+		case "/large":
+			_, _ = w.Write([]byte(`["https://a.example/cb",` + strings.Repeat(" ", maxSectorSize) + `"https://b.example/cb"]`))
+			return
+		case "/missing":
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, _ = w.Write([]byte(`["https://a.example/cb", "https://b.example/cb"]`))
+	}))
+	defer server.Close()
+	client := server.Client()
+	client.Timeout = 100 * time.Millisecond
+	defer func(c *http.Client) { sectorClient = c }(sectorClient)
+	sectorClient = client
+	host := strings.TrimPrefix(server.URL, "https://")
+
+	tests := []struct {
+		name         string
+		redirectURIs []string
+		sectorURI    string
+		want         string
+		ok           bool
+	}{
+		{"redirect uri host", []string{"https://a.example/cb", "https://a.example/other"}, "", "a.example", true},
+		{"redirect uris of several hosts", []string{"https://a.example/cb", "https://b.example/cb"}, "", "", false},
+		{"no redirect uris", nil, "", "", false},
+		{"sector identifier uri", []string{"https://a.example/cb", "https://b.example/cb"}, server.URL + "/sector", host, true},
+		{"redirect uri not in the sector", []string{"https://c.example/cb"}, server.URL + "/sector", "", false},
+		{"not https", []string{"https://a.example/cb"}, "http://" + host + "/sector", "", false},
+		{"not found", []string{"https://a.example/cb"}, server.URL + "/missing", "", false},
+		{"too large", []string{"https://a.example/cb"}, server.URL + "/large", "", false},
+		{"too slow", []string{"https://a.example/cb"}, server.URL + "/slow", "", false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			recorder := httptest.NewRecorder()
+			ctx, _ := gin.CreateTestContext(recorder)
+			ctx.Request = httptest.NewRequest(http.MethodPost, "/api/v1/register", nil)
+			m := &ClientMetadata{RedirectURIs: test.redirectURIs, SectorIdentifierURI: test.sectorURI}
+			got, ok := m.sector(ctx)
+			if got != test.want || ok != test.ok {
+				t.Errorf("sector = %q, %v, want %q, %v", got, ok, test.want, test.ok)
+			}
+			if !ok && recorder.Code != http.StatusBadRequest {
+				t.Errorf("status = %d, want %d", recorder.Code, http.StatusBadRequest)
+			}
+		})
+	}
+}
+
+func TestApplyKeepsSector(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`["https://a.example/cb", "https://b.example/cb"]`))
+	}))
+	defer server.Close()
+	defer func(c *http.Client) { sectorClient = c }(sectorClient)
+	sectorClient = server.Client()
+	host := strings.TrimPrefix(server.URL, "https://")
+
+	db := dbtest.Open()
+	db.Procedure("get_server_key_with_alg", func([]driver.Value) ([]string, [][]driver.Value, error) {
+		return []string{"kid", "type", "alg", "created", "private_key", "public_key"},
+			[][]driver.Value{{"key", "RSA", "RS256", time.Now(), "", ""}}, nil
+	})
+	tests := []struct {
+		name         string
+		sector       string
+		sectorURI    string
+		redirectURIs []string
+		want         string
+		ok           bool
+	}{
+		{"registered sector identifier uri", host, server.URL + "/sector",
+			[]string{"https://b.example/cb"}, host, true},
+		{"same redirect uri host", "a.example", "", []string{"https://a.example/other"}, "a.example", true},
+		{"other redirect uri host", "a.example", "", []string{"https://c.example/cb"}, "", false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ctx, recorder := dbtest.Recorded(db, http.MethodPut, "/api/v1/register/client")
+			app := &appdb.Application{ID: "client", SubjectType: discovery.SubjectTypePairwise,
+				SectorIdentifier: test.sector, SectorIdentifierURI: test.sectorURI}
+			m := &ClientMetadata{RedirectURIs: test.redirectURIs}
+			if ok := m.apply(ctx, app); ok != test.ok {
+				t.Fatalf("apply = %v, want %v (%s)", ok, test.ok, recorder.Body)
+			}
+			if !test.ok {
+				return
+			}
+			if app.SubjectType != discovery.SubjectTypePairwise || app.SectorIdentifier != test.want ||
+				app.SectorIdentifierURI != test.sectorURI {
+				t.Errorf("subject type %q, sector %q, uri %q, want pairwise, %q, %q",
+					app.SubjectType, app.SectorIdentifier, app.SectorIdentifierURI, test.want, test.sectorURI)
+			}
+			if got := metadataFromApp(app).SectorIdentifierURI; got != test.sectorURI {
+				t.Errorf("sector_identifier_uri = %q, want %q", got, test.sectorURI)
+			}
+		})
+	}
+}
+
+func TestValidRedirectURI(t *testing.T) {
+	tests := []struct {
+		uri  string
+		want bool
+	}{
+		{"https://client.example/cb", true},
+		{"HTTPS://client.example/cb?a=b", true},
+		{"http://localhost:8080/demo", true},
+		{"http://127.0.0.1/cb", true},
+		{"http://[::1]:8080/cb", true},
+		{"http://client.example/cb", false},
+		{"http://localhost.client.example/cb", false},
+		{"https://client.example/cb#fragment", false},
+		{"javascript://x/%0Aalert(document.domain)//", false},
+		{"JavaScript://x/%0Aalert(1)", false},
+		{"data://x/text/html,<script>alert(1)</script>", false},
+		{"vbscript://x/msgbox", false},
+		{"com.example.app://cb", false},
+		{"/relative", false},
+		{"https://client.example/" + strings.Repeat("a", 250), false},
+	}
+	for _, test := range tests {
+		if got := validRedirectURI(test.uri); got != test.want {
+			t.Errorf("validRedirectURI(%q) = %v, want %v", test.uri, got, test.want)
+		}
+	}
+}
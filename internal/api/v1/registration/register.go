@@ -0,0 +1,123 @@
+package registration
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"net/http"
+	"strings"
+	"time"
+	"uyulala/internal/api"
+	"uyulala/internal/api/application"
+	"uyulala/internal/api/token"
+	"uyulala/internal/db"
+	"uyulala/internal/db/appdb"
+
+	"github.com/gin-gonic/gin"
+)
+
+type updateClientRequest struct {
+	ClientID     string `json:"client_id"`
+	ClientSecret string `json:"client_secret"`
+	ClientMetadata
+}
+
+func bearerToken(ctx *gin.Context) string {
+	fields := strings.Fields(ctx.GetHeader("Authorization"))
+	if len(fields) != 2 || !strings.EqualFold(fields[0], "bearer") {
+		return ""
+	}
+	return fields[1]
+}
+
+func hashToken(t string) string {
+	sum := sha256.Sum256([]byte(t))
+	return hex.EncodeToString(sum[:])
+}
+
+// registerHandler registers a new client (RFC 7591). It requires an initial access token issued by an administrative
+// application.
+func registerHandler(ctx *gin.Context) {
+	tok, typ, err := token.Verify(ctx, bearerToken(ctx))
+	if err != nil || typ != token.TypeInitialAccess {
+		api.OAuth2ErrorResponse(ctx, http.StatusUnauthorized, "invalid_token", "Invalid initial access token")
+		return
+	}
+	if issuer, err := appdb.GetApplication(ctx, tok.Subject()); err != nil || !issuer.Admin {
+		api.OAuth2ErrorResponse(ctx, http.StatusUnauthorized, "invalid_token", "Initial access token issuer is no longer valid")
+		return
+	}
+
+	metadata := &ClientMetadata{}
+	if err := ctx.BindJSON(metadata); err != nil {
+		api.OAuth2ErrorResponse(ctx, http.StatusBadRequest, "invalid_client_metadata", "Invalid client metadata")
+		return
+	}
+	app := &appdb.Application{
+		ID:      db.GenerateUUID(),
+		Secret:  db.GenerateUUID(),
+		Created: time.Now(),
+	}
+	if !metadata.apply(ctx, app) {
+		return
+	}
+	if err := appdb.Create(ctx, app); err != nil {
+		api.AbortError(ctx, http.StatusInternalServerError, "internal_error", "Unexpected error", err)
+		return
+	}
+	registrationToken := db.GenerateID(32)
+	if err := appdb.SetRegistrationToken(ctx, app.ID, hashToken(registrationToken)); err != nil {
+		api.AbortError(ctx, http.StatusInternalServerError, "internal_error", "Unexpected error", err)
+		return
+	}
+	ctx.JSON(http.StatusCreated, registrationResponse(app, registrationToken))
+}
+
+// registrationMiddleware authenticates requests to the client configuration endpoint (RFC 7592)
+// with the registration access token of the client.
+func registrationMiddleware(ctx *gin.Context) {
+	app, err := appdb.GetApplication(ctx, ctx.Param("client_id"))
+	if err != nil || app.RegistrationTokenHash == "" ||
+		subtle.ConstantTimeCompare([]byte(hashToken(bearerToken(ctx))), []byte(app.RegistrationTokenHash)) == 0 {
+		api.OAuth2ErrorResponse(ctx, http.StatusUnauthorized, "invalid_token", "Invalid registration access token")
+		return
+	}
+	ctx.Set("application", app)
+}
+
+func readClientHandler(ctx *gin.Context) {
+	api.JSONResponse(ctx, registrationResponse(application.GetCurrentApplication(ctx), ""))
+}
+
+func updateClientHandler(ctx *gin.Context) {
+	app := application.GetCurrentApplication(ctx)
+	req := &updateClientRequest{}
+	if err := ctx.BindJSON(req); err != nil {
+		api.OAuth2ErrorResponse(ctx, http.StatusBadRequest, "invalid_client_metadata", "Invalid client metadata")
+		return
+	}
+	if req.ClientID != app.ID {
+		api.OAuth2ErrorResponse(ctx, http.StatusBadRequest, "invalid_client_metadata", "client_id does not match")
+		return
+	}
+	if req.ClientSecret != "" && subtle.ConstantTimeCompare([]byte(req.ClientSecret), []byte(app.Secret)) == 0 {
+		api.OAuth2ErrorResponse(ctx, http.StatusBadRequest, "invalid_client_metadata", "client_secret does not match")
+		return
+	}
+	if !req.ClientMetadata.apply(ctx, app) {
+		return
+	}
+	if err := appdb.Update(ctx, app); err != nil {
+		api.AbortError(ctx, http.StatusInternalServerError, "internal_error", "Unexpected error", err)
+		return
+	}
+	api.JSONResponse(ctx, registrationResponse(app, ""))
+}
+
+func deleteClientHandler(ctx *gin.Context) {
+	if err := appdb.Delete(ctx, application.GetCurrentApplication(ctx).ID); err != nil {
+		api.AbortError(ctx, http.StatusInternalServerError, "internal_error", "Unexpected error", err)
+		return
+	}
+	ctx.Status(http.StatusNoContent)
+}
@@ -0,0 +1,17 @@
+package registration
+
+import (
+	"github.com/gin-gonic/gin"
+)
+
+func AddRoutes(g *gin.RouterGroup) {
+	g.POST("", registerHandler)
+	g.OPTIONS("", func(context *gin.Context) {})
+
+	client := g.Group("/:client_id")
+	client.OPTIONS("", func(context *gin.Context) {})
+	client.Use(registrationMiddleware)
+	client.GET("", readClientHandler)
+	client.PUT("", updateClientHandler)
+	client.DELETE("", deleteClientHandler)
+}
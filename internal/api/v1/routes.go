@@ -6,6 +6,7 @@ import (
 	"uyulala/internal/api/v1/client"
 	"uyulala/internal/api/v1/oidc"
 	"uyulala/internal/api/v1/public"
+	"uyulala/internal/api/v1/registration"
 	"uyulala/internal/api/v1/service"
 	"uyulala/internal/api/v1/user"
 
@@ -53,6 +54,9 @@ func AddRoutes(g *gin.RouterGroup) {
 		application.UserMiddleware(),
 	)
 
+	registrationGroup := g.Group("/register")
+	registrationGroup.Use(cors.New(clientCorsConfig))
+
 	issuerGroup := g.Group("/oidc")
 	issuerGroup.Use(
 		cors.New(clientCorsConfig),
@@ -63,5 +67,6 @@ func AddRoutes(g *gin.RouterGroup) {
 	client.AddRoutes(clientGroup)
 	service.AddRoutes(serviceGroup)
 	user.AddRoutes(userGroup)
+	registration.AddRoutes(registrationGroup)
 	oidc.AddRoutes(issuerGroup)
 }
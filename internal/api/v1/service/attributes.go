@@ -0,0 +1,63 @@
+package service
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"uyulala/internal/api"
+	"uyulala/internal/api/token"
+	"uyulala/internal/db/userdb"
+
+	"github.com/gin-gonic/gin"
+)
+
+type updateAttributesRequest struct {
+	UserID     string         `json:"userId"`
+	Attributes map[string]any `json:"attributes"`
+}
+
+func getAttributesHandler(ctx *gin.Context) {
+	userID := ctx.Query("userId")
+	if _, err := userdb.GetUser(ctx, userID); errors.Is(err, sql.ErrNoRows) {
+		api.AbortError(ctx, http.StatusNotFound, "user_not_found", "User not found", err)
+		return
+	} else if err != nil {
+		api.AbortError(ctx, http.StatusInternalServerError, "internal_error", "Internal error", err)
+		return
+	}
+	attributes, err := userdb.GetAttributes(ctx, userID)
+	if err != nil {
+		api.AbortError(ctx, http.StatusInternalServerError, "internal_error", "Internal error", err)
+		return
+	}
+	ctx.JSON(http.StatusOK, attributes)
+}
+
+func updateAttributesHandler(ctx *gin.Context) {
+	var req updateAttributesRequest
+	if err := ctx.BindJSON(&req); err != nil {
+		api.AbortError(ctx, http.StatusBadRequest, "invalid_request", "Invalid request", err)
+		return
+	}
+	if err := token.ValidateAttributes(req.Attributes); err != nil {
+		api.AbortError(ctx, http.StatusBadRequest, "invalid_request", err.Error(), err)
+		return
+	}
+	if _, err := userdb.GetUser(ctx, req.UserID); errors.Is(err, sql.ErrNoRows) {
+		api.AbortError(ctx, http.StatusNotFound, "user_not_found", "User not found", err)
+		return
+	} else if err != nil {
+		api.AbortError(ctx, http.StatusInternalServerError, "internal_error", "Unexpected error", err)
+		return
+	}
+	if err := userdb.SetAttributes(ctx, req.UserID, req.Attributes); err != nil {
+		api.AbortError(ctx, http.StatusInternalServerError, "internal_error", "Unexpected error", err)
+		return
+	}
+	attributes, err := userdb.GetAttributes(ctx, req.UserID)
+	if err != nil {
+		api.AbortError(ctx, http.StatusInternalServerError, "internal_error", "Unexpected error", err)
+		return
+	}
+	api.JSONResponse(ctx, attributes)
+}
@@ -0,0 +1,42 @@
+package service
+
+import (
+	"net/http"
+	"uyulala/internal/api"
+	"uyulala/internal/api/token"
+	"uyulala/internal/db/userdb"
+
+	"github.com/gin-gonic/gin"
+)
+
+type deleteConsentRequest struct {
+	UserID string `json:"userId"`
+	AppID  string `json:"appId"`
+}
+
+func listConsentsHandler(ctx *gin.Context) {
+	userID := ctx.Query("userId")
+	if userID == "" {
+		api.AbortError(ctx, http.StatusBadRequest, "invalid_request", "Missing userId", nil)
+		return
+	}
+	consents, err := userdb.ListConsents(ctx, userID)
+	if err != nil {
+		api.AbortError(ctx, http.StatusInternalServerError, "internal_error", "Internal error", err)
+		return
+	}
+	ctx.JSON(http.StatusOK, consents)
+}
+
+func deleteConsentHandler(ctx *gin.Context) {
+	var req deleteConsentRequest
+	if err := ctx.BindJSON(&req); err != nil {
+		api.AbortError(ctx, http.StatusBadRequest, "invalid_request", "Invalid request", err)
+		return
+	}
+	if err := token.RevokeConsent(ctx, req.UserID, req.AppID); err != nil {
+		api.AbortError(ctx, http.StatusInternalServerError, "internal_error", "Unexpected error", err)
+		return
+	}
+	api.DeletedResponse(ctx)
+}
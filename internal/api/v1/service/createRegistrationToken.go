@@ -0,0 +1,41 @@
+package service
+
+import (
+	"net/http"
+	"time"
+	"uyulala/internal/api"
+	"uyulala/internal/api/application"
+	"uyulala/internal/api/token"
+
+	"github.com/gin-gonic/gin"
+)
+
+type createRegistrationTokenRequest struct {
+	// Timeout is the lifetime of the token in seconds.
+	Timeout int64 `json:"timeout"`
+}
+
+type createRegistrationTokenResponse struct {
+	InitialAccessToken string `json:"initial_access_token"`
+	ExpiresIn          int64  `json:"expires_in"`
+}
+
+func createRegistrationTokenHandler(ctx *gin.Context) {
+	req := &createRegistrationTokenRequest{Timeout: 24 * 60 * 60}
+	if err := ctx.BindJSON(req); err != nil {
+		api.AbortError(ctx, http.StatusBadRequest, "invalid_request", "Invalid request", err)
+		return
+	}
+	timeout := time.Duration(req.Timeout).Abs() * time.Second
+	initialAccessToken, err := token.InitialAccessToken(ctx, application.GetCurrentApplication(ctx), timeout)
+	if err != nil {
+		if !ctx.IsAborted() {
+			api.AbortError(ctx, http.StatusInternalServerError, "internal_error", "Unexpected error", err)
+		}
+		return
+	}
+	api.JSONResponse(ctx, &createRegistrationTokenResponse{
+		InitialAccessToken: initialAccessToken,
+		ExpiresIn:          int64(timeout / time.Second),
+	})
+}
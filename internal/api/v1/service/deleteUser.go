@@ -3,6 +3,7 @@ package service
 import (
 	"net/http"
 	"uyulala/internal/api"
+	"uyulala/internal/api/token"
 	"uyulala/internal/db/userdb"
 
 	"github.com/gin-gonic/gin"
@@ -18,6 +19,11 @@ func deleteUserHandler(ctx *gin.Context) {
 		api.AbortError(ctx, http.StatusBadRequest, "invalid_request", "Invalid request", err)
 		return
 	}
+	// The sessions are removed together with the user, tell the applications holding tokens for them.
+	if err := token.EndUserSessions(ctx, req.UserID); err != nil {
+		api.AbortError(ctx, http.StatusInternalServerError, "internal_error", "Unexpected error", err)
+		return
+	}
 	if err := userdb.DeleteUser(ctx, req.UserID); err != nil {
 		api.AbortError(ctx, http.StatusInternalServerError, "internal_error", "Unexpected error", err)
 		return
@@ -0,0 +1,72 @@
+package service
+
+import (
+	"net/http"
+	"uyulala/internal/api"
+	"uyulala/internal/api/token"
+	"uyulala/internal/db/keydb"
+	"uyulala/internal/db/resourcedb"
+
+	"github.com/gin-gonic/gin"
+)
+
+type deleteResourceRequest struct {
+	ID string `json:"id"`
+}
+
+func listResourcesHandler(ctx *gin.Context) {
+	resources, err := resourcedb.List(ctx)
+	if err != nil {
+		api.AbortError(ctx, http.StatusInternalServerError, "internal_error", "Internal error", err)
+		return
+	}
+	if resources == nil {
+		resources = []*resourcedb.ResourceServer{}
+	}
+	ctx.JSON(http.StatusOK, resources)
+}
+
+func createResourceHandler(ctx *gin.Context) {
+	req := &resourcedb.ResourceServer{}
+	if err := ctx.BindJSON(req); err != nil {
+		api.AbortError(ctx, http.StatusBadRequest, "invalid_request", "Invalid request", err)
+		return
+	}
+	if !token.ValidResourceIndicator(req.ID) {
+		api.AbortError(ctx, http.StatusBadRequest, "invalid_request", "The resource indicator must be an absolute uri without fragment", nil)
+		return
+	}
+	if req.AccessTokenLength < 0 {
+		api.AbortError(ctx, http.StatusBadRequest, "invalid_request", "Token length can't be negative", nil)
+		return
+	}
+	if req.KeyID != "" {
+		if _, err := keydb.GetKey(ctx, req.KeyID); err != nil {
+			api.AbortError(ctx, http.StatusBadRequest, "invalid_request", "Unknown key", err)
+			return
+		}
+	}
+	if err := resourcedb.Create(ctx, req); err != nil {
+		api.AbortError(ctx, http.StatusInternalServerError, "internal_error", "Unexpected error", err)
+		return
+	}
+	resource, err := resourcedb.Get(ctx, req.ID)
+	if err != nil {
+		api.AbortError(ctx, http.StatusInternalServerError, "internal_error", "Unexpected error", err)
+		return
+	}
+	api.JSONResponse(ctx, resource)
+}
+
+func deleteResourceHandler(ctx *gin.Context) {
+	var req deleteResourceRequest
+	if err := ctx.BindJSON(&req); err != nil {
+		api.AbortError(ctx, http.StatusBadRequest, "invalid_request", "Invalid request", err)
+		return
+	}
+	if err := resourcedb.Delete(ctx, req.ID); err != nil {
+		api.AbortError(ctx, http.StatusInternalServerError, "internal_error", "Unexpected error", err)
+		return
+	}
+	api.DeletedResponse(ctx)
+}
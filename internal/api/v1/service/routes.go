@@ -6,10 +6,24 @@ import (
 
 func AddRoutes(g *gin.RouterGroup) {
 	g.GET("/list/users", listUsersHandler)
+	g.GET("/list/resources", listResourcesHandler)
+	g.GET("/list/scopes", listScopesHandler)
+	g.GET("/list/consents", listConsentsHandler)
+	g.GET("/get/token_policy", getTokenPolicyHandler)
+	g.GET("/get/attributes", getAttributesHandler)
 
 	g.POST("/create/user", createUserHandler)
 	g.POST("/create/key", createKeyHandler)
+	g.POST("/create/registration_token", createRegistrationTokenHandler)
+	g.POST("/create/resource", createResourceHandler)
+	g.POST("/create/scope", createScopeHandler)
+
+	g.POST("/update/token_policy", updateTokenPolicyHandler)
+	g.POST("/update/attributes", updateAttributesHandler)
 
 	g.POST("/delete/user", deleteUserHandler)
 	g.POST("/delete/key", deleteUserKeyHandler)
+	g.POST("/delete/resource", deleteResourceHandler)
+	g.POST("/delete/scope", deleteScopeHandler)
+	g.POST("/delete/consent", deleteConsentHandler)
 }
@@ -0,0 +1,77 @@
+package service
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"strings"
+	"uyulala/internal/api"
+	"uyulala/internal/db/appdb"
+	"uyulala/internal/db/scopedb"
+
+	"github.com/gin-gonic/gin"
+)
+
+type deleteScopeRequest struct {
+	Name string `json:"name"`
+}
+
+func listScopesHandler(ctx *gin.Context) {
+	scopes, err := scopedb.List(ctx)
+	if err != nil {
+		api.AbortError(ctx, http.StatusInternalServerError, "internal_error", "Internal error", err)
+		return
+	}
+	if scopes == nil {
+		scopes = []*scopedb.Scope{}
+	}
+	ctx.JSON(http.StatusOK, scopes)
+}
+
+func createScopeHandler(ctx *gin.Context) {
+	req := &scopedb.Scope{}
+	if err := ctx.BindJSON(req); err != nil {
+		api.AbortError(ctx, http.StatusBadRequest, "invalid_request", "Invalid request", err)
+		return
+	}
+	if req.Name == "" || strings.ContainsAny(req.Name, " \t\r\n\"\\") {
+		api.AbortError(ctx, http.StatusBadRequest, "invalid_request", "Invalid scope name", nil)
+		return
+	}
+	if req.Name == "openid" || req.Name == "offline_access" {
+		api.AbortError(ctx, http.StatusBadRequest, "invalid_request", "openid and offline_access can't be registered", nil)
+		return
+	}
+	for _, appID := range req.Apps {
+		if _, err := appdb.GetApplication(ctx, appID); errors.Is(err, sql.ErrNoRows) {
+			api.AbortError(ctx, http.StatusBadRequest, "invalid_request", "Unknown application "+appID, err)
+			return
+		} else if err != nil {
+			api.AbortError(ctx, http.StatusInternalServerError, "internal_error", "Unexpected error", err)
+			return
+		}
+	}
+	if err := scopedb.Create(ctx, req); err != nil {
+		api.AbortError(ctx, http.StatusInternalServerError, "internal_error", "Unexpected error", err)
+		return
+	}
+	scope, err := scopedb.Get(ctx, req.Name)
+	if err != nil {
+		api.AbortError(ctx, http.StatusInternalServerError, "internal_error", "Unexpected error", err)
+		return
+	}
+	api.JSONResponse(ctx, scope)
+}
+
+func deleteScopeHandler(ctx *gin.Context) {
+	var req deleteScopeRequest
+	if err := ctx.BindJSON(&req); err != nil {
+		api.AbortError(ctx, http.StatusBadRequest, "invalid_request", "Invalid request", err)
+		return
+	}
+	if err := scopedb.Delete(ctx, req.Name); err != nil {
+		api.AbortError(ctx, http.StatusInternalServerError, "internal_error", "Unexpected error", err)
+		return
+	}
+	api.DeletedResponse(ctx)
+}
@@ -0,0 +1,103 @@
+package service
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"uyulala/internal/api"
+	"uyulala/internal/api/application"
+	"uyulala/internal/api/token"
+	"uyulala/internal/db/appdb"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TokenPolicy are the token settings of an application. Lengths are in seconds, zero uses the server default.
+type TokenPolicy struct {
+	AppID              string              `json:"appId"`
+	AccessTokenLength  int64               `json:"accessTokenLength"`
+	IDTokenLength      int64               `json:"idTokenLength"`
+	RefreshTokenLength int64               `json:"refreshTokenLength"`
+	Audiences          []string            `json:"audiences"`
+	Claims             []*appdb.TokenClaim `json:"claims"`
+}
+
+func tokenPolicyFromApp(app *appdb.Application) *TokenPolicy {
+	policy := &TokenPolicy{
+		AppID:              app.ID,
+		AccessTokenLength:  app.AccessTokenLength,
+		IDTokenLength:      app.IDTokenLength,
+		RefreshTokenLength: app.RefreshTokenLength,
+		Audiences:          app.Audiences,
+		Claims:             app.TokenClaims,
+	}
+	if policy.Audiences == nil {
+		policy.Audiences = []string{}
+	}
+	if policy.Claims == nil {
+		policy.Claims = []*appdb.TokenClaim{}
+	}
+	return policy
+}
+
+// policyApplication returns the application appID, or the calling application when it's empty.
+func policyApplication(ctx *gin.Context, appID string) *appdb.Application {
+	if appID == "" {
+		appID = application.GetCurrentApplication(ctx).ID
+	}
+	app, err := appdb.GetApplication(ctx, appID)
+	if errors.Is(err, sql.ErrNoRows) {
+		api.AbortError(ctx, http.StatusNotFound, "not_found", "Application not found", err)
+		return nil
+	} else if err != nil {
+		api.AbortError(ctx, http.StatusInternalServerError, "internal_error", "Unexpected error", err)
+		return nil
+	}
+	return app
+}
+
+func getTokenPolicyHandler(ctx *gin.Context) {
+	app := policyApplication(ctx, ctx.Query("appId"))
+	if app == nil {
+		return
+	}
+	api.JSONResponse(ctx, tokenPolicyFromApp(app))
+}
+
+func updateTokenPolicyHandler(ctx *gin.Context) {
+	req := &TokenPolicy{}
+	if err := ctx.BindJSON(req); err != nil {
+		api.AbortError(ctx, http.StatusBadRequest, "invalid_request", "Invalid request", err)
+		return
+	}
+	if req.AccessTokenLength < 0 || req.IDTokenLength < 0 || req.RefreshTokenLength < 0 {
+		api.AbortError(ctx, http.StatusBadRequest, "invalid_request", "Token lengths can't be negative", nil)
+		return
+	}
+	for _, audience := range req.Audiences {
+		if audience == "" || len(audience) > 255 {
+			api.AbortError(ctx, http.StatusBadRequest, "invalid_request", "Invalid audience", nil)
+			return
+		}
+	}
+	for _, claim := range req.Claims {
+		if err := token.ValidateClaim(claim); err != nil {
+			api.AbortError(ctx, http.StatusBadRequest, "invalid_request", "Invalid claim "+claim.Name, err)
+			return
+		}
+	}
+	app := policyApplication(ctx, req.AppID)
+	if app == nil {
+		return
+	}
+	app.AccessTokenLength = req.AccessTokenLength
+	app.IDTokenLength = req.IDTokenLength
+	app.RefreshTokenLength = req.RefreshTokenLength
+	app.Audiences = req.Audiences
+	app.TokenClaims = req.Claims
+	if err := appdb.SetTokenPolicy(ctx, app); err != nil {
+		api.AbortError(ctx, http.StatusInternalServerError, "internal_error", "Unexpected error", err)
+		return
+	}
+	api.JSONResponse(ctx, tokenPolicyFromApp(app))
+}
@@ -0,0 +1,56 @@
+package user
+
+import (
+	"fmt"
+	"net/http"
+	"uyulala/internal/api"
+	"uyulala/internal/api/application"
+	"uyulala/internal/api/token"
+	"uyulala/internal/db/userdb"
+
+	"github.com/gin-gonic/gin"
+)
+
+type updateAttributesRequest struct {
+	Attributes map[string]any `json:"attributes"`
+}
+
+func getAttributes(c *gin.Context) {
+	jwt := application.GetCurrentJWT(c)
+	attributes, err := userdb.GetAttributes(c, jwt.Subject())
+	if err != nil {
+		api.AbortError(c, http.StatusInternalServerError, "internal_error", "internal error", err)
+		return
+	}
+	api.JSONResponse(c, attributes)
+}
+
+// updateAttributes lets users edit their own standard claims. Custom attributes and email_verified are managed by
+// the service api, and changing the email makes it unverified.
+func updateAttributes(c *gin.Context) {
+	jwt := application.GetCurrentJWT(c)
+	var req updateAttributesRequest
+	if err := c.BindJSON(&req); err != nil {
+		api.AbortError(c, http.StatusBadRequest, "invalid_request", "Invalid request", err)
+		return
+	}
+	for name := range req.Attributes {
+		if !api.IsStandardClaim(name) || name == "email_verified" {
+			err := fmt.Errorf("attribute %s is not editable", name)
+			api.AbortError(c, http.StatusForbidden, "not_editable", err.Error(), err)
+			return
+		}
+	}
+	if err := token.ValidateAttributes(req.Attributes); err != nil {
+		api.AbortError(c, http.StatusBadRequest, "invalid_request", err.Error(), err)
+		return
+	}
+	if _, ok := req.Attributes["email"]; ok {
+		req.Attributes["email_verified"] = nil
+	}
+	if err := userdb.SetAttributes(c, jwt.Subject(), req.Attributes); err != nil {
+		api.AbortError(c, http.StatusInternalServerError, "internal_error", "internal error", err)
+		return
+	}
+	getAttributes(c)
+}
@@ -0,0 +1,39 @@
+package user
+
+import (
+	"net/http"
+	"uyulala/internal/api"
+	"uyulala/internal/api/application"
+	"uyulala/internal/api/token"
+	"uyulala/internal/db/userdb"
+
+	"github.com/gin-gonic/gin"
+)
+
+type revokeConsentRequest struct {
+	AppID string `json:"appId"`
+}
+
+func listConsents(c *gin.Context) {
+	jwt := application.GetCurrentJWT(c)
+	consents, err := userdb.ListConsents(c, jwt.Subject())
+	if err != nil {
+		api.AbortError(c, http.StatusInternalServerError, "internal_error", "internal error", err)
+		return
+	}
+	api.JSONResponse(c, consents)
+}
+
+func revokeConsent(c *gin.Context) {
+	jwt := application.GetCurrentJWT(c)
+	var req revokeConsentRequest
+	if err := c.BindJSON(&req); err != nil {
+		api.AbortError(c, http.StatusBadRequest, "invalid_request", "Invalid request", err)
+		return
+	}
+	if err := token.RevokeConsent(c, jwt.Subject(), req.AppID); err != nil {
+		api.AbortError(c, http.StatusInternalServerError, "internal_error", "internal error", err)
+		return
+	}
+	api.DeletedResponse(c)
+}
@@ -8,4 +8,8 @@ func AddRoutes(g *gin.RouterGroup) {
 	g.POST("/addKey", addKey)
 	g.POST("/deleteKey", deleteKey)
 	g.GET("/listKeys", listKeys)
+	g.GET("/listConsents", listConsents)
+	g.POST("/revokeConsent", revokeConsent)
+	g.GET("/getAttributes", getAttributes)
+	g.POST("/updateAttributes", updateAttributes)
 }
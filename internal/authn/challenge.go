@@ -0,0 +1,25 @@
+package authn
+
+import (
+	"bytes"
+	"crypto/sha256"
+)
+
+// ChallengeHash is the WebAuthn challenge of a challenge with signature text or data, so the user signs them:
+// SHA256(UserID + '\n' + AppID + '\n' + ChallengeID + '\n' + nonce + '\n' + Text + '\n' + Data)
+func ChallengeHash(userID, appID, challengeID, nonce, text string, data []byte) []byte {
+	buff := bytes.Buffer{}
+	buff.Write([]byte(userID))
+	buff.WriteByte('\n')
+	buff.Write([]byte(appID))
+	buff.WriteByte('\n')
+	buff.Write([]byte(challengeID))
+	buff.WriteByte('\n')
+	buff.Write([]byte(nonce))
+	buff.WriteByte('\n')
+	buff.Write([]byte(text))
+	buff.WriteByte('\n')
+	buff.Write(data)
+	hash := sha256.Sum256(buff.Bytes())
+	return hash[:]
+}
@@ -2,25 +2,53 @@ package appdb
 
 import (
 	"database/sql"
+	"net/url"
+	"slices"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/jmoiron/sqlx"
 	"gitlab.com/daedaluz/gindb"
 )
 
 type Application struct {
-	ID                   string    `json:"id" db:"id"`
-	Name                 string    `json:"name" db:"name"`
-	Created              time.Time `json:"created" db:"created"`
-	Secret               string    `json:"-" db:"secret"`
-	Description          string    `json:"description" db:"description"`
-	Icon                 string    `json:"icon" db:"icon"`
-	IDTokenAlg           string    `json:"idTokenAlg" db:"alg"`
-	KeyID                string    `json:"keyId" db:"kid"`
-	Admin                bool      `json:"admin" db:"is_admin"`
-	RedirectURI          []string  `json:"-"`
-	CIBAMode             string    `json:"-" db:"ciba_mode"`
-	NotificationEndpoint string    `json:"-" db:"notification_endpoint"`
+	ID                    string        `json:"id" db:"id"`
+	Name                  string        `json:"name" db:"name"`
+	Created               time.Time     `json:"created" db:"created"`
+	Secret                string        `json:"-" db:"secret"`
+	Description           string        `json:"description" db:"description"`
+	Icon                  string        `json:"icon" db:"icon"`
+	IDTokenAlg            string        `json:"idTokenAlg" db:"alg"`
+	KeyID                 string        `json:"keyId" db:"kid"`
+	Admin                 bool          `json:"admin" db:"is_admin"`
+	RedirectURI           []string      `json:"-"`
+	PostLogoutRedirectURI []string      `json:"-"`
+	AllowedScopes         []string      `json:"-"`
+	ResponseTypes         []string      `json:"-"`
+	CIBAMode              string        `json:"-" db:"ciba_mode"`
+	NotificationEndpoint  string        `json:"-" db:"notification_endpoint"`
+	BackChannelLogoutURI  string        `json:"-" db:"backchannel_logout_uri"`
+	RequirePAR            bool          `json:"-" db:"require_par"`
+	JWKS                  string        `json:"-" db:"jwks"`
+	JWKSURI               string        `json:"-" db:"jwks_uri"`
+	AuthMethod            string        `json:"-" db:"token_endpoint_auth_method"`
+	TLSClientSubjectDN    string        `json:"-" db:"tls_client_auth_subject_dn"`
+	TLSClientThumbprint   string        `json:"-" db:"tls_client_thumbprint"`
+	RegistrationTokenHash string        `json:"-" db:"registration_token_hash"`
+	RegistrationScope     string        `json:"-" db:"registration_scope"`
+	SubjectType           string        `json:"-" db:"subject_type"`
+	SectorIdentifier      string        `json:"-" db:"sector_identifier"`
+	SectorIdentifierURI   string        `json:"-" db:"sector_identifier_uri"`
+	IDTokenEncryptionAlg  string        `json:"-" db:"id_token_encrypted_response_alg"`
+	IDTokenEncryptionEnc  string        `json:"-" db:"id_token_encrypted_response_enc"`
+	UserInfoEncryptionAlg string        `json:"-" db:"userinfo_encrypted_response_alg"`
+	UserInfoEncryptionEnc string        `json:"-" db:"userinfo_encrypted_response_enc"`
+	AccessTokenLength     int64         `json:"-" db:"access_token_length"`
+	IDTokenLength         int64         `json:"-" db:"id_token_length"`
+	RefreshTokenLength    int64         `json:"-" db:"refresh_token_length"`
+	FirstParty            bool          `json:"firstParty" db:"first_party"`
+	Audiences             []string      `json:"-"`
+	TokenClaims           []*TokenClaim `json:"-"`
 }
 
 func GetApplication(ctx *gin.Context, appID string) (*Application, error) {
@@ -40,17 +68,75 @@ func GetApplication(ctx *gin.Context, appID string) (*Application, error) {
 	}
 	res.Close()
 
-	res, err = tx.Queryx(`call get_app_redirect_urls(?)`, appID)
+	if app.RedirectURI, err = getStrings(tx, `call get_app_redirect_urls(?)`, appID); err != nil {
+		return nil, err
+	}
+	if app.PostLogoutRedirectURI, err = getStrings(tx, `call get_app_post_logout_redirect_urls(?)`, appID); err != nil {
+		return nil, err
+	}
+	if app.AllowedScopes, err = getStrings(tx, `call get_app_scopes(?)`, appID); err != nil {
+		return nil, err
+	}
+	if app.ResponseTypes, err = getStrings(tx, `call get_app_response_types(?)`, appID); err != nil {
+		return nil, err
+	}
+	if app.Audiences, err = getStrings(tx, `call get_app_audiences(?)`, appID); err != nil {
+		return nil, err
+	}
+	if err := tx.Select(&app.TokenClaims, `call get_app_token_claims(?)`, appID); err != nil {
+		return nil, err
+	}
+	return app, nil
+}
+
+// AllowsAuthMethod reports whether the application may authenticate with method.
+// Applications without a registered method may use any of them.
+func (a *Application) AllowsAuthMethod(method string) bool {
+	return a.AuthMethod == "" || a.AuthMethod == method
+}
+
+// AllowsResponseType reports whether the application may use responseType, normalized with api.ResponseType.
+// Applications without registered response types may only use code.
+func (a *Application) AllowsResponseType(responseType string) bool {
+	if len(a.ResponseTypes) == 0 {
+		return responseType == "code"
+	}
+	return slices.Contains(a.ResponseTypes, responseType)
+}
+
+// Sector returns the host pairwise subject identifiers of the application are calculated for.
+// Without a registered sector identifier, the host of its first redirect uri is used.
+func (a *Application) Sector() string {
+	if a.SectorIdentifier != "" {
+		return a.SectorIdentifier
+	}
+	for _, uri := range a.RedirectURI {
+		if parsed, err := url.Parse(uri); err == nil && parsed.Host != "" {
+			return parsed.Host
+		}
+	}
+	return a.ID
+}
+
+// SetFirstParty marks the application as operated by the server itself, whose users are not asked for consent.
+func SetFirstParty(tx *sqlx.Tx, appID string, firstParty bool) error {
+	_, err := tx.Exec(`call set_app_first_party(?, ?)`, appID, firstParty)
+	return err
+}
+
+func getStrings(tx *sqlx.Tx, query, appID string) ([]string, error) {
+	res, err := tx.Queryx(query, appID)
 	if err != nil {
 		return nil, err
 	}
 	defer res.Close()
+	var values []string
 	for res.Next() {
-		var url string
-		if err := res.Scan(&url); err != nil {
+		var value string
+		if err := res.Scan(&value); err != nil {
 			return nil, err
 		}
-		app.RedirectURI = append(app.RedirectURI, url)
+		values = append(values, value)
 	}
-	return app, nil
+	return values, nil
 }
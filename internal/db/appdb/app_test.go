@@ -0,0 +1,23 @@
+package appdb
+
+import "testing"
+
+func TestSector(t *testing.T) {
+	tests := []struct {
+		name string
+		app  *Application
+		want string
+	}{
+		{"sector identifier", &Application{ID: "client", SectorIdentifier: "sector.example",
+			RedirectURI: []string{"https://rp.example/cb"}}, "sector.example"},
+		{"redirect host", &Application{ID: "client", RedirectURI: []string{"not a url%", "https://rp.example/cb"}}, "rp.example"},
+		{"client id", &Application{ID: "client"}, "client"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := test.app.Sector(); got != test.want {
+				t.Fatalf("got %q, want %q", got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,13 @@
+package appdb
+
+import (
+	"time"
+	"uyulala/internal/db"
+
+	"github.com/gin-gonic/gin"
+)
+
+// UseAssertionID records the jti of a client assertion of the application until it expires, see db.UseID.
+func UseAssertionID(ctx *gin.Context, appID, jti string, expire time.Time) (bool, error) {
+	return db.UseID(ctx, "use_app_assertion_id", appID, jti, expire)
+}
@@ -0,0 +1,32 @@
+package appdb
+
+import (
+	"net/http"
+	"testing"
+	"time"
+	"uyulala/internal/db/dbtest"
+
+	"gitlab.com/daedaluz/gindb"
+)
+
+func TestUseAssertionIDSurvivesRollback(t *testing.T) {
+	db := dbtest.Open()
+	expire := time.Now().Add(time.Minute)
+
+	ctx := dbtest.Context(db, http.MethodPost, "/api/v1/oauth2/token")
+	if unused, err := UseAssertionID(ctx, "app", "jti-1", expire); err != nil || !unused {
+		t.Fatalf("first use: unused %v, err %v", unused, err)
+	}
+	// The token request fails after the client was authenticated.
+	if err := gindb.Rollback(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx = dbtest.Context(db, http.MethodPost, "/api/v1/oauth2/token")
+	if unused, err := UseAssertionID(ctx, "app", "jti-1", expire); err != nil || unused {
+		t.Fatalf("replay after a failed request: unused %v, err %v", unused, err)
+	}
+	if unused, err := UseAssertionID(ctx, "other", "jti-1", expire); err != nil || !unused {
+		t.Fatalf("same jti of another app: unused %v, err %v", unused, err)
+	}
+}
@@ -0,0 +1,63 @@
+package appdb
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/jmoiron/sqlx"
+	"gitlab.com/daedaluz/gindb"
+)
+
+const (
+	TokenAccess = "access_token"
+	TokenID     = "id_token"
+)
+
+// TokenClaim is a custom claim the application adds to its access or ID tokens.
+// Value is either a static value or a template, see token.CustomClaims.
+type TokenClaim struct {
+	Token string `json:"token" db:"token"`
+	Name  string `json:"name" db:"name"`
+	Value string `json:"value" db:"value"`
+}
+
+// Claims returns the custom claims of the application for the token type.
+func (a *Application) Claims(tokenType string) []*TokenClaim {
+	var claims []*TokenClaim
+	for _, claim := range a.TokenClaims {
+		if claim.Token == tokenType {
+			claims = append(claims, claim)
+		}
+	}
+	return claims
+}
+
+// SetTokenPolicy replaces the token lengths, audiences and custom claims of an application.
+func SetTokenPolicy(ctx *gin.Context, app *Application) error {
+	return SetTokenPolicyTx(gindb.GetTX(ctx), app)
+}
+
+// SetTokenPolicyTx is SetTokenPolicy within tx.
+func SetTokenPolicyTx(tx *sqlx.Tx, app *Application) error {
+	if _, err := tx.Exec(`call set_app_token_lengths(?, ?, ?, ?)`,
+		app.ID, app.AccessTokenLength, app.IDTokenLength, app.RefreshTokenLength); err != nil {
+		return err
+	}
+	for _, query := range []string{
+		`call delete_app_audiences(?)`,
+		`call delete_app_token_claims(?)`,
+	} {
+		if _, err := tx.Exec(query, app.ID); err != nil {
+			return err
+		}
+	}
+	for _, audience := range app.Audiences {
+		if _, err := tx.Exec(`call create_app_audience(?, ?)`, app.ID, audience); err != nil {
+			return err
+		}
+	}
+	for _, claim := range app.TokenClaims {
+		if _, err := tx.Exec(`call create_app_token_claim(?, ?, ?, ?)`, app.ID, claim.Token, claim.Name, claim.Value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
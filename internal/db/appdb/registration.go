@@ -0,0 +1,87 @@
+package appdb
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/jmoiron/sqlx"
+	"gitlab.com/daedaluz/gindb"
+)
+
+// Create stores a new dynamically registered application together with its redirect urls and response types.
+func Create(ctx *gin.Context, app *Application) error {
+	tx := gindb.GetTX(ctx)
+	if _, err := tx.Exec(`call create_app(?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		app.ID, app.Secret, app.Name, app.Description, app.Icon, app.CIBAMode, app.NotificationEndpoint,
+		app.IDTokenAlg, app.KeyID, app.Admin, app.BackChannelLogoutURI, app.RequirePAR, app.JWKS, app.JWKSURI,
+		app.AuthMethod, app.TLSClientSubjectDN, app.TLSClientThumbprint, app.SubjectType, app.SectorIdentifier,
+		app.IDTokenEncryptionAlg, app.IDTokenEncryptionEnc, app.UserInfoEncryptionAlg, app.UserInfoEncryptionEnc); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`call set_app_registration_scope(?, ?)`, app.ID, app.RegistrationScope); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`call set_app_sector_identifier_uri(?, ?)`, app.ID, app.SectorIdentifierURI); err != nil {
+		return err
+	}
+	return createLists(tx, app)
+}
+
+// Update replaces the registered settings, redirect urls and response types of an application. The id, secret and
+// the scopes allowed for client credentials are left as is.
+func Update(ctx *gin.Context, app *Application) error {
+	tx := gindb.GetTX(ctx)
+	if _, err := tx.Exec(`call update_app(?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		app.ID, app.Name, app.Description, app.Icon, app.CIBAMode, app.NotificationEndpoint,
+		app.IDTokenAlg, app.KeyID, app.BackChannelLogoutURI, app.RequirePAR, app.JWKS, app.JWKSURI,
+		app.AuthMethod, app.TLSClientSubjectDN, app.TLSClientThumbprint, app.SubjectType, app.SectorIdentifier,
+		app.IDTokenEncryptionAlg, app.IDTokenEncryptionEnc, app.UserInfoEncryptionAlg, app.UserInfoEncryptionEnc); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`call set_app_registration_scope(?, ?)`, app.ID, app.RegistrationScope); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`call set_app_sector_identifier_uri(?, ?)`, app.ID, app.SectorIdentifierURI); err != nil {
+		return err
+	}
+	for _, query := range []string{
+		`call delete_app_redirect_urls(?)`,
+		`call delete_app_post_logout_redirect_urls(?)`,
+		`call delete_app_response_types(?)`,
+	} {
+		if _, err := tx.Exec(query, app.ID); err != nil {
+			return err
+		}
+	}
+	return createLists(tx, app)
+}
+
+func Delete(ctx *gin.Context, appID string) error {
+	tx := gindb.GetTX(ctx)
+	_, err := tx.Exec(`call delete_app(?)`, appID)
+	return err
+}
+
+// SetRegistrationToken stores the hash of the registration access token of a dynamically registered application.
+func SetRegistrationToken(ctx *gin.Context, appID, tokenHash string) error {
+	tx := gindb.GetTX(ctx)
+	_, err := tx.Exec(`call set_app_registration_token(?, ?)`, appID, tokenHash)
+	return err
+}
+
+func createLists(tx *sqlx.Tx, app *Application) error {
+	for _, url := range app.RedirectURI {
+		if _, err := tx.Exec(`call create_app_redirect_url(?, ?)`, app.ID, url); err != nil {
+			return err
+		}
+	}
+	for _, url := range app.PostLogoutRedirectURI {
+		if _, err := tx.Exec(`call create_app_post_logout_redirect_url(?, ?)`, app.ID, url); err != nil {
+			return err
+		}
+	}
+	for _, responseType := range app.ResponseTypes {
+		if _, err := tx.Exec(`call create_app_response_type(?, ?)`, app.ID, responseType); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,53 @@
+package appdb
+
+import (
+	"database/sql/driver"
+	"net/http"
+	"slices"
+	"strings"
+	"testing"
+	"uyulala/internal/db/dbtest"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestRegistrationKeepsAllowedScopes(t *testing.T) {
+	var calls []string
+	db := dbtest.Open()
+	for _, procedure := range []string{
+		"create_app", "update_app", "set_app_registration_scope", "set_app_sector_identifier_uri",
+		"delete_app_redirect_urls", "delete_app_post_logout_redirect_urls", "delete_app_response_types",
+		"create_app_redirect_url", "create_app_response_type",
+	} {
+		db.Procedure(procedure, func(args []driver.Value) ([]string, [][]driver.Value, error) {
+			call := procedure
+			if procedure == "set_app_registration_scope" {
+				call += " " + args[1].(string)
+			}
+			calls = append(calls, call)
+			return nil, nil, nil
+		})
+	}
+	app := &Application{
+		ID:                "client",
+		RedirectURI:       []string{"https://client.example/cb"},
+		ResponseTypes:     []string{"code"},
+		AllowedScopes:     []string{"payments:write"},
+		RegistrationScope: "openid payments:write",
+	}
+	for name, store := range map[string]func(ctx *gin.Context, app *Application) error{"Create": Create, "Update": Update} {
+		calls = nil
+		ctx := dbtest.Context(db, http.MethodPut, "/api/v1/register/client")
+		if err := store(ctx, app); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		for _, call := range calls {
+			if strings.Contains(call, "app_scope") {
+				t.Errorf("%s: %s changes the scopes allowed for client credentials", name, call)
+			}
+		}
+		if !slices.Contains(calls, "set_app_registration_scope openid payments:write") {
+			t.Errorf("%s: registration scope not stored, calls %q", name, calls)
+		}
+	}
+}
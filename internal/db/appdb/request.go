@@ -0,0 +1,13 @@
+package appdb
+
+import (
+	"time"
+	"uyulala/internal/db"
+
+	"github.com/gin-gonic/gin"
+)
+
+// UseRequestObjectID records the jti of a request object of the application until it expires, see db.UseID.
+func UseRequestObjectID(ctx *gin.Context, appID, jti string, expire time.Time) (bool, error) {
+	return db.UseID(ctx, "use_app_request_object_id", appID, jti, expire)
+}
@@ -328,3 +328,13 @@ func DeleteCIBARequest(ctx *gin.Context, requestID string) error {
 	}
 	return nil
 }
+
+// GetCIBARequestID returns the auth_req_id of a CIBA challenge, or sql.ErrNoRows if the challenge wasn't started with CIBA.
+func GetCIBARequestID(ctx *gin.Context, challengeID string) (string, error) {
+	tx := gindb.GetTX(ctx)
+	var requestID string
+	if err := tx.Get(&requestID, `call get_ciba_request_id(?)`, challengeID); err != nil {
+		return "", err
+	}
+	return requestID, nil
+}
@@ -0,0 +1,129 @@
+package challengedb
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"math/big"
+	"net/http"
+	"strings"
+	"time"
+	"uyulala/internal/api"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gitlab.com/daedaluz/gindb"
+)
+
+// userCodeAlphabet has no vowels and no ambiguous characters, as recommended in RFC 8628 section 6.1.
+const userCodeAlphabet = "BCDFGHJKLMNPQRSTVWXZ"
+
+type DeviceCode struct {
+	DeviceCode  string       `db:"device_code"`
+	UserCode    string       `db:"user_code"`
+	ChallengeID string       `db:"challenge_id"`
+	Interval    int64        `db:"poll_interval"`
+	LastPoll    sql.NullTime `db:"last_poll"`
+}
+
+// NormalizeUserCode strips separators from a user code as typed by a user.
+func NormalizeUserCode(userCode string) string {
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case '-', ' ', '\t':
+			return -1
+		}
+		return r
+	}, strings.ToUpper(userCode))
+}
+
+// FormatUserCode formats a user code as XXXX-XXXX to make it easier to type.
+func FormatUserCode(userCode string) string {
+	if len(userCode) != 8 {
+		return userCode
+	}
+	return userCode[:4] + "-" + userCode[4:]
+}
+
+func generateUserCode() (string, error) {
+	data := make([]byte, 8)
+	for i := range data {
+		n, err := rand.Int(rand.Reader, big.NewInt(int64(len(userCodeAlphabet))))
+		if err != nil {
+			return "", err
+		}
+		data[i] = userCodeAlphabet[n.Int64()]
+	}
+	return string(data), nil
+}
+
+func CreateDeviceCode(ctx *gin.Context, challengeID string, interval int64) (deviceCode, userCode string, err error) {
+	id, err := uuid.NewRandom()
+	if err != nil {
+		return "", "", err
+	}
+	userCode, err = generateUserCode()
+	if err != nil {
+		return "", "", err
+	}
+	tx := gindb.GetTX(ctx)
+	_, err = tx.Exec(`call create_device_code(?, ?, ?, ?)`, id.String(), userCode, challengeID, interval)
+	return id.String(), userCode, err
+}
+
+func GetDeviceCode(ctx *gin.Context, deviceCode string) (*DeviceCode, error) {
+	res := &DeviceCode{}
+	tx := gindb.GetTX(ctx)
+	if err := tx.Get(res, `call get_device_code(?)`, deviceCode); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// PollDeviceCode records a token request for the device code together with the interval the client must keep.
+func PollDeviceCode(ctx *gin.Context, deviceCode string, interval int64) error {
+	tx := gindb.GetTX(ctx)
+	_, err := tx.Exec(`call poll_device_code(?, ?)`, deviceCode, interval)
+	return err
+}
+
+func DeleteDeviceCode(ctx *gin.Context, deviceCode string) error {
+	tx := gindb.GetTX(ctx)
+	_, err := tx.Exec(`call delete_device_code(?)`, deviceCode)
+	return err
+}
+
+func GetChallengeByUserCode(ctx *gin.Context, userCode string) (ch *Data, err error) {
+	ch = &Data{}
+	tx := gindb.GetTX(ctx)
+	res := tx.QueryRowx(`call get_challenge_by_user_code(?)`, NormalizeUserCode(userCode))
+	if err := res.StructScan(ch); err != nil {
+		return nil, err
+	}
+	return
+}
+
+// TooEarly reports whether the device code was polled again before its interval passed.
+func (d *DeviceCode) TooEarly() bool {
+	return d.LastPoll.Valid && time.Since(d.LastPoll.Time) < time.Duration(d.Interval)*time.Second
+}
+
+// ValidateDeviceCollect maps the challenge status to the token endpoint errors of RFC 8628 section 3.5.
+func (c *Data) ValidateDeviceCollect(ctx *gin.Context) bool {
+	if c.Expired() {
+		api.OAuth2ErrorResponse(ctx, http.StatusBadRequest, "expired_token", "Challenge has expired")
+		return false
+	}
+	switch c.Status {
+	case StatusPending, StatusViewed:
+		api.OAuth2ErrorResponse(ctx, http.StatusBadRequest, "authorization_pending", "Waiting for user to sign the challenge")
+	case StatusRejected:
+		api.OAuth2ErrorResponse(ctx, http.StatusBadRequest, "access_denied", "Challenge has been rejected")
+	case StatusCollected:
+		api.OAuth2ErrorResponse(ctx, http.StatusBadRequest, "invalid_grant", "Challenge has already been collected")
+	case StatusSigned:
+		return true
+	default:
+		api.OAuth2ErrorResponse(ctx, http.StatusInternalServerError, "invalid_status", "Invalid challenge status")
+	}
+	return false
+}
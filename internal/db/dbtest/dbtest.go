@@ -0,0 +1,232 @@
+// Package dbtest provides an in-memory stand-in for the database in tests.
+package dbtest
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+	"uyulala/internal/db/scopedb"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jmoiron/sqlx"
+	"gitlab.com/daedaluz/gindb"
+)
+
+var ErrUnsupported = errors.New("dbtest: unsupported statement")
+
+var databases atomic.Int64
+
+func init() {
+	sql.Register("dbtest", &memDriver{stores: map[string]*store{}})
+}
+
+// Procedure implements a stored procedure, returning the columns and rows of its result set.
+type Procedure func(args []driver.Value) ([]string, [][]driver.Value, error)
+
+// DB is an in-memory database. It implements the procedures that record one-time identifiers
+// (call use_..._id(key, id, expire)) with the transaction semantics of the real database: an identifier recorded
+// in a transaction is forgotten when the transaction is rolled back. Other procedures are added with Procedure.
+type DB struct {
+	*sqlx.DB
+	store *store
+}
+
+// Open returns a new empty database.
+func Open() *DB {
+	name := fmt.Sprintf("db%d", databases.Add(1))
+	db := sqlx.MustOpen("dbtest", name)
+	d := db.Driver().(*memDriver)
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.stores[name] = &store{used: map[string]bool{}, procedures: map[string]Procedure{}}
+	return &DB{DB: db, store: d.stores[name]}
+}
+
+// Procedure implements the stored procedure name with fn.
+func (db *DB) Procedure(name string, fn Procedure) {
+	db.store.mu.Lock()
+	defer db.store.mu.Unlock()
+	db.store.procedures[name] = fn
+}
+
+// Scopes implements the scope registry (call list_scopes_full()) with scopes.
+func (db *DB) Scopes(scopes []*scopedb.Scope) {
+	db.Procedure("list_scopes_full", func([]driver.Value) ([]string, [][]driver.Value, error) {
+		var rows [][]driver.Value
+		for _, scope := range scopes {
+			rows = append(rows, []driver.Value{scope.Name, scope.Description, time.Now(), nil, nil})
+			for _, claim := range scope.Claims {
+				rows = append(rows, []driver.Value{scope.Name, scope.Description, time.Now(), claim, nil})
+			}
+			for _, app := range scope.Apps {
+				rows = append(rows, []driver.Value{scope.Name, scope.Description, time.Now(), nil, app})
+			}
+		}
+		return []string{"name", "description", "created", "claim", "application_id"}, rows, nil
+	})
+}
+
+// Context returns a gin context for a request to method and path, with db and a transaction like the
+// gindb middlewares set up.
+func Context(db *DB, method, path string) *gin.Context {
+	ctx, _ := Recorded(db, method, path)
+	return ctx
+}
+
+// Recorded is Context together with the recorder of the response.
+func Recorded(db *DB, method, path string) (*gin.Context, *httptest.ResponseRecorder) {
+	recorder := httptest.NewRecorder()
+	ctx, _ := gin.CreateTestContext(recorder)
+	ctx.Request = httptest.NewRequest(method, path, nil)
+	gindb.MiddlewareDB(db.DB)(ctx)
+	if err := gindb.BeginTx(ctx); err != nil {
+		panic(err)
+	}
+	return ctx, recorder
+}
+
+type memDriver struct {
+	mu     sync.Mutex
+	stores map[string]*store
+}
+
+func (d *memDriver) Open(name string) (driver.Conn, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	s, ok := d.stores[name]
+	if !ok {
+		s = &store{used: map[string]bool{}, procedures: map[string]Procedure{}}
+		d.stores[name] = s
+	}
+	return &conn{store: s}, nil
+}
+
+type store struct {
+	mu         sync.Mutex
+	used       map[string]bool
+	procedures map[string]Procedure
+}
+
+type conn struct {
+	store *store
+	// pending holds the identifiers recorded in the open transaction, nil outside of one.
+	pending map[string]bool
+}
+
+func (c *conn) Prepare(query string) (driver.Stmt, error) {
+	return &stmt{conn: c, query: query}, nil
+}
+
+func (c *conn) Close() error {
+	return nil
+}
+
+func (c *conn) Begin() (driver.Tx, error) {
+	c.pending = map[string]bool{}
+	return c, nil
+}
+
+func (c *conn) Commit() error {
+	c.store.mu.Lock()
+	defer c.store.mu.Unlock()
+	for id := range c.pending {
+		c.store.used[id] = true
+	}
+	c.pending = nil
+	return nil
+}
+
+func (c *conn) Rollback() error {
+	c.pending = nil
+	return nil
+}
+
+// use records id and reports whether it was unused.
+func (c *conn) use(id string) bool {
+	c.store.mu.Lock()
+	defer c.store.mu.Unlock()
+	if c.store.used[id] || c.pending[id] {
+		return false
+	}
+	if c.pending != nil {
+		c.pending[id] = true
+	} else {
+		c.store.used[id] = true
+	}
+	return true
+}
+
+type stmt struct {
+	conn  *conn
+	query string
+}
+
+func (s *stmt) Close() error {
+	return nil
+}
+
+func (s *stmt) NumInput() int {
+	return -1
+}
+
+func (s *stmt) Exec(args []driver.Value) (driver.Result, error) {
+	if _, _, err := s.call(args); err != nil {
+		return nil, err
+	}
+	return driver.RowsAffected(1), nil
+}
+
+func (s *stmt) Query(args []driver.Value) (driver.Rows, error) {
+	columns, values, err := s.call(args)
+	if err != nil {
+		return nil, err
+	}
+	return &rows{columns: columns, values: values}, nil
+}
+
+func (s *stmt) call(args []driver.Value) ([]string, [][]driver.Value, error) {
+	procedure, _, _ := strings.Cut(strings.TrimPrefix(s.query, "call "), "(")
+	s.conn.store.mu.Lock()
+	fn, ok := s.conn.store.procedures[procedure]
+	s.conn.store.mu.Unlock()
+	switch {
+	case ok:
+		return fn(args)
+	case strings.HasPrefix(procedure, "use_") && len(args) == 3:
+		var inserted int64
+		if s.conn.use(fmt.Sprint(procedure, "\x00", args[0], "\x00", args[1])) {
+			inserted = 1
+		}
+		return []string{"row_count()"}, [][]driver.Value{{inserted}}, nil
+	}
+	return nil, nil, fmt.Errorf("%w: %s", ErrUnsupported, s.query)
+}
+
+type rows struct {
+	columns []string
+	values  [][]driver.Value
+}
+
+func (r *rows) Columns() []string {
+	return r.columns
+}
+
+func (r *rows) Close() error {
+	return nil
+}
+
+func (r *rows) Next(dest []driver.Value) error {
+	if len(r.values) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.values[0])
+	r.values = r.values[1:]
+	return nil
+}
@@ -0,0 +1,124 @@
+package groupdb
+
+import (
+	"database/sql"
+	"time"
+	"uyulala/internal/db"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jmoiron/sqlx"
+	"gitlab.com/daedaluz/gindb"
+)
+
+// Group is a named set of users, provisioned with SCIM.
+type Group struct {
+	ID          string         `db:"id"`
+	DisplayName string         `db:"display_name"`
+	ExternalID  sql.NullString `db:"external_id"`
+	Created     time.Time      `db:"created"`
+	Modified    time.Time      `db:"modified"`
+	Members     []Member
+}
+
+// Member is a user in a group.
+type Member struct {
+	UserID   string         `db:"user_id"`
+	UserName sql.NullString `db:"user_name"`
+}
+
+// Filter selects groups by display name and external id, an empty field matches every group.
+type Filter struct {
+	DisplayName string
+	ExternalID  string
+}
+
+func (f *Filter) args() []any {
+	return []any{
+		sql.NullString{String: f.DisplayName, Valid: f.DisplayName != ""},
+		sql.NullString{String: f.ExternalID, Valid: f.ExternalID != ""},
+	}
+}
+
+func Get(ctx *gin.Context, groupID string) (*Group, error) {
+	tx := gindb.GetTX(ctx)
+	group := &Group{}
+	if err := tx.Get(group, `call get_group(?)`, groupID); err != nil {
+		return nil, err
+	}
+	if err := tx.Select(&group.Members, `call get_group_members(?)`, groupID); err != nil {
+		return nil, err
+	}
+	return group, nil
+}
+
+func Count(ctx *gin.Context, filter *Filter) (int, error) {
+	tx := gindb.GetTX(ctx)
+	var count int
+	if err := tx.Get(&count, `call count_groups(?, ?)`, filter.args()...); err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+// List returns at most count groups matching filter from offset with their members, in the order they were created.
+func List(ctx *gin.Context, filter *Filter, offset, count int) ([]*Group, error) {
+	tx := gindb.GetTX(ctx)
+	groups := make([]*Group, 0)
+	args := append(filter.args(), offset, count)
+	if err := tx.Select(&groups, `call list_groups(?, ?, ?, ?)`, args...); err != nil {
+		return nil, err
+	}
+	for _, group := range groups {
+		if err := tx.Select(&group.Members, `call get_group_members(?)`, group.ID); err != nil {
+			return nil, err
+		}
+	}
+	return groups, nil
+}
+
+// ListForUser returns the groups the user is a member of, without their members.
+func ListForUser(ctx *gin.Context, userID string) ([]*Group, error) {
+	tx := gindb.GetTX(ctx)
+	groups := make([]*Group, 0)
+	if err := tx.Select(&groups, `call get_user_groups(?)`, userID); err != nil {
+		return nil, err
+	}
+	return groups, nil
+}
+
+// Create stores a new group with its members, group.ID is set to the id of the new group.
+func Create(ctx *gin.Context, group *Group) error {
+	tx := gindb.GetTX(ctx)
+	group.ID = db.GenerateUUID()
+	if _, err := tx.Exec(`call create_group(?, ?, ?)`, group.ID, group.DisplayName, group.ExternalID); err != nil {
+		return err
+	}
+	return createMembers(tx, group)
+}
+
+// Update replaces the attributes and the members of a group.
+func Update(ctx *gin.Context, group *Group) error {
+	tx := gindb.GetTX(ctx)
+	if _, err := tx.Exec(`call update_group(?, ?, ?)`, group.ID, group.DisplayName, group.ExternalID); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`call delete_group_members(?)`, group.ID); err != nil {
+		return err
+	}
+	return createMembers(tx, group)
+}
+
+func Delete(ctx *gin.Context, groupID string) error {
+	tx := gindb.GetTX(ctx)
+	_, err := tx.Exec(`call delete_group(?)`, groupID)
+	return err
+}
+
+func createMembers(tx *sqlx.Tx, group *Group) error {
+	for _, member := range group.Members {
+		if _, err := tx.Exec(`call create_group_member(?, ?)`, group.ID, member.UserID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -5,8 +5,19 @@ import (
 	"crypto/rand"
 	"encoding/gob"
 	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
 
+	"github.com/gin-gonic/gin"
+	"github.com/go-sql-driver/mysql"
 	"github.com/google/uuid"
+	"gitlab.com/daedaluz/gindb"
+)
+
+const (
+	errDuplicateEntry   = 1062
+	errNoReferencedRow2 = 1452
 )
 
 func GobEncodeData(data any) ([]byte, error) {
@@ -41,3 +52,28 @@ func GenerateID(length int) string {
 	uid := hex.EncodeToString(data)
 	return uid
 }
+
+// IsDuplicate reports whether err is the violation of a unique key.
+func IsDuplicate(err error) bool {
+	var mysqlErr *mysql.MySQLError
+	return errors.As(err, &mysqlErr) && mysqlErr.Number == errDuplicateEntry
+}
+
+// IsMissingReference reports whether err is the violation of a foreign key, a row referring to a missing row.
+func IsMissingReference(err error) bool {
+	var mysqlErr *mysql.MySQLError
+	return errors.As(err, &mysqlErr) && mysqlErr.Number == errNoReferencedRow2
+}
+
+// UseID records the one-time identifier id, like the jti of a JWT, for key until it expires with procedure, which
+// is called as procedure(key, id, expire). It returns false if id has already been used with key.
+// The id is recorded outside the request transaction, so that it stays used when the request fails and the JWT can't
+// be replayed by a request that rolls back.
+func UseID(ctx *gin.Context, procedure, key, id string, expire time.Time) (bool, error) {
+	var inserted int64
+	conn := gindb.GetConnection(ctx)
+	if err := conn.Get(&inserted, fmt.Sprintf(`call %s(?, ?, ?)`, procedure), key, id, expire); err != nil {
+		return false, err
+	}
+	return inserted == 1, nil
+}
@@ -0,0 +1,13 @@
+package keydb
+
+import (
+	"time"
+	"uyulala/internal/db"
+
+	"github.com/gin-gonic/gin"
+)
+
+// UseProofID records the jti of a DPoP proof signed by the key with thumbprint jkt until it expires, see db.UseID.
+func UseProofID(c *gin.Context, jkt, jti string, expire time.Time) (bool, error) {
+	return db.UseID(c, "use_dpop_proof_id", jkt, jti, expire)
+}
@@ -95,7 +95,7 @@ func GetFirstWithAlg(c *gin.Context, alg string) (*ServerKey, error) {
 	tx := gindb.GetTX(c)
 	res := &ServerKey{}
 	row := tx.QueryRowx(`call get_server_key_with_alg(?)`, alg)
-	err := row.Scan(&res)
+	err := row.StructScan(res)
 	if err != nil {
 		return nil, err
 	}
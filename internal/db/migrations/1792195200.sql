@@ -0,0 +1,6 @@
+/******* CIBA NOTIFICATIONS *******/
+
+CREATE OR REPLACE PROCEDURE get_ciba_request_id(IN challenge_id VARCHAR(36))
+BEGIN
+    SELECT request_id FROM challenge_ciba_request_ids c WHERE c.challenge_id = challenge_id;
+END;
@@ -0,0 +1,18 @@
+/******* RP-INITIATED LOGOUT *******/
+
+CREATE OR REPLACE TABLE application_post_logout_redirect_urls
+(
+    application_id VARCHAR(36)  NOT NULL,
+    url            VARCHAR(250) NOT NULL,
+    CONSTRAINT FOREIGN KEY application_post_logout_redirect_urls_application_id (application_id) REFERENCES applications (id) ON DELETE CASCADE
+);
+
+CREATE OR REPLACE PROCEDURE create_app_post_logout_redirect_url(IN app_id VARCHAR(36), IN url VARCHAR(250))
+BEGIN
+    INSERT INTO application_post_logout_redirect_urls(application_id, url) VALUES (app_id, url);
+END;
+
+CREATE OR REPLACE PROCEDURE get_app_post_logout_redirect_urls(IN app_id VARCHAR(36))
+BEGIN
+    SELECT url FROM application_post_logout_redirect_urls a WHERE a.application_id = app_id;
+END;
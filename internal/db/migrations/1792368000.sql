@@ -0,0 +1,38 @@
+/******* BACK-CHANNEL LOGOUT *******/
+
+ALTER TABLE applications
+    ADD COLUMN backchannel_logout_uri VARCHAR(2048) NOT NULL DEFAULT '';
+
+CREATE OR REPLACE PROCEDURE create_app(IN app_id VARCHAR(36), IN secret VARCHAR(36), IN app_name VARCHAR(100),
+                                       IN description VARCHAR(250), IN icon VARCHAR(1024),
+                                       IN ciba_mode VARCHAR(20),
+                                       IN notification_endpoint VARCHAR(2048),
+                                       IN alg ENUM ('ES256', 'ES384', 'ES512', 'RS256', 'RS384', 'RS512'),
+                                       IN kid VARCHAR(16), IN is_admin BOOLEAN,
+                                       IN backchannel_logout_uri VARCHAR(2048))
+BEGIN
+    INSERT INTO applications (id, name, secret, description, icon, alg, kid, is_admin, ciba_mode, notification_endpoint,
+                              backchannel_logout_uri)
+    VALUES (app_id, app_name, secret, description, icon, alg, kid, is_admin, ciba_mode, notification_endpoint,
+            backchannel_logout_uri);
+    SELECT app_id, secret;
+END;
+
+CREATE OR REPLACE PROCEDURE get_app(IN app_id VARCHAR(36))
+BEGIN
+    SELECT id,
+           created,
+           name,
+           secret,
+           description,
+           icon,
+           ciba_mode,
+           notification_endpoint,
+           backchannel_logout_uri,
+           is_admin,
+           alg,
+           kid
+    FROM applications
+    WHERE id = app_id
+    LIMIT 1;
+END;
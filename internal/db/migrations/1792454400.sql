@@ -0,0 +1,19 @@
+/******* CLIENT CREDENTIALS *******/
+
+CREATE OR REPLACE TABLE application_scopes
+(
+    application_id VARCHAR(36)  NOT NULL,
+    scope          VARCHAR(250) NOT NULL,
+    PRIMARY KEY (application_id, scope),
+    CONSTRAINT FOREIGN KEY application_scopes_application_id (application_id) REFERENCES applications (id) ON DELETE CASCADE
+);
+
+CREATE OR REPLACE PROCEDURE create_app_scope(IN app_id VARCHAR(36), IN scope VARCHAR(250))
+BEGIN
+    INSERT INTO application_scopes(application_id, scope) VALUES (app_id, scope);
+END;
+
+CREATE OR REPLACE PROCEDURE get_app_scopes(IN app_id VARCHAR(36))
+BEGIN
+    SELECT scope FROM application_scopes a WHERE a.application_id = app_id;
+END;
@@ -0,0 +1,62 @@
+/******* DEVICE AUTHORIZATION *******/
+
+CREATE OR REPLACE TABLE challenge_device_codes
+(
+    device_code   VARCHAR(36) PRIMARY KEY,
+    user_code     VARCHAR(16) NOT NULL UNIQUE,
+    challenge_id  VARCHAR(36) NOT NULL,
+    poll_interval INT         NOT NULL DEFAULT 5,
+    last_poll     DATETIME,
+    CONSTRAINT FOREIGN KEY challenge_device_codes_challenge_id (challenge_id) REFERENCES challenges (id) ON DELETE CASCADE
+);
+
+CREATE OR REPLACE PROCEDURE create_device_code(IN device_code VARCHAR(36), IN user_code VARCHAR(16),
+                                               IN challenge_id VARCHAR(36), IN poll_interval INT)
+BEGIN
+    INSERT INTO challenge_device_codes(device_code, user_code, challenge_id, poll_interval)
+    VALUES (device_code, user_code, challenge_id, poll_interval);
+END;
+
+CREATE OR REPLACE PROCEDURE get_device_code(IN device_code VARCHAR(36))
+BEGIN
+    SELECT device_code, user_code, challenge_id, poll_interval, last_poll
+    FROM challenge_device_codes c
+    WHERE c.device_code = device_code;
+END;
+
+CREATE OR REPLACE PROCEDURE poll_device_code(IN device_code VARCHAR(36), IN poll_interval INT)
+BEGIN
+    UPDATE challenge_device_codes c
+    SET c.last_poll     = current_timestamp(),
+        c.poll_interval = poll_interval
+    WHERE c.device_code = device_code;
+END;
+
+CREATE OR REPLACE PROCEDURE delete_device_code(IN device_code VARCHAR(36))
+BEGIN
+    DELETE FROM challenge_device_codes WHERE challenge_device_codes.device_code = device_code;
+END;
+
+CREATE OR REPLACE PROCEDURE get_challenge_by_user_code(IN user_code VARCHAR(16))
+BEGIN
+    SELECT created,
+           id,
+           type,
+           app_id,
+           expire,
+           public_data,
+           private_data,
+           signature_text,
+           signature_data,
+           nonce,
+           signature,
+           credential,
+           signed,
+           redirect_url,
+           oauth2_context,
+           status,
+           secret
+    FROM challenge_device_codes AS c
+             RIGHT JOIN challenges AS c2 on c.challenge_id = c2.id
+    WHERE c.user_code = user_code;
+END;
@@ -0,0 +1,68 @@
+/******* PUSHED AUTHORIZATION REQUESTS *******/
+
+ALTER TABLE applications
+    ADD COLUMN require_par BOOLEAN NOT NULL DEFAULT FALSE;
+
+CREATE OR REPLACE TABLE pushed_authorization_requests
+(
+    id         VARCHAR(64) PRIMARY KEY,
+    app_id     VARCHAR(36) NOT NULL,
+    parameters TEXT        NOT NULL,
+    expire     DATETIME    NOT NULL,
+    CONSTRAINT FOREIGN KEY pushed_authorization_requests_app_id (app_id) REFERENCES applications (id) ON DELETE CASCADE
+);
+
+CREATE OR REPLACE PROCEDURE create_pushed_request(IN request_id VARCHAR(64), IN app_id VARCHAR(36),
+                                                  IN parameters TEXT, IN expire DATETIME)
+BEGIN
+    INSERT INTO pushed_authorization_requests(id, app_id, parameters, expire)
+    VALUES (request_id, app_id, parameters, expire);
+END;
+
+CREATE OR REPLACE PROCEDURE get_pushed_request(IN request_id VARCHAR(64))
+BEGIN
+    SELECT id, app_id, parameters, expire
+    FROM pushed_authorization_requests
+    WHERE id = request_id;
+END;
+
+CREATE OR REPLACE PROCEDURE delete_pushed_request(IN request_id VARCHAR(64))
+BEGIN
+    DELETE FROM pushed_authorization_requests WHERE id = request_id OR expire < current_timestamp();
+END;
+
+CREATE OR REPLACE PROCEDURE create_app(IN app_id VARCHAR(36), IN secret VARCHAR(36), IN app_name VARCHAR(100),
+                                       IN description VARCHAR(250), IN icon VARCHAR(1024),
+                                       IN ciba_mode VARCHAR(20),
+                                       IN notification_endpoint VARCHAR(2048),
+                                       IN alg ENUM ('ES256', 'ES384', 'ES512', 'RS256', 'RS384', 'RS512'),
+                                       IN kid VARCHAR(16), IN is_admin BOOLEAN,
+                                       IN backchannel_logout_uri VARCHAR(2048),
+                                       IN require_par BOOLEAN)
+BEGIN
+    INSERT INTO applications (id, name, secret, description, icon, alg, kid, is_admin, ciba_mode, notification_endpoint,
+                              backchannel_logout_uri, require_par)
+    VALUES (app_id, app_name, secret, description, icon, alg, kid, is_admin, ciba_mode, notification_endpoint,
+            backchannel_logout_uri, require_par);
+    SELECT app_id, secret;
+END;
+
+CREATE OR REPLACE PROCEDURE get_app(IN app_id VARCHAR(36))
+BEGIN
+    SELECT id,
+           created,
+           name,
+           secret,
+           description,
+           icon,
+           ciba_mode,
+           notification_endpoint,
+           backchannel_logout_uri,
+           require_par,
+           is_admin,
+           alg,
+           kid
+    FROM applications
+    WHERE id = app_id
+    LIMIT 1;
+END;
@@ -0,0 +1,60 @@
+/******* CLIENT ASSERTIONS *******/
+
+ALTER TABLE applications
+    ADD COLUMN token_endpoint_auth_method VARCHAR(32) NOT NULL DEFAULT '';
+
+CREATE OR REPLACE TABLE application_assertion_ids
+(
+    app_id VARCHAR(36)  NOT NULL,
+    jti    VARCHAR(255) NOT NULL,
+    expire DATETIME     NOT NULL,
+    PRIMARY KEY (app_id, jti),
+    CONSTRAINT FOREIGN KEY application_assertion_ids_app_id (app_id) REFERENCES applications (id) ON DELETE CASCADE
+);
+
+CREATE OR REPLACE PROCEDURE use_app_assertion_id(IN app_id VARCHAR(36), IN jti VARCHAR(255), IN expire DATETIME)
+BEGIN
+    DELETE FROM application_assertion_ids WHERE application_assertion_ids.expire < current_timestamp();
+    INSERT IGNORE INTO application_assertion_ids(app_id, jti, expire) VALUES (app_id, jti, expire);
+    SELECT row_count();
+END;
+
+CREATE OR REPLACE PROCEDURE create_app(IN app_id VARCHAR(36), IN secret VARCHAR(36), IN app_name VARCHAR(100),
+                                       IN description VARCHAR(250), IN icon VARCHAR(1024),
+                                       IN ciba_mode VARCHAR(20),
+                                       IN notification_endpoint VARCHAR(2048),
+                                       IN alg ENUM ('ES256', 'ES384', 'ES512', 'RS256', 'RS384', 'RS512'),
+                                       IN kid VARCHAR(16), IN is_admin BOOLEAN,
+                                       IN backchannel_logout_uri VARCHAR(2048),
+                                       IN require_par BOOLEAN, IN jwks TEXT, IN jwks_uri VARCHAR(2048),
+                                       IN token_endpoint_auth_method VARCHAR(32))
+BEGIN
+    INSERT INTO applications (id, name, secret, description, icon, alg, kid, is_admin, ciba_mode, notification_endpoint,
+                              backchannel_logout_uri, require_par, jwks, jwks_uri, token_endpoint_auth_method)
+    VALUES (app_id, app_name, secret, description, icon, alg, kid, is_admin, ciba_mode, notification_endpoint,
+            backchannel_logout_uri, require_par, jwks, jwks_uri, token_endpoint_auth_method);
+    SELECT app_id, secret;
+END;
+
+CREATE OR REPLACE PROCEDURE get_app(IN app_id VARCHAR(36))
+BEGIN
+    SELECT id,
+           created,
+           name,
+           secret,
+           description,
+           icon,
+           ciba_mode,
+           notification_endpoint,
+           backchannel_logout_uri,
+           require_par,
+           jwks,
+           jwks_uri,
+           token_endpoint_auth_method,
+           is_admin,
+           alg,
+           kid
+    FROM applications
+    WHERE id = app_id
+    LIMIT 1;
+END;
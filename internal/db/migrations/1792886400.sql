@@ -0,0 +1,51 @@
+/******* MUTUAL TLS *******/
+
+ALTER TABLE applications
+    ADD COLUMN tls_client_auth_subject_dn VARCHAR(1024) NOT NULL DEFAULT '',
+    ADD COLUMN tls_client_thumbprint      VARCHAR(64)   NOT NULL DEFAULT '';
+
+CREATE OR REPLACE PROCEDURE create_app(IN app_id VARCHAR(36), IN secret VARCHAR(36), IN app_name VARCHAR(100),
+                                       IN description VARCHAR(250), IN icon VARCHAR(1024),
+                                       IN ciba_mode VARCHAR(20),
+                                       IN notification_endpoint VARCHAR(2048),
+                                       IN alg ENUM ('ES256', 'ES384', 'ES512', 'RS256', 'RS384', 'RS512'),
+                                       IN kid VARCHAR(16), IN is_admin BOOLEAN,
+                                       IN backchannel_logout_uri VARCHAR(2048),
+                                       IN require_par BOOLEAN, IN jwks TEXT, IN jwks_uri VARCHAR(2048),
+                                       IN token_endpoint_auth_method VARCHAR(32),
+                                       IN tls_client_auth_subject_dn VARCHAR(1024),
+                                       IN tls_client_thumbprint VARCHAR(64))
+BEGIN
+    INSERT INTO applications (id, name, secret, description, icon, alg, kid, is_admin, ciba_mode, notification_endpoint,
+                              backchannel_logout_uri, require_par, jwks, jwks_uri, token_endpoint_auth_method,
+                              tls_client_auth_subject_dn, tls_client_thumbprint)
+    VALUES (app_id, app_name, secret, description, icon, alg, kid, is_admin, ciba_mode, notification_endpoint,
+            backchannel_logout_uri, require_par, jwks, jwks_uri, token_endpoint_auth_method,
+            tls_client_auth_subject_dn, tls_client_thumbprint);
+    SELECT app_id, secret;
+END;
+
+CREATE OR REPLACE PROCEDURE get_app(IN app_id VARCHAR(36))
+BEGIN
+    SELECT id,
+           created,
+           name,
+           secret,
+           description,
+           icon,
+           ciba_mode,
+           notification_endpoint,
+           backchannel_logout_uri,
+           require_par,
+           jwks,
+           jwks_uri,
+           token_endpoint_auth_method,
+           tls_client_auth_subject_dn,
+           tls_client_thumbprint,
+           is_admin,
+           alg,
+           kid
+    FROM applications
+    WHERE id = app_id
+    LIMIT 1;
+END;
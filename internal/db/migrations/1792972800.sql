@@ -0,0 +1,16 @@
+/******* DPOP *******/
+
+CREATE OR REPLACE TABLE dpop_proof_ids
+(
+    jkt    VARCHAR(64)  NOT NULL,
+    jti    VARCHAR(255) NOT NULL,
+    expire DATETIME     NOT NULL,
+    PRIMARY KEY (jkt, jti)
+);
+
+CREATE OR REPLACE PROCEDURE use_dpop_proof_id(IN jkt VARCHAR(64), IN jti VARCHAR(255), IN expire DATETIME)
+BEGIN
+    DELETE FROM dpop_proof_ids WHERE dpop_proof_ids.expire < current_timestamp();
+    INSERT IGNORE INTO dpop_proof_ids(jkt, jti, expire) VALUES (jkt, jti, expire);
+    SELECT row_count();
+END;
@@ -0,0 +1,85 @@
+/******* DYNAMIC CLIENT REGISTRATION *******/
+
+ALTER TABLE applications
+    ADD COLUMN registration_token_hash VARCHAR(64) NOT NULL DEFAULT '';
+
+CREATE OR REPLACE PROCEDURE update_app(IN app_id VARCHAR(36), IN app_name VARCHAR(100),
+                                       IN description VARCHAR(250), IN icon VARCHAR(1024),
+                                       IN ciba_mode VARCHAR(20),
+                                       IN notification_endpoint VARCHAR(2048),
+                                       IN alg ENUM ('ES256', 'ES384', 'ES512', 'RS256', 'RS384', 'RS512'),
+                                       IN kid VARCHAR(16),
+                                       IN backchannel_logout_uri VARCHAR(2048),
+                                       IN require_par BOOLEAN, IN jwks TEXT, IN jwks_uri VARCHAR(2048),
+                                       IN token_endpoint_auth_method VARCHAR(32),
+                                       IN tls_client_auth_subject_dn VARCHAR(1024),
+                                       IN tls_client_thumbprint VARCHAR(64))
+BEGIN
+    UPDATE applications a
+    SET a.name                       = app_name,
+        a.description                = description,
+        a.icon                       = icon,
+        a.ciba_mode                  = ciba_mode,
+        a.notification_endpoint      = notification_endpoint,
+        a.alg                        = alg,
+        a.kid                        = kid,
+        a.backchannel_logout_uri     = backchannel_logout_uri,
+        a.require_par                = require_par,
+        a.jwks                       = jwks,
+        a.jwks_uri                   = jwks_uri,
+        a.token_endpoint_auth_method = token_endpoint_auth_method,
+        a.tls_client_auth_subject_dn = tls_client_auth_subject_dn,
+        a.tls_client_thumbprint      = tls_client_thumbprint
+    WHERE a.id = app_id;
+END;
+
+CREATE OR REPLACE PROCEDURE set_app_registration_token(IN app_id VARCHAR(36), IN token_hash VARCHAR(64))
+BEGIN
+    UPDATE applications SET registration_token_hash = token_hash WHERE id = app_id;
+END;
+
+CREATE OR REPLACE PROCEDURE delete_app(IN app_id VARCHAR(36))
+BEGIN
+    DELETE FROM applications WHERE id = app_id;
+END;
+
+CREATE OR REPLACE PROCEDURE delete_app_redirect_urls(IN app_id VARCHAR(36))
+BEGIN
+    DELETE FROM application_redirect_urls WHERE application_id = app_id;
+END;
+
+CREATE OR REPLACE PROCEDURE delete_app_post_logout_redirect_urls(IN app_id VARCHAR(36))
+BEGIN
+    DELETE FROM application_post_logout_redirect_urls WHERE application_id = app_id;
+END;
+
+CREATE OR REPLACE PROCEDURE delete_app_scopes(IN app_id VARCHAR(36))
+BEGIN
+    DELETE FROM application_scopes WHERE application_id = app_id;
+END;
+
+CREATE OR REPLACE PROCEDURE get_app(IN app_id VARCHAR(36))
+BEGIN
+    SELECT id,
+           created,
+           name,
+           secret,
+           description,
+           icon,
+           ciba_mode,
+           notification_endpoint,
+           backchannel_logout_uri,
+           require_par,
+           jwks,
+           jwks_uri,
+           token_endpoint_auth_method,
+           tls_client_auth_subject_dn,
+           tls_client_thumbprint,
+           registration_token_hash,
+           is_admin,
+           alg,
+           kid
+    FROM applications
+    WHERE id = app_id
+    LIMIT 1;
+END;
@@ -0,0 +1,114 @@
+/******* PAIRWISE SUBJECT IDENTIFIERS *******/
+
+ALTER TABLE applications
+    ADD COLUMN subject_type      ENUM ('public', 'pairwise') NOT NULL DEFAULT 'public',
+    ADD COLUMN sector_identifier VARCHAR(255)                NOT NULL DEFAULT '';
+
+CREATE OR REPLACE TABLE pairwise_subjects
+(
+    sector_identifier VARCHAR(255) NOT NULL,
+    subject           VARCHAR(64)  NOT NULL,
+    user_id           VARCHAR(36)  NOT NULL,
+    PRIMARY KEY (sector_identifier, subject),
+    CONSTRAINT FOREIGN KEY pairwise_subjects_user_id (user_id) REFERENCES users (id) ON DELETE CASCADE
+);
+
+CREATE OR REPLACE PROCEDURE create_pairwise_subject(IN sector_identifier VARCHAR(255), IN subject VARCHAR(64),
+                                                    IN user_id VARCHAR(36))
+BEGIN
+    INSERT IGNORE INTO pairwise_subjects(sector_identifier, subject, user_id) VALUES (sector_identifier, subject, user_id);
+END;
+
+CREATE OR REPLACE PROCEDURE get_pairwise_subject_user(IN sector_identifier VARCHAR(255), IN subject VARCHAR(64))
+BEGIN
+    SELECT p.user_id
+    FROM pairwise_subjects p
+    WHERE p.sector_identifier = sector_identifier
+      AND p.subject = subject
+    LIMIT 1;
+END;
+
+CREATE OR REPLACE PROCEDURE create_app(IN app_id VARCHAR(36), IN secret VARCHAR(36), IN app_name VARCHAR(100),
+                                       IN description VARCHAR(250), IN icon VARCHAR(1024),
+                                       IN ciba_mode VARCHAR(20),
+                                       IN notification_endpoint VARCHAR(2048),
+                                       IN alg ENUM ('ES256', 'ES384', 'ES512', 'RS256', 'RS384', 'RS512'),
+                                       IN kid VARCHAR(16), IN is_admin BOOLEAN,
+                                       IN backchannel_logout_uri VARCHAR(2048),
+                                       IN require_par BOOLEAN, IN jwks TEXT, IN jwks_uri VARCHAR(2048),
+                                       IN token_endpoint_auth_method VARCHAR(32),
+                                       IN tls_client_auth_subject_dn VARCHAR(1024),
+                                       IN tls_client_thumbprint VARCHAR(64),
+                                       IN subject_type ENUM ('public', 'pairwise'),
+                                       IN sector_identifier VARCHAR(255))
+BEGIN
+    INSERT INTO applications (id, name, secret, description, icon, alg, kid, is_admin, ciba_mode, notification_endpoint,
+                              backchannel_logout_uri, require_par, jwks, jwks_uri, token_endpoint_auth_method,
+                              tls_client_auth_subject_dn, tls_client_thumbprint, subject_type, sector_identifier)
+    VALUES (app_id, app_name, secret, description, icon, alg, kid, is_admin, ciba_mode, notification_endpoint,
+            backchannel_logout_uri, require_par, jwks, jwks_uri, token_endpoint_auth_method,
+            tls_client_auth_subject_dn, tls_client_thumbprint, subject_type, sector_identifier);
+    SELECT app_id, secret;
+END;
+
+CREATE OR REPLACE PROCEDURE update_app(IN app_id VARCHAR(36), IN app_name VARCHAR(100),
+                                       IN description VARCHAR(250), IN icon VARCHAR(1024),
+                                       IN ciba_mode VARCHAR(20),
+                                       IN notification_endpoint VARCHAR(2048),
+                                       IN alg ENUM ('ES256', 'ES384', 'ES512', 'RS256', 'RS384', 'RS512'),
+                                       IN kid VARCHAR(16),
+                                       IN backchannel_logout_uri VARCHAR(2048),
+                                       IN require_par BOOLEAN, IN jwks TEXT, IN jwks_uri VARCHAR(2048),
+                                       IN token_endpoint_auth_method VARCHAR(32),
+                                       IN tls_client_auth_subject_dn VARCHAR(1024),
+                                       IN tls_client_thumbprint VARCHAR(64),
+                                       IN subject_type ENUM ('public', 'pairwise'),
+                                       IN sector_identifier VARCHAR(255))
+BEGIN
+    UPDATE applications a
+    SET a.name                       = app_name,
+        a.description                = description,
+        a.icon                       = icon,
+        a.ciba_mode                  = ciba_mode,
+        a.notification_endpoint      = notification_endpoint,
+        a.alg                        = alg,
+        a.kid                        = kid,
+        a.backchannel_logout_uri     = backchannel_logout_uri,
+        a.require_par                = require_par,
+        a.jwks                       = jwks,
+        a.jwks_uri                   = jwks_uri,
+        a.token_endpoint_auth_method = token_endpoint_auth_method,
+        a.tls_client_auth_subject_dn = tls_client_auth_subject_dn,
+        a.tls_client_thumbprint      = tls_client_thumbprint,
+        a.subject_type               = subject_type,
+        a.sector_identifier          = sector_identifier
+    WHERE a.id = app_id;
+END;
+
+CREATE OR REPLACE PROCEDURE get_app(IN app_id VARCHAR(36))
+BEGIN
+    SELECT id,
+           created,
+           name,
+           secret,
+           description,
+           icon,
+           ciba_mode,
+           notification_endpoint,
+           backchannel_logout_uri,
+           require_par,
+           jwks,
+           jwks_uri,
+           token_endpoint_auth_method,
+           tls_client_auth_subject_dn,
+           tls_client_thumbprint,
+           registration_token_hash,
+           subject_type,
+           sector_identifier,
+           is_admin,
+           alg,
+           kid
+    FROM applications
+    WHERE id = app_id
+    LIMIT 1;
+END;
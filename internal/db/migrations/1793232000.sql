@@ -0,0 +1,24 @@
+/******* RESPONSE TYPES *******/
+
+CREATE OR REPLACE TABLE application_response_types
+(
+    application_id VARCHAR(36) NOT NULL,
+    response_type  VARCHAR(64) NOT NULL,
+    PRIMARY KEY (application_id, response_type),
+    CONSTRAINT FOREIGN KEY application_response_types_application_id (application_id) REFERENCES applications (id) ON DELETE CASCADE
+);
+
+CREATE OR REPLACE PROCEDURE create_app_response_type(IN app_id VARCHAR(36), IN response_type VARCHAR(64))
+BEGIN
+    INSERT INTO application_response_types(application_id, response_type) VALUES (app_id, response_type);
+END;
+
+CREATE OR REPLACE PROCEDURE get_app_response_types(IN app_id VARCHAR(36))
+BEGIN
+    SELECT response_type FROM application_response_types a WHERE a.application_id = app_id;
+END;
+
+CREATE OR REPLACE PROCEDURE delete_app_response_types(IN app_id VARCHAR(36))
+BEGIN
+    DELETE FROM application_response_types WHERE application_id = app_id;
+END;
@@ -0,0 +1,112 @@
+/******* ENCRYPTED ID TOKENS AND USERINFO *******/
+
+ALTER TABLE applications
+    ADD COLUMN id_token_encrypted_response_alg VARCHAR(32) NOT NULL DEFAULT '',
+    ADD COLUMN id_token_encrypted_response_enc VARCHAR(32) NOT NULL DEFAULT '',
+    ADD COLUMN userinfo_encrypted_response_alg VARCHAR(32) NOT NULL DEFAULT '',
+    ADD COLUMN userinfo_encrypted_response_enc VARCHAR(32) NOT NULL DEFAULT '';
+
+CREATE OR REPLACE PROCEDURE create_app(IN app_id VARCHAR(36), IN secret VARCHAR(36), IN app_name VARCHAR(100),
+                                       IN description VARCHAR(250), IN icon VARCHAR(1024),
+                                       IN ciba_mode VARCHAR(20),
+                                       IN notification_endpoint VARCHAR(2048),
+                                       IN alg ENUM ('ES256', 'ES384', 'ES512', 'RS256', 'RS384', 'RS512'),
+                                       IN kid VARCHAR(16), IN is_admin BOOLEAN,
+                                       IN backchannel_logout_uri VARCHAR(2048),
+                                       IN require_par BOOLEAN, IN jwks TEXT, IN jwks_uri VARCHAR(2048),
+                                       IN token_endpoint_auth_method VARCHAR(32),
+                                       IN tls_client_auth_subject_dn VARCHAR(1024),
+                                       IN tls_client_thumbprint VARCHAR(64),
+                                       IN subject_type ENUM ('public', 'pairwise'),
+                                       IN sector_identifier VARCHAR(255),
+                                       IN id_token_encrypted_response_alg VARCHAR(32),
+                                       IN id_token_encrypted_response_enc VARCHAR(32),
+                                       IN userinfo_encrypted_response_alg VARCHAR(32),
+                                       IN userinfo_encrypted_response_enc VARCHAR(32))
+BEGIN
+    INSERT INTO applications (id, name, secret, description, icon, alg, kid, is_admin, ciba_mode, notification_endpoint,
+                              backchannel_logout_uri, require_par, jwks, jwks_uri, token_endpoint_auth_method,
+                              tls_client_auth_subject_dn, tls_client_thumbprint, subject_type, sector_identifier,
+                              id_token_encrypted_response_alg, id_token_encrypted_response_enc,
+                              userinfo_encrypted_response_alg, userinfo_encrypted_response_enc)
+    VALUES (app_id, app_name, secret, description, icon, alg, kid, is_admin, ciba_mode, notification_endpoint,
+            backchannel_logout_uri, require_par, jwks, jwks_uri, token_endpoint_auth_method,
+            tls_client_auth_subject_dn, tls_client_thumbprint, subject_type, sector_identifier,
+            id_token_encrypted_response_alg, id_token_encrypted_response_enc,
+            userinfo_encrypted_response_alg, userinfo_encrypted_response_enc);
+    SELECT app_id, secret;
+END;
+
+CREATE OR REPLACE PROCEDURE update_app(IN app_id VARCHAR(36), IN app_name VARCHAR(100),
+                                       IN description VARCHAR(250), IN icon VARCHAR(1024),
+                                       IN ciba_mode VARCHAR(20),
+                                       IN notification_endpoint VARCHAR(2048),
+                                       IN alg ENUM ('ES256', 'ES384', 'ES512', 'RS256', 'RS384', 'RS512'),
+                                       IN kid VARCHAR(16),
+                                       IN backchannel_logout_uri VARCHAR(2048),
+                                       IN require_par BOOLEAN, IN jwks TEXT, IN jwks_uri VARCHAR(2048),
+                                       IN token_endpoint_auth_method VARCHAR(32),
+                                       IN tls_client_auth_subject_dn VARCHAR(1024),
+                                       IN tls_client_thumbprint VARCHAR(64),
+                                       IN subject_type ENUM ('public', 'pairwise'),
+                                       IN sector_identifier VARCHAR(255),
+                                       IN id_token_encrypted_response_alg VARCHAR(32),
+                                       IN id_token_encrypted_response_enc VARCHAR(32),
+                                       IN userinfo_encrypted_response_alg VARCHAR(32),
+                                       IN userinfo_encrypted_response_enc VARCHAR(32))
+BEGIN
+    UPDATE applications a
+    SET a.name                            = app_name,
+        a.description                     = description,
+        a.icon                            = icon,
+        a.ciba_mode                       = ciba_mode,
+        a.notification_endpoint           = notification_endpoint,
+        a.alg                             = alg,
+        a.kid                             = kid,
+        a.backchannel_logout_uri          = backchannel_logout_uri,
+        a.require_par                     = require_par,
+        a.jwks                            = jwks,
+        a.jwks_uri                        = jwks_uri,
+        a.token_endpoint_auth_method      = token_endpoint_auth_method,
+        a.tls_client_auth_subject_dn      = tls_client_auth_subject_dn,
+        a.tls_client_thumbprint           = tls_client_thumbprint,
+        a.subject_type                    = subject_type,
+        a.sector_identifier               = sector_identifier,
+        a.id_token_encrypted_response_alg = id_token_encrypted_response_alg,
+        a.id_token_encrypted_response_enc = id_token_encrypted_response_enc,
+        a.userinfo_encrypted_response_alg = userinfo_encrypted_response_alg,
+        a.userinfo_encrypted_response_enc = userinfo_encrypted_response_enc
+    WHERE a.id = app_id;
+END;
+
+CREATE OR REPLACE PROCEDURE get_app(IN app_id VARCHAR(36))
+BEGIN
+    SELECT id,
+           created,
+           name,
+           secret,
+           description,
+           icon,
+           ciba_mode,
+           notification_endpoint,
+           backchannel_logout_uri,
+           require_par,
+           jwks,
+           jwks_uri,
+           token_endpoint_auth_method,
+           tls_client_auth_subject_dn,
+           tls_client_thumbprint,
+           registration_token_hash,
+           subject_type,
+           sector_identifier,
+           id_token_encrypted_response_alg,
+           id_token_encrypted_response_enc,
+           userinfo_encrypted_response_alg,
+           userinfo_encrypted_response_enc,
+           is_admin,
+           alg,
+           kid
+    FROM applications
+    WHERE id = app_id
+    LIMIT 1;
+END;
@@ -0,0 +1,101 @@
+/******* TOKEN POLICY *******/
+
+ALTER TABLE applications
+    ADD COLUMN access_token_length  INT UNSIGNED NOT NULL DEFAULT 0,
+    ADD COLUMN id_token_length      INT UNSIGNED NOT NULL DEFAULT 0,
+    ADD COLUMN refresh_token_length INT UNSIGNED NOT NULL DEFAULT 0;
+
+CREATE OR REPLACE TABLE application_audiences
+(
+    application_id VARCHAR(36)  NOT NULL,
+    audience       VARCHAR(255) NOT NULL,
+    PRIMARY KEY (application_id, audience),
+    CONSTRAINT FOREIGN KEY application_audiences_application_id (application_id) REFERENCES applications (id) ON DELETE CASCADE
+);
+
+CREATE OR REPLACE TABLE application_token_claims
+(
+    application_id VARCHAR(36)                       NOT NULL,
+    token          ENUM ('access_token', 'id_token') NOT NULL,
+    name           VARCHAR(255)                      NOT NULL,
+    value          TEXT                              NOT NULL,
+    PRIMARY KEY (application_id, token, name),
+    CONSTRAINT FOREIGN KEY application_token_claims_application_id (application_id) REFERENCES applications (id) ON DELETE CASCADE
+);
+
+CREATE OR REPLACE PROCEDURE set_app_token_lengths(IN app_id VARCHAR(36), IN access_token_length INT UNSIGNED,
+                                                  IN id_token_length INT UNSIGNED,
+                                                  IN refresh_token_length INT UNSIGNED)
+BEGIN
+    UPDATE applications a
+    SET a.access_token_length  = access_token_length,
+        a.id_token_length      = id_token_length,
+        a.refresh_token_length = refresh_token_length
+    WHERE a.id = app_id;
+END;
+
+CREATE OR REPLACE PROCEDURE create_app_audience(IN app_id VARCHAR(36), IN audience VARCHAR(255))
+BEGIN
+    INSERT INTO application_audiences(application_id, audience) VALUES (app_id, audience);
+END;
+
+CREATE OR REPLACE PROCEDURE get_app_audiences(IN app_id VARCHAR(36))
+BEGIN
+    SELECT audience FROM application_audiences a WHERE a.application_id = app_id;
+END;
+
+CREATE OR REPLACE PROCEDURE delete_app_audiences(IN app_id VARCHAR(36))
+BEGIN
+    DELETE FROM application_audiences WHERE application_id = app_id;
+END;
+
+CREATE OR REPLACE PROCEDURE create_app_token_claim(IN app_id VARCHAR(36), IN token ENUM ('access_token', 'id_token'),
+                                                   IN name VARCHAR(255), IN value TEXT)
+BEGIN
+    INSERT INTO application_token_claims(application_id, token, name, value) VALUES (app_id, token, name, value);
+END;
+
+CREATE OR REPLACE PROCEDURE get_app_token_claims(IN app_id VARCHAR(36))
+BEGIN
+    SELECT token, name, value FROM application_token_claims a WHERE a.application_id = app_id;
+END;
+
+CREATE OR REPLACE PROCEDURE delete_app_token_claims(IN app_id VARCHAR(36))
+BEGIN
+    DELETE FROM application_token_claims WHERE application_id = app_id;
+END;
+
+CREATE OR REPLACE PROCEDURE get_app(IN app_id VARCHAR(36))
+BEGIN
+    SELECT id,
+           created,
+           name,
+           secret,
+           description,
+           icon,
+           ciba_mode,
+           notification_endpoint,
+           backchannel_logout_uri,
+           require_par,
+           jwks,
+           jwks_uri,
+           token_endpoint_auth_method,
+           tls_client_auth_subject_dn,
+           tls_client_thumbprint,
+           registration_token_hash,
+           subject_type,
+           sector_identifier,
+           id_token_encrypted_response_alg,
+           id_token_encrypted_response_enc,
+           userinfo_encrypted_response_alg,
+           userinfo_encrypted_response_enc,
+           access_token_length,
+           id_token_length,
+           refresh_token_length,
+           is_admin,
+           alg,
+           kid
+    FROM applications
+    WHERE id = app_id
+    LIMIT 1;
+END;
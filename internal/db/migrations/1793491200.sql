@@ -0,0 +1,93 @@
+/******* RESOURCE SERVERS *******/
+
+CREATE OR REPLACE TABLE resource_servers
+(
+    id                  VARCHAR(255) PRIMARY KEY,
+    name                VARCHAR(100) NOT NULL DEFAULT '',
+    access_token_length INT UNSIGNED NOT NULL DEFAULT 0,
+    kid                 VARCHAR(16)  NULL     DEFAULT NULL,
+    created             DATETIME     NOT NULL DEFAULT current_timestamp(),
+    CONSTRAINT FOREIGN KEY resource_servers_kid (kid) REFERENCES server_keys (kid) ON DELETE SET NULL
+);
+
+CREATE OR REPLACE TABLE resource_server_scopes
+(
+    resource_id VARCHAR(255) NOT NULL,
+    scope       VARCHAR(255) NOT NULL,
+    PRIMARY KEY (resource_id, scope),
+    CONSTRAINT FOREIGN KEY resource_server_scopes_resource_id (resource_id) REFERENCES resource_servers (id) ON DELETE CASCADE
+);
+
+CREATE OR REPLACE PROCEDURE create_resource_server(IN resource_id VARCHAR(255), IN resource_name VARCHAR(100),
+                                                   IN access_token_length INT UNSIGNED, IN kid VARCHAR(16))
+BEGIN
+    INSERT INTO resource_servers(id, name, access_token_length, kid)
+    VALUES (resource_id, resource_name, access_token_length, NULLIF(kid, ''));
+END;
+
+CREATE OR REPLACE PROCEDURE get_resource_server(IN resource_id VARCHAR(255))
+BEGIN
+    SELECT id, name, access_token_length, COALESCE(kid, '') AS kid, created
+    FROM resource_servers
+    WHERE id = resource_id
+    LIMIT 1;
+END;
+
+CREATE OR REPLACE PROCEDURE list_resource_servers()
+BEGIN
+    SELECT id, name, access_token_length, COALESCE(kid, '') AS kid, created
+    FROM resource_servers
+    ORDER BY id;
+END;
+
+CREATE OR REPLACE PROCEDURE delete_resource_server(IN resource_id VARCHAR(255))
+BEGIN
+    DELETE FROM resource_servers WHERE id = resource_id;
+END;
+
+CREATE OR REPLACE PROCEDURE create_resource_server_scope(IN resource_id VARCHAR(255), IN scope VARCHAR(255))
+BEGIN
+    INSERT INTO resource_server_scopes(resource_id, scope) VALUES (resource_id, scope);
+END;
+
+CREATE OR REPLACE PROCEDURE get_resource_server_scopes(IN resource_id VARCHAR(255))
+BEGIN
+    SELECT scope FROM resource_server_scopes r WHERE r.resource_id = resource_id;
+END;
+
+/******* SESSION RESOURCES *******/
+
+ALTER TABLE sessions
+    ADD COLUMN resources TEXT NOT NULL DEFAULT '';
+
+CREATE OR REPLACE PROCEDURE create_session(IN session_id VARCHAR(16), IN user_id VARCHAR(36), IN app_id VARCHAR(36),
+                                           IN requested_scopes VARCHAR(1024),
+                                           IN expire_at DATETIME, IN resources TEXT)
+BEGIN
+    INSERT INTO sessions(id, user_id, app_id, requested_scopes, expire_at, resources)
+    VALUES (session_id, user_id, app_id, requested_scopes, expire_at, resources);
+END;
+
+CREATE OR REPLACE PROCEDURE get_session(IN session_id VARCHAR(18))
+BEGIN
+    SELECT id, user_id, app_id, requested_scopes, counter, created_at, expire_at, resources
+    FROM sessions
+    WHERE sessions.id = session_id
+      AND (sessions.expire_at > current_timestamp() OR sessions.expire_at IS NULL);
+END;
+
+CREATE OR REPLACE PROCEDURE get_sessions_for_user(IN user_id VARCHAR(36))
+BEGIN
+    SELECT id, user_id, app_id, requested_scopes, counter, created_at, expire_at, resources
+    FROM sessions
+    WHERE sessions.user_id = user_id
+      AND (sessions.expire_at > current_timestamp() OR sessions.expire_at IS NULL);
+END;
+
+CREATE OR REPLACE PROCEDURE list_sessions_for_user(IN user_id VARCHAR(36))
+BEGIN
+    SELECT id, user_id, app_id, requested_scopes, counter, created_at, expire_at, resources
+    FROM sessions
+    WHERE sessions.user_id = user_id
+      AND (sessions.expire_at > current_timestamp() OR sessions.expire_at IS NULL);
+END;
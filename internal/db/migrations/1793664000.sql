@@ -0,0 +1,143 @@
+/******* SCOPE REGISTRY *******/
+
+CREATE OR REPLACE TABLE scopes
+(
+    name        VARCHAR(255) PRIMARY KEY,
+    description VARCHAR(1024) NOT NULL DEFAULT '',
+    created     DATETIME      NOT NULL DEFAULT current_timestamp()
+);
+
+CREATE OR REPLACE TABLE scope_claims
+(
+    scope VARCHAR(255) NOT NULL,
+    claim VARCHAR(255) NOT NULL,
+    PRIMARY KEY (scope, claim),
+    CONSTRAINT FOREIGN KEY scope_claims_scope (scope) REFERENCES scopes (name) ON DELETE CASCADE
+);
+
+CREATE OR REPLACE TABLE scope_applications
+(
+    scope          VARCHAR(255) NOT NULL,
+    application_id VARCHAR(36)  NOT NULL,
+    PRIMARY KEY (scope, application_id),
+    CONSTRAINT FOREIGN KEY scope_applications_scope (scope) REFERENCES scopes (name) ON DELETE CASCADE,
+    CONSTRAINT FOREIGN KEY scope_applications_application_id (application_id) REFERENCES applications (id) ON DELETE CASCADE
+);
+
+CREATE OR REPLACE PROCEDURE create_scope(IN scope_name VARCHAR(255), IN description VARCHAR(1024))
+BEGIN
+    INSERT INTO scopes(name, description) VALUES (scope_name, description);
+END;
+
+CREATE OR REPLACE PROCEDURE get_scope(IN scope_name VARCHAR(255))
+BEGIN
+    SELECT name, description, created FROM scopes WHERE name = scope_name LIMIT 1;
+END;
+
+CREATE OR REPLACE PROCEDURE list_scopes()
+BEGIN
+    SELECT name, description, created FROM scopes ORDER BY name;
+END;
+
+CREATE OR REPLACE PROCEDURE delete_scope(IN scope_name VARCHAR(255))
+BEGIN
+    DELETE FROM scopes WHERE name = scope_name;
+END;
+
+CREATE OR REPLACE PROCEDURE create_scope_claim(IN scope_name VARCHAR(255), IN claim VARCHAR(255))
+BEGIN
+    INSERT INTO scope_claims(scope, claim) VALUES (scope_name, claim);
+END;
+
+CREATE OR REPLACE PROCEDURE get_scope_claims(IN scope_name VARCHAR(255))
+BEGIN
+    SELECT claim FROM scope_claims s WHERE s.scope = scope_name;
+END;
+
+CREATE OR REPLACE PROCEDURE create_scope_app(IN scope_name VARCHAR(255), IN app_id VARCHAR(36))
+BEGIN
+    INSERT INTO scope_applications(scope, application_id) VALUES (scope_name, app_id);
+END;
+
+CREATE OR REPLACE PROCEDURE get_scope_apps(IN scope_name VARCHAR(255))
+BEGIN
+    SELECT application_id FROM scope_applications s WHERE s.scope = scope_name;
+END;
+
+/******* CONSENT *******/
+
+ALTER TABLE applications
+    ADD COLUMN first_party BOOLEAN NOT NULL DEFAULT FALSE;
+
+CREATE OR REPLACE PROCEDURE set_app_first_party(IN app_id VARCHAR(36), IN first_party BOOLEAN)
+BEGIN
+    UPDATE applications a SET a.first_party = first_party WHERE a.id = app_id;
+END;
+
+CREATE OR REPLACE TABLE consents
+(
+    user_id        VARCHAR(36)  NOT NULL,
+    application_id VARCHAR(36)  NOT NULL,
+    scope          VARCHAR(255) NOT NULL,
+    granted        DATETIME     NOT NULL DEFAULT current_timestamp(),
+    PRIMARY KEY (user_id, application_id, scope),
+    CONSTRAINT FOREIGN KEY consents_user_id (user_id) REFERENCES users (id) ON DELETE CASCADE,
+    CONSTRAINT FOREIGN KEY consents_application_id (application_id) REFERENCES applications (id) ON DELETE CASCADE,
+    CONSTRAINT FOREIGN KEY consents_scope (scope) REFERENCES scopes (name) ON DELETE CASCADE
+);
+
+CREATE OR REPLACE PROCEDURE create_consent(IN user_id VARCHAR(36), IN app_id VARCHAR(36), IN scope VARCHAR(255))
+BEGIN
+    INSERT INTO consents(user_id, application_id, scope)
+    VALUES (user_id, app_id, scope)
+    ON DUPLICATE KEY UPDATE granted = current_timestamp();
+END;
+
+CREATE OR REPLACE PROCEDURE list_consents_for_user(IN user_id VARCHAR(36))
+BEGIN
+    SELECT c.user_id, c.application_id, c.scope, c.granted
+    FROM consents c
+    WHERE c.user_id = user_id
+    ORDER BY c.application_id, c.scope;
+END;
+
+CREATE OR REPLACE PROCEDURE delete_consents(IN user_id VARCHAR(36), IN app_id VARCHAR(36))
+BEGIN
+    DELETE FROM consents WHERE consents.user_id = user_id AND consents.application_id = app_id;
+END;
+
+CREATE OR REPLACE PROCEDURE get_app(IN app_id VARCHAR(36))
+BEGIN
+    SELECT id,
+           created,
+           name,
+           secret,
+           description,
+           icon,
+           ciba_mode,
+           notification_endpoint,
+           backchannel_logout_uri,
+           require_par,
+           jwks,
+           jwks_uri,
+           token_endpoint_auth_method,
+           tls_client_auth_subject_dn,
+           tls_client_thumbprint,
+           registration_token_hash,
+           subject_type,
+           sector_identifier,
+           id_token_encrypted_response_alg,
+           id_token_encrypted_response_enc,
+           userinfo_encrypted_response_alg,
+           userinfo_encrypted_response_enc,
+           access_token_length,
+           id_token_length,
+           refresh_token_length,
+           first_party,
+           is_admin,
+           alg,
+           kid
+    FROM applications
+    WHERE id = app_id
+    LIMIT 1;
+END;
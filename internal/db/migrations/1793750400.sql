@@ -0,0 +1,65 @@
+/******* USER ATTRIBUTES *******/
+
+CREATE OR REPLACE TABLE user_attributes
+(
+    user_id VARCHAR(36)  NOT NULL,
+    name    VARCHAR(255) NOT NULL,
+    value   TEXT         NOT NULL,
+    PRIMARY KEY (user_id, name),
+    CONSTRAINT FOREIGN KEY user_attributes_user_id (user_id) REFERENCES users (id) ON DELETE CASCADE
+);
+
+CREATE OR REPLACE PROCEDURE set_user_attribute(IN user_id VARCHAR(36), IN name VARCHAR(255), IN value TEXT)
+BEGIN
+    INSERT INTO user_attributes(user_id, name, value)
+    VALUES (user_id, name, value)
+    ON DUPLICATE KEY UPDATE user_attributes.value = value;
+END;
+
+CREATE OR REPLACE PROCEDURE delete_user_attribute(IN user_id VARCHAR(36), IN name VARCHAR(255))
+BEGIN
+    DELETE FROM user_attributes WHERE user_attributes.user_id = user_id AND user_attributes.name = name;
+END;
+
+CREATE OR REPLACE PROCEDURE get_user_attributes(IN user_id VARCHAR(36))
+BEGIN
+    SELECT name, value FROM user_attributes WHERE user_attributes.user_id = user_id;
+END;
+
+/******* SESSION CLAIMS REQUEST *******/
+
+ALTER TABLE sessions
+    ADD COLUMN claims TEXT NOT NULL DEFAULT '';
+
+CREATE OR REPLACE PROCEDURE create_session(IN session_id VARCHAR(16), IN user_id VARCHAR(36), IN app_id VARCHAR(36),
+                                           IN requested_scopes VARCHAR(1024),
+                                           IN expire_at DATETIME, IN resources TEXT,
+                                           IN authorization_details TEXT, IN claims TEXT)
+BEGIN
+    INSERT INTO sessions(id, user_id, app_id, requested_scopes, expire_at, resources, authorization_details, claims)
+    VALUES (session_id, user_id, app_id, requested_scopes, expire_at, resources, authorization_details, claims);
+END;
+
+CREATE OR REPLACE PROCEDURE get_session(IN session_id VARCHAR(18))
+BEGIN
+    SELECT id, user_id, app_id, requested_scopes, counter, created_at, expire_at, resources, authorization_details, claims
+    FROM sessions
+    WHERE sessions.id = session_id
+      AND (sessions.expire_at > current_timestamp() OR sessions.expire_at IS NULL);
+END;
+
+CREATE OR REPLACE PROCEDURE get_sessions_for_user(IN user_id VARCHAR(36))
+BEGIN
+    SELECT id, user_id, app_id, requested_scopes, counter, created_at, expire_at, resources, authorization_details, claims
+    FROM sessions
+    WHERE sessions.user_id = user_id
+      AND (sessions.expire_at > current_timestamp() OR sessions.expire_at IS NULL);
+END;
+
+CREATE OR REPLACE PROCEDURE list_sessions_for_user(IN user_id VARCHAR(36))
+BEGIN
+    SELECT id, user_id, app_id, requested_scopes, counter, created_at, expire_at, resources, authorization_details, claims
+    FROM sessions
+    WHERE sessions.user_id = user_id
+      AND (sessions.expire_at > current_timestamp() OR sessions.expire_at IS NULL);
+END;
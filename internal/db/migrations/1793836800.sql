@@ -0,0 +1,114 @@
+/******* SCIM PROVISIONING *******/
+
+ALTER TABLE users
+    ADD COLUMN external_id VARCHAR(255) NULL,
+    ADD COLUMN user_name   VARCHAR(255) NULL UNIQUE,
+    ADD COLUMN active      BOOLEAN      NOT NULL DEFAULT TRUE,
+    ADD COLUMN modified    DATETIME     NOT NULL DEFAULT current_timestamp() ON UPDATE current_timestamp();
+
+CREATE OR REPLACE PROCEDURE get_user(IN user_id VARCHAR(36))
+BEGIN
+    SELECT id, created, active FROM users WHERE id = user_id;
+END;
+
+CREATE OR REPLACE PROCEDURE create_provisioned_user(IN user_id VARCHAR(36), IN external_id VARCHAR(255),
+                                                    IN user_name VARCHAR(255), IN active BOOLEAN)
+BEGIN
+    INSERT INTO users(id, created, external_id, user_name, active)
+    VALUES (user_id, current_timestamp(), external_id, user_name, active);
+END;
+
+CREATE OR REPLACE PROCEDURE update_provisioned_user(IN user_id VARCHAR(36), IN external_id VARCHAR(255),
+                                                    IN user_name VARCHAR(255), IN active BOOLEAN)
+BEGIN
+    UPDATE users u
+    SET u.external_id = external_id,
+        u.user_name   = user_name,
+        u.active      = active,
+        u.modified    = current_timestamp()
+    WHERE u.id = user_id;
+END;
+
+CREATE OR REPLACE PROCEDURE get_provisioned_user(IN user_id VARCHAR(36))
+BEGIN
+    SELECT id, external_id, user_name, active, created, modified FROM users WHERE id = user_id;
+END;
+
+CREATE OR REPLACE PROCEDURE list_provisioned_users()
+BEGIN
+    SELECT id, external_id, user_name, active, created, modified FROM users ORDER BY created, id;
+END;
+
+/******* GROUPS *******/
+
+CREATE OR REPLACE TABLE user_groups
+(
+    id           VARCHAR(36)  PRIMARY KEY,
+    display_name VARCHAR(255) NOT NULL UNIQUE,
+    external_id  VARCHAR(255) NULL,
+    created      DATETIME     NOT NULL DEFAULT current_timestamp(),
+    modified     DATETIME     NOT NULL DEFAULT current_timestamp() ON UPDATE current_timestamp()
+);
+
+CREATE OR REPLACE TABLE user_group_members
+(
+    group_id VARCHAR(36) NOT NULL,
+    user_id  VARCHAR(36) NOT NULL,
+    PRIMARY KEY (group_id, user_id),
+    CONSTRAINT FOREIGN KEY user_group_members_group_id (group_id) REFERENCES user_groups (id) ON DELETE CASCADE,
+    CONSTRAINT FOREIGN KEY user_group_members_user_id (user_id) REFERENCES users (id) ON DELETE CASCADE
+);
+
+CREATE OR REPLACE PROCEDURE create_group(IN group_id VARCHAR(36), IN display_name VARCHAR(255),
+                                         IN external_id VARCHAR(255))
+BEGIN
+    INSERT INTO user_groups(id, display_name, external_id) VALUES (group_id, display_name, external_id);
+END;
+
+CREATE OR REPLACE PROCEDURE update_group(IN group_id VARCHAR(36), IN display_name VARCHAR(255),
+                                         IN external_id VARCHAR(255))
+BEGIN
+    UPDATE user_groups g
+    SET g.display_name = display_name,
+        g.external_id  = external_id,
+        g.modified     = current_timestamp()
+    WHERE g.id = group_id;
+END;
+
+CREATE OR REPLACE PROCEDURE get_group(IN group_id VARCHAR(36))
+BEGIN
+    SELECT id, display_name, external_id, created, modified FROM user_groups WHERE id = group_id;
+END;
+
+CREATE OR REPLACE PROCEDURE list_groups()
+BEGIN
+    SELECT id, display_name, external_id, created, modified FROM user_groups ORDER BY created, id;
+END;
+
+CREATE OR REPLACE PROCEDURE delete_group(IN group_id VARCHAR(36))
+BEGIN
+    DELETE FROM user_groups WHERE id = group_id;
+END;
+
+CREATE OR REPLACE PROCEDURE create_group_member(IN group_id VARCHAR(36), IN user_id VARCHAR(36))
+BEGIN
+    INSERT INTO user_group_members(group_id, user_id) VALUES (group_id, user_id);
+END;
+
+CREATE OR REPLACE PROCEDURE delete_group_members(IN group_id VARCHAR(36))
+BEGIN
+    DELETE FROM user_group_members WHERE user_group_members.group_id = group_id;
+END;
+
+CREATE OR REPLACE PROCEDURE get_group_members(IN group_id VARCHAR(36))
+BEGIN
+    SELECT user_id FROM user_group_members m WHERE m.group_id = group_id;
+END;
+
+CREATE OR REPLACE PROCEDURE get_user_groups(IN user_id VARCHAR(36))
+BEGIN
+    SELECT g.id, g.display_name, g.external_id, g.created, g.modified
+    FROM user_groups g
+             JOIN user_group_members m ON m.group_id = g.id
+    WHERE m.user_id = user_id;
+END;
@@ -0,0 +1,9 @@
+/******* OAUTH2 CONTEXT SIZE *******/
+
+ALTER TABLE challenges
+    MODIFY COLUMN oauth2_context MEDIUMTEXT NOT NULL DEFAULT '';
+
+CREATE OR REPLACE PROCEDURE set_oauth2_context(IN challenge_id VARCHAR(36), IN oauth2_context MEDIUMTEXT)
+BEGIN
+    UPDATE challenges AS c SET c.oauth2_context = oauth2_context WHERE c.id = challenge_id;
+END;
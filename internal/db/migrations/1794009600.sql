@@ -0,0 +1,48 @@
+/******* SCIM PAGINATION *******/
+
+CREATE OR REPLACE PROCEDURE count_provisioned_users(IN user_name VARCHAR(255), IN external_id VARCHAR(255))
+BEGIN
+    SELECT count(*)
+    FROM users u
+    WHERE (user_name IS NULL OR u.user_name = user_name)
+      AND (external_id IS NULL OR u.external_id = external_id);
+END;
+
+CREATE OR REPLACE PROCEDURE list_provisioned_users(IN user_name VARCHAR(255), IN external_id VARCHAR(255),
+                                                   IN page_offset INT, IN page_size INT)
+BEGIN
+    SELECT u.id, u.external_id, u.user_name, u.active, u.created, u.modified
+    FROM users u
+    WHERE (user_name IS NULL OR u.user_name = user_name)
+      AND (external_id IS NULL OR u.external_id = external_id)
+    ORDER BY u.created, u.id
+    LIMIT page_offset, page_size;
+END;
+
+CREATE OR REPLACE PROCEDURE count_groups(IN display_name VARCHAR(255), IN external_id VARCHAR(255))
+BEGIN
+    SELECT count(*)
+    FROM user_groups g
+    WHERE (display_name IS NULL OR g.display_name = display_name)
+      AND (external_id IS NULL OR g.external_id = external_id);
+END;
+
+CREATE OR REPLACE PROCEDURE list_groups(IN display_name VARCHAR(255), IN external_id VARCHAR(255),
+                                        IN page_offset INT, IN page_size INT)
+BEGIN
+    SELECT g.id, g.display_name, g.external_id, g.created, g.modified
+    FROM user_groups g
+    WHERE (display_name IS NULL OR g.display_name = display_name)
+      AND (external_id IS NULL OR g.external_id = external_id)
+    ORDER BY g.created, g.id
+    LIMIT page_offset, page_size;
+END;
+
+CREATE OR REPLACE PROCEDURE get_group_members(IN group_id VARCHAR(36))
+BEGIN
+    SELECT m.user_id, u.user_name
+    FROM user_group_members m
+             JOIN users u ON u.id = m.user_id
+    WHERE m.group_id = group_id
+    ORDER BY u.user_name, m.user_id;
+END;
@@ -0,0 +1,17 @@
+/******* REQUEST OBJECT IDS *******/
+
+CREATE OR REPLACE TABLE application_request_object_ids
+(
+    app_id VARCHAR(36)  NOT NULL,
+    jti    VARCHAR(255) NOT NULL,
+    expire DATETIME     NOT NULL,
+    PRIMARY KEY (app_id, jti),
+    CONSTRAINT FOREIGN KEY application_request_object_ids_app_id (app_id) REFERENCES applications (id) ON DELETE CASCADE
+);
+
+CREATE OR REPLACE PROCEDURE use_app_request_object_id(IN app_id VARCHAR(36), IN jti VARCHAR(255), IN expire DATETIME)
+BEGIN
+    DELETE FROM application_request_object_ids WHERE application_request_object_ids.expire < current_timestamp();
+    INSERT IGNORE INTO application_request_object_ids(app_id, jti, expire) VALUES (app_id, jti, expire);
+    SELECT row_count();
+END;
@@ -0,0 +1,45 @@
+/******* SESSION AUTHENTICATION CONTEXT *******/
+
+ALTER TABLE sessions
+    ADD COLUMN acr       VARCHAR(255) NOT NULL DEFAULT '',
+    ADD COLUMN amr       VARCHAR(255) NOT NULL DEFAULT '',
+    ADD COLUMN auth_time DATETIME     NULL;
+
+CREATE OR REPLACE PROCEDURE create_session(IN session_id VARCHAR(16), IN user_id VARCHAR(36), IN app_id VARCHAR(36),
+                                           IN requested_scopes VARCHAR(1024),
+                                           IN expire_at DATETIME, IN resources TEXT,
+                                           IN authorization_details TEXT, IN claims TEXT,
+                                           IN acr VARCHAR(255), IN amr VARCHAR(255), IN auth_time DATETIME)
+BEGIN
+    INSERT INTO sessions(id, user_id, app_id, requested_scopes, expire_at, resources, authorization_details, claims,
+                         acr, amr, auth_time)
+    VALUES (session_id, user_id, app_id, requested_scopes, expire_at, resources, authorization_details, claims,
+            acr, amr, auth_time);
+END;
+
+CREATE OR REPLACE PROCEDURE get_session(IN session_id VARCHAR(18))
+BEGIN
+    SELECT id, user_id, app_id, requested_scopes, counter, created_at, expire_at, resources, authorization_details, claims,
+           acr, amr, auth_time
+    FROM sessions
+    WHERE sessions.id = session_id
+      AND (sessions.expire_at > current_timestamp() OR sessions.expire_at IS NULL);
+END;
+
+CREATE OR REPLACE PROCEDURE get_sessions_for_user(IN user_id VARCHAR(36))
+BEGIN
+    SELECT id, user_id, app_id, requested_scopes, counter, created_at, expire_at, resources, authorization_details, claims,
+           acr, amr, auth_time
+    FROM sessions
+    WHERE sessions.user_id = user_id
+      AND (sessions.expire_at > current_timestamp() OR sessions.expire_at IS NULL);
+END;
+
+CREATE OR REPLACE PROCEDURE list_sessions_for_user(IN user_id VARCHAR(36))
+BEGIN
+    SELECT id, user_id, app_id, requested_scopes, counter, created_at, expire_at, resources, authorization_details, claims,
+           acr, amr, auth_time
+    FROM sessions
+    WHERE sessions.user_id = user_id
+      AND (sessions.expire_at > current_timestamp() OR sessions.expire_at IS NULL);
+END;
@@ -0,0 +1,47 @@
+/******* SECTOR IDENTIFIER URI *******/
+
+ALTER TABLE applications
+    ADD COLUMN sector_identifier_uri VARCHAR(2048) NOT NULL DEFAULT '';
+
+CREATE OR REPLACE PROCEDURE set_app_sector_identifier_uri(IN app_id VARCHAR(36), IN uri VARCHAR(2048))
+BEGIN
+    UPDATE applications SET sector_identifier_uri = uri WHERE id = app_id;
+END;
+
+CREATE OR REPLACE PROCEDURE get_app(IN app_id VARCHAR(36))
+BEGIN
+    SELECT id,
+           created,
+           name,
+           secret,
+           description,
+           icon,
+           ciba_mode,
+           notification_endpoint,
+           backchannel_logout_uri,
+           require_par,
+           jwks,
+           jwks_uri,
+           token_endpoint_auth_method,
+           tls_client_auth_subject_dn,
+           tls_client_thumbprint,
+           registration_token_hash,
+           registration_scope,
+           subject_type,
+           sector_identifier,
+           sector_identifier_uri,
+           id_token_encrypted_response_alg,
+           id_token_encrypted_response_enc,
+           userinfo_encrypted_response_alg,
+           userinfo_encrypted_response_enc,
+           access_token_length,
+           id_token_length,
+           refresh_token_length,
+           first_party,
+           is_admin,
+           alg,
+           kid
+    FROM applications
+    WHERE id = app_id
+    LIMIT 1;
+END;
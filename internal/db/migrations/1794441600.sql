@@ -0,0 +1,13 @@
+/******* SCOPE REGISTRY WITH CLAIMS AND APPLICATIONS *******/
+
+CREATE OR REPLACE PROCEDURE list_scopes_full()
+BEGIN
+    SELECT s.name, s.description, s.created, c.claim, NULL AS application_id
+    FROM scopes s
+             LEFT JOIN scope_claims c ON c.scope = s.name
+    UNION ALL
+    SELECT s.name, s.description, s.created, NULL AS claim, a.application_id
+    FROM scopes s
+             JOIN scope_applications a ON a.scope = s.name
+    ORDER BY name;
+END;
@@ -0,0 +1,56 @@
+package requestdb
+
+import (
+	"net/url"
+	"strings"
+	"time"
+	"uyulala/internal/db"
+
+	"github.com/gin-gonic/gin"
+	"gitlab.com/daedaluz/gindb"
+)
+
+// URIPrefix is the prefix of the request_uri values handed out for pushed authorization requests (RFC 9126).
+const URIPrefix = "urn:ietf:params:oauth:request_uri:"
+
+type PushedRequest struct {
+	ID         string    `db:"id"`
+	AppID      string    `db:"app_id"`
+	Parameters string    `db:"parameters"`
+	Expire     time.Time `db:"expire"`
+}
+
+func (r *PushedRequest) Expired() bool {
+	return time.Now().After(r.Expire)
+}
+
+func (r *PushedRequest) Form() (url.Values, error) {
+	return url.ParseQuery(r.Parameters)
+}
+
+// Create stores the authorization request parameters and returns the request_uri referencing them.
+func Create(ctx *gin.Context, appID string, parameters url.Values, expire time.Time) (string, error) {
+	id := db.GenerateID(16)
+	tx := gindb.GetTX(ctx)
+	if _, err := tx.Exec(`call create_pushed_request(?, ?, ?, ?)`, id, appID, parameters.Encode(), expire); err != nil {
+		return "", err
+	}
+	return URIPrefix + id, nil
+}
+
+// Get looks up a pushed authorization request by its request_uri.
+func Get(ctx *gin.Context, requestURI string) (*PushedRequest, error) {
+	r := &PushedRequest{}
+	tx := gindb.GetTX(ctx)
+	if err := tx.Get(r, `call get_pushed_request(?)`, strings.TrimPrefix(requestURI, URIPrefix)); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Delete removes a pushed authorization request, along with any expired ones. A request_uri can only be used once.
+func Delete(ctx *gin.Context, id string) error {
+	tx := gindb.GetTX(ctx)
+	_, err := tx.Exec(`call delete_pushed_request(?)`, id)
+	return err
+}
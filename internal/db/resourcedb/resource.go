@@ -0,0 +1,73 @@
+package resourcedb
+
+import (
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jmoiron/sqlx"
+	"gitlab.com/daedaluz/gindb"
+)
+
+// ResourceServer is a protected resource access tokens can be restricted to with a resource indicator (RFC 8707).
+type ResourceServer struct {
+	// ID is the resource indicator, an absolute uri.
+	ID   string `json:"id" db:"id"`
+	Name string `json:"name" db:"name"`
+	// AccessTokenLength is in seconds, zero uses the length of the application.
+	AccessTokenLength int64 `json:"accessTokenLength" db:"access_token_length"`
+	// KeyID is the server key tokens for the resource are signed with, empty uses the key of the application.
+	KeyID   string    `json:"keyId" db:"kid"`
+	Created time.Time `json:"created" db:"created"`
+	Scopes  []string  `json:"scopes"`
+}
+
+func Get(ctx *gin.Context, resourceID string) (*ResourceServer, error) {
+	tx := gindb.GetTX(ctx)
+	resource := &ResourceServer{}
+	if err := tx.Get(resource, `call get_resource_server(?)`, resourceID); err != nil {
+		return nil, err
+	}
+	if err := tx.Select(&resource.Scopes, `call get_resource_server_scopes(?)`, resourceID); err != nil {
+		return nil, err
+	}
+	return resource, nil
+}
+
+func List(ctx *gin.Context) ([]*ResourceServer, error) {
+	tx := gindb.GetTX(ctx)
+	var resources []*ResourceServer
+	if err := tx.Select(&resources, `call list_resource_servers()`); err != nil {
+		return nil, err
+	}
+	for _, resource := range resources {
+		if err := tx.Select(&resource.Scopes, `call get_resource_server_scopes(?)`, resource.ID); err != nil {
+			return nil, err
+		}
+	}
+	return resources, nil
+}
+
+// Create stores a new resource server together with its scopes.
+func Create(ctx *gin.Context, resource *ResourceServer) error {
+	return CreateTx(gindb.GetTX(ctx), resource)
+}
+
+// CreateTx is Create within tx.
+func CreateTx(tx *sqlx.Tx, resource *ResourceServer) error {
+	if _, err := tx.Exec(`call create_resource_server(?, ?, ?, ?)`,
+		resource.ID, resource.Name, resource.AccessTokenLength, resource.KeyID); err != nil {
+		return err
+	}
+	for _, scope := range resource.Scopes {
+		if _, err := tx.Exec(`call create_resource_server_scope(?, ?)`, resource.ID, scope); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func Delete(ctx *gin.Context, resourceID string) error {
+	tx := gindb.GetTX(ctx)
+	_, err := tx.Exec(`call delete_resource_server(?)`, resourceID)
+	return err
+}
@@ -0,0 +1,134 @@
+package scopedb
+
+import (
+	"database/sql"
+	"slices"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jmoiron/sqlx"
+	"gitlab.com/daedaluz/gindb"
+)
+
+// scopesKey is where List keeps the scopes of the request, Create and Delete reset it.
+const scopesKey = "scopedb_scopes"
+
+// Scope is a registered OAuth2 scope users are asked to consent to.
+type Scope struct {
+	Name        string    `json:"name" db:"name"`
+	Description string    `json:"description" db:"description"`
+	Created     time.Time `json:"created" db:"created"`
+	// Claims are the user claims granted by the scope.
+	Claims []string `json:"claims"`
+	// Apps are the applications allowed to request the scope, empty allows all.
+	Apps []string `json:"apps"`
+}
+
+// Allowed reports whether appID may request the scope.
+func (s *Scope) Allowed(appID string) bool {
+	if len(s.Apps) == 0 {
+		return true
+	}
+	for _, app := range s.Apps {
+		if app == appID {
+			return true
+		}
+	}
+	return false
+}
+
+// Get returns the registered scope name, sql.ErrNoRows if there is none.
+func Get(ctx *gin.Context, name string) (*Scope, error) {
+	scopes, err := List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, scope := range scopes {
+		if scope.Name == name {
+			return scope, nil
+		}
+	}
+	return nil, sql.ErrNoRows
+}
+
+// List returns the registered scopes together with their claims and applications, ordered by name. They are loaded
+// once per request.
+func List(ctx *gin.Context) ([]*Scope, error) {
+	if scopes, ok := ctx.Value(scopesKey).([]*Scope); ok {
+		return slices.Clone(scopes), nil
+	}
+	var rows []struct {
+		Name          string         `db:"name"`
+		Description   string         `db:"description"`
+		Created       time.Time      `db:"created"`
+		Claim         sql.NullString `db:"claim"`
+		ApplicationID sql.NullString `db:"application_id"`
+	}
+	if err := gindb.GetTX(ctx).Select(&rows, `call list_scopes_full()`); err != nil {
+		return nil, err
+	}
+	var scopes []*Scope
+	for _, row := range rows {
+		if len(scopes) == 0 || scopes[len(scopes)-1].Name != row.Name {
+			scopes = append(scopes, &Scope{Name: row.Name, Description: row.Description, Created: row.Created,
+				Claims: []string{}, Apps: []string{}})
+		}
+		scope := scopes[len(scopes)-1]
+		if row.Claim.Valid {
+			scope.Claims = append(scope.Claims, row.Claim.String)
+		}
+		if row.ApplicationID.Valid {
+			scope.Apps = append(scope.Apps, row.ApplicationID.String)
+		}
+	}
+	ctx.Set(scopesKey, scopes)
+	return slices.Clone(scopes), nil
+}
+
+// Registered returns the registered scopes among names, keyed by name.
+func Registered(ctx *gin.Context, names []string) (map[string]*Scope, error) {
+	scopes, err := List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	registered := make(map[string]*Scope)
+	for _, name := range names {
+		for _, scope := range scopes {
+			if scope.Name == name {
+				registered[name] = scope
+			}
+		}
+	}
+	return registered, nil
+}
+
+// Create stores a new scope together with its claims and applications.
+func Create(ctx *gin.Context, scope *Scope) error {
+	ctx.Set(scopesKey, nil)
+	return CreateTx(gindb.GetTX(ctx), scope)
+}
+
+// CreateTx is Create within tx.
+func CreateTx(tx *sqlx.Tx, scope *Scope) error {
+	if _, err := tx.Exec(`call create_scope(?, ?)`, scope.Name, scope.Description); err != nil {
+		return err
+	}
+	for _, claim := range scope.Claims {
+		if _, err := tx.Exec(`call create_scope_claim(?, ?)`, scope.Name, claim); err != nil {
+			return err
+		}
+	}
+	for _, app := range scope.Apps {
+		if _, err := tx.Exec(`call create_scope_app(?, ?)`, scope.Name, app); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func Delete(ctx *gin.Context, name string) error {
+	ctx.Set(scopesKey, nil)
+	tx := gindb.GetTX(ctx)
+	_, err := tx.Exec(`call delete_scope(?)`, name)
+	return err
+}
@@ -2,6 +2,7 @@ package sessiondb
 
 import (
 	"database/sql"
+	"strings"
 	"time"
 	"uyulala/internal/db"
 
@@ -22,6 +23,17 @@ type Session struct {
 	RequestedScopes string       `db:"requested_scopes" json:"requestedScopes"`
 	Created         time.Time    `db:"created_at" json:"created"`
 	ExpireAt        sql.NullTime `db:"expire_at" json:"expires"`
+	// Resources are the space separated resource indicators the session was authorized for.
+	Resources string `db:"resources" json:"resources"`
+	// AuthorizationDetails are the canonical authorization details (RFC 9396) the user signed.
+	AuthorizationDetails string `db:"authorization_details" json:"authorizationDetails"`
+	// Claims is the claims request parameter of the authorization, applied to the userinfo responses of the session.
+	Claims string `db:"claims" json:"claims"`
+	// ACR, the space separated AMR and AuthTime are the authentication of the user the session started with,
+	// repeated in the ID tokens of refreshes.
+	ACR      string       `db:"acr" json:"acr"`
+	AMR      string       `db:"amr" json:"amr"`
+	AuthTime sql.NullTime `db:"auth_time" json:"authTime"`
 }
 
 func Get(c *gin.Context, sessionID string) (*Session, error) {
@@ -34,19 +46,28 @@ func Get(c *gin.Context, sessionID string) (*Session, error) {
 	return s, nil
 }
 
-func Create(c *gin.Context, userID, appID, scopes string) (*Session, error) {
-	dur := viper.GetDuration("refreshToken.length")
+// Create starts a session of a refresh token valid for length, which never expires when zero.
+// acr, amr and authTime are the authentication of the user, see Session.
+func Create(c *gin.Context, userID, appID, scopes, resources, authorizationDetails, claims string,
+	acr string, amr []string, authTime time.Time, length time.Duration) (*Session, error) {
+	dur := length
 	exp := time.Time{}
 	if dur != 0 {
 		exp = time.Now().Add(dur)
 	}
 	sess := &Session{
-		ID:              db.GenerateID(8),
-		UserID:          userID,
-		AppID:           appID,
-		RequestedScopes: scopes,
-		Counter:         0,
-		ExpireAt:        sql.NullTime{},
+		ID:                   db.GenerateID(8),
+		UserID:               userID,
+		AppID:                appID,
+		RequestedScopes:      scopes,
+		Resources:            resources,
+		AuthorizationDetails: authorizationDetails,
+		Claims:               claims,
+		ACR:                  acr,
+		AMR:                  strings.Join(amr, " "),
+		AuthTime:             sql.NullTime{Time: authTime, Valid: !authTime.IsZero()},
+		Counter:              0,
+		ExpireAt:             sql.NullTime{},
 	}
 	if !exp.IsZero() {
 		sess.ExpireAt = sql.NullTime{
@@ -56,8 +77,9 @@ func Create(c *gin.Context, userID, appID, scopes string) (*Session, error) {
 	}
 
 	tx := gindb.GetTX(c)
-	_, err := tx.Exec(`call create_session(?, ?, ?, ?, ?)`,
-		sess.ID, userID, appID, scopes, sess.ExpireAt)
+	_, err := tx.Exec(`call create_session(?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		sess.ID, userID, appID, scopes, sess.ExpireAt, resources, authorizationDetails, claims,
+		sess.ACR, sess.AMR, sess.AuthTime)
 	if err != nil {
 		return nil, err
 	}
@@ -70,12 +92,13 @@ func Delete(c *gin.Context, sid string) error {
 	return err
 }
 
-func Rotate(c *gin.Context, session *Session) error {
+// Rotate increments the counter of the session and, with refreshToken.extendOnUse, extends it by length.
+func Rotate(c *gin.Context, session *Session, length time.Duration) error {
 	tx := gindb.GetTX(c)
 	session.Counter++
-	if viper.GetBool("refreshToken.extendOnUse") && viper.GetDuration("refreshToken.length") > 0 {
+	if viper.GetBool("refreshToken.extendOnUse") && length > 0 {
 		session.ExpireAt = sql.NullTime{
-			Time:  time.Now().Add(viper.GetDuration("refreshToken.length")),
+			Time:  time.Now().Add(length),
 			Valid: true,
 		}
 	}
@@ -84,7 +107,8 @@ func Rotate(c *gin.Context, session *Session) error {
 }
 
 // CreateRefreshToken generates a signed jwt token with the session id as the jwt id and a counter-claim to prevent reuse of the token.
-func (s *Session) CreateRefreshToken(key jwk.Key) (string, error) {
+// A non-nil cnf binds the token to the client the same way as the access tokens.
+func (s *Session) CreateRefreshToken(key jwk.Key, cnf map[string]any) (string, error) {
 	hdrs := jws.NewHeaders()
 	_ = hdrs.Set("typ", "refresh+jwt")
 	token := jwt.New()
@@ -94,6 +118,9 @@ func (s *Session) CreateRefreshToken(key jwk.Key) (string, error) {
 	_ = token.Set(jwt.IssuerKey, viper.GetString("issuer"))
 	_ = token.Set(jwt.AudienceKey, viper.GetString("issuer"))
 	_ = token.Set(jwt.SubjectKey, s.AppID)
+	if cnf != nil {
+		_ = token.Set("cnf", cnf)
+	}
 
 	tokenBytes, err := jwt.Sign(token, jwa.SignatureAlgorithm(key.Algorithm()), key, jwt.WithHeaders(hdrs))
 	if err != nil {
@@ -0,0 +1,52 @@
+package userdb
+
+import (
+	"encoding/json"
+
+	"github.com/gin-gonic/gin"
+	"gitlab.com/daedaluz/gindb"
+)
+
+type attribute struct {
+	Name  string `db:"name"`
+	Value string `db:"value"`
+}
+
+// GetAttributes returns the profile attributes of the user, like name and email, keyed by claim name.
+func GetAttributes(ctx *gin.Context, userID string) (map[string]any, error) {
+	tx := gindb.GetTX(ctx)
+	var rows []attribute
+	if err := tx.Select(&rows, `call get_user_attributes(?)`, userID); err != nil {
+		return nil, err
+	}
+	attributes := make(map[string]any, len(rows))
+	for _, row := range rows {
+		var value any
+		if err := json.Unmarshal([]byte(row.Value), &value); err != nil {
+			return nil, err
+		}
+		attributes[row.Name] = value
+	}
+	return attributes, nil
+}
+
+// SetAttributes updates the profile attributes of the user, attributes set to nil are removed.
+func SetAttributes(ctx *gin.Context, userID string, attributes map[string]any) error {
+	tx := gindb.GetTX(ctx)
+	for name, value := range attributes {
+		if value == nil {
+			if _, err := tx.Exec(`call delete_user_attribute(?, ?)`, userID, name); err != nil {
+				return err
+			}
+			continue
+		}
+		data, err := json.Marshal(value)
+		if err != nil {
+			return err
+		}
+		if _, err := tx.Exec(`call set_user_attribute(?, ?, ?)`, userID, name, string(data)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,59 @@
+package userdb
+
+import (
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gitlab.com/daedaluz/gindb"
+)
+
+// Consent is a scope a user has granted to an application.
+type Consent struct {
+	UserID  string    `json:"userId" db:"user_id"`
+	AppID   string    `json:"appId" db:"application_id"`
+	Scope   string    `json:"scope" db:"scope"`
+	Granted time.Time `json:"granted" db:"granted"`
+}
+
+// GrantConsent records that the user has granted the scopes to the application.
+func GrantConsent(ctx *gin.Context, userID, appID string, scopes []string) error {
+	tx := gindb.GetTX(ctx)
+	for _, scope := range scopes {
+		if _, err := tx.Exec(`call create_consent(?, ?, ?)`, userID, appID, scope); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ListConsents returns all consents granted by the user.
+func ListConsents(ctx *gin.Context, userID string) ([]*Consent, error) {
+	tx := gindb.GetTX(ctx)
+	consents := make([]*Consent, 0)
+	if err := tx.Select(&consents, `call list_consents_for_user(?)`, userID); err != nil {
+		return nil, err
+	}
+	return consents, nil
+}
+
+// ConsentedScopes returns the scopes the user has granted to the application.
+func ConsentedScopes(ctx *gin.Context, userID, appID string) ([]string, error) {
+	consents, err := ListConsents(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	var scopes []string
+	for _, consent := range consents {
+		if consent.AppID == appID {
+			scopes = append(scopes, consent.Scope)
+		}
+	}
+	return scopes, nil
+}
+
+// RevokeConsent removes all consents the user has granted to the application.
+func RevokeConsent(ctx *gin.Context, userID, appID string) error {
+	tx := gindb.GetTX(ctx)
+	_, err := tx.Exec(`call delete_consents(?, ?)`, userID, appID)
+	return err
+}
@@ -0,0 +1,23 @@
+package userdb
+
+import (
+	"github.com/gin-gonic/gin"
+	"gitlab.com/daedaluz/gindb"
+)
+
+// CreatePairwiseSubject records which user a pairwise subject identifier of a sector belongs to.
+func CreatePairwiseSubject(ctx *gin.Context, sector, subject, userID string) error {
+	tx := gindb.GetTX(ctx)
+	_, err := tx.Exec(`call create_pairwise_subject(?, ?, ?)`, sector, subject, userID)
+	return err
+}
+
+// GetPairwiseSubjectUser returns the id of the user a pairwise subject identifier of a sector belongs to.
+func GetPairwiseSubjectUser(ctx *gin.Context, sector, subject string) (string, error) {
+	var userID string
+	tx := gindb.GetTX(ctx)
+	if err := tx.Get(&userID, `call get_pairwise_subject_user(?, ?)`, sector, subject); err != nil {
+		return "", err
+	}
+	return userID, nil
+}
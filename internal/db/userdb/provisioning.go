@@ -0,0 +1,77 @@
+package userdb
+
+import (
+	"database/sql"
+	"time"
+	"uyulala/internal/db"
+
+	"github.com/gin-gonic/gin"
+	"gitlab.com/daedaluz/gindb"
+)
+
+// ProvisionedUser is a user as managed by a provisioning client (SCIM), identified there by user name and
+// external id.
+type ProvisionedUser struct {
+	ID         string         `db:"id"`
+	ExternalID sql.NullString `db:"external_id"`
+	UserName   sql.NullString `db:"user_name"`
+	Active     bool           `db:"active"`
+	Created    time.Time      `db:"created"`
+	Modified   time.Time      `db:"modified"`
+}
+
+func GetProvisionedUser(ctx *gin.Context, userID string) (*ProvisionedUser, error) {
+	tx := gindb.GetTX(ctx)
+	user := &ProvisionedUser{}
+	if err := tx.Get(user, `call get_provisioned_user(?)`, userID); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// ProvisionedUserFilter selects provisioned users by user name and external id, an empty field matches every user.
+type ProvisionedUserFilter struct {
+	UserName   string
+	ExternalID string
+}
+
+func (f *ProvisionedUserFilter) args() []any {
+	return []any{
+		sql.NullString{String: f.UserName, Valid: f.UserName != ""},
+		sql.NullString{String: f.ExternalID, Valid: f.ExternalID != ""},
+	}
+}
+
+func CountProvisionedUsers(ctx *gin.Context, filter *ProvisionedUserFilter) (int, error) {
+	tx := gindb.GetTX(ctx)
+	var count int
+	if err := tx.Get(&count, `call count_provisioned_users(?, ?)`, filter.args()...); err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+// ListProvisionedUsers returns at most count users matching filter from offset, in the order they were created.
+func ListProvisionedUsers(ctx *gin.Context, filter *ProvisionedUserFilter, offset, count int) ([]*ProvisionedUser, error) {
+	tx := gindb.GetTX(ctx)
+	users := make([]*ProvisionedUser, 0)
+	args := append(filter.args(), offset, count)
+	if err := tx.Select(&users, `call list_provisioned_users(?, ?, ?, ?)`, args...); err != nil {
+		return nil, err
+	}
+	return users, nil
+}
+
+// CreateProvisionedUser creates a user without keys, user.ID is set to the id of the new user.
+func CreateProvisionedUser(ctx *gin.Context, user *ProvisionedUser) error {
+	tx := gindb.GetTX(ctx)
+	user.ID = db.GenerateID(18)
+	_, err := tx.Exec(`call create_provisioned_user(?, ?, ?, ?)`, user.ID, user.ExternalID, user.UserName, user.Active)
+	return err
+}
+
+func UpdateProvisionedUser(ctx *gin.Context, user *ProvisionedUser) error {
+	tx := gindb.GetTX(ctx)
+	_, err := tx.Exec(`call update_provisioned_user(?, ?, ?, ?)`, user.ID, user.ExternalID, user.UserName, user.Active)
+	return err
+}
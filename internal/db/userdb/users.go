@@ -37,6 +37,8 @@ type User struct {
 	ID       string    `db:"id"`
 	LastAuth time.Time `db:"last_auth"`
 	Created  time.Time `db:"created"`
+	// Active is false for users deactivated by provisioning, they can't sign in.
+	Active bool `db:"active"`
 }
 
 func GetUser(ctx *gin.Context, userID string) (*User, error) {
@@ -0,0 +1,103 @@
+package mtls
+
+import (
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+
+	"github.com/spf13/viper"
+)
+
+// ConfirmationKey is the cnf member binding a token to a client certificate (RFC 8705 section 3.1).
+const ConfirmationKey = "x5t#S256"
+
+var (
+	ErrNoClientCA      = errors.New("no client CA configured")
+	ErrInvalidCAFile   = errors.New("no certificates found in client CA file")
+	ErrNoCertificate   = errors.New("no client certificate presented")
+	ErrSubjectMismatch = errors.New("certificate subject does not match")
+
+	clientCAs *x509.CertPool
+)
+
+// Configure loads the CA certificates that client certificates used with tls_client_auth are verified against.
+func Configure(caFile string) error {
+	data, err := os.ReadFile(caFile)
+	if err != nil {
+		return err
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(data) {
+		return ErrInvalidCAFile
+	}
+	clientCAs = pool
+	return nil
+}
+
+// Issuer returns the base URL of the mutual-TLS listener, tls.mtlsIssuer or the issuer on the port of tls.mtlsAddr.
+// It is empty when client certificates are not requested.
+func Issuer() string {
+	if !viper.GetBool("tls.clientAuth") {
+		return ""
+	}
+	if issuer := viper.GetString("tls.mtlsIssuer"); issuer != "" {
+		return issuer
+	}
+	issuer, err := url.Parse(viper.GetString("issuer"))
+	if err != nil {
+		return ""
+	}
+	_, port, err := net.SplitHostPort(viper.GetString("tls.mtlsAddr"))
+	if err != nil {
+		return ""
+	}
+	issuer.Host = net.JoinHostPort(issuer.Hostname(), port)
+	return issuer.String()
+}
+
+// Certificate returns the client certificate presented on the connection of r, if any.
+// The listener only requests the certificate, it is up to the caller to verify it.
+func Certificate(r *http.Request) *x509.Certificate {
+	if r.TLS == nil || len(r.TLS.PeerCertificates) == 0 {
+		return nil
+	}
+	return r.TLS.PeerCertificates[0]
+}
+
+// Thumbprint returns the base64url encoded SHA-256 thumbprint of cert.
+func Thumbprint(cert *x509.Certificate) string {
+	sum := sha256.Sum256(cert.Raw)
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// VerifySubject verifies the client certificate of r against the configured CAs and checks that its subject
+// distinguished name is subjectDN (tls_client_auth).
+func VerifySubject(r *http.Request, subjectDN string) error {
+	cert := Certificate(r)
+	if cert == nil {
+		return ErrNoCertificate
+	}
+	if clientCAs == nil {
+		return ErrNoClientCA
+	}
+	intermediates := x509.NewCertPool()
+	for _, c := range r.TLS.PeerCertificates[1:] {
+		intermediates.AddCert(c)
+	}
+	if _, err := cert.Verify(x509.VerifyOptions{
+		Roots:         clientCAs,
+		Intermediates: intermediates,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}); err != nil {
+		return err
+	}
+	if cert.Subject.String() != subjectDN {
+		return ErrSubjectMismatch
+	}
+	return nil
+}
@@ -0,0 +1,141 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/spf13/viper"
+)
+
+const pendingKey = "notify.pending"
+
+// Notification is a POST request that is delivered to a client endpoint outside the request cycle.
+type Notification struct {
+	URL         string
+	Token       string
+	ContentType string
+	Body        []byte
+}
+
+// JSON creates a notification with a JSON encoded body, authenticated with token as a bearer token if it is set.
+func JSON(endpoint, token string, body any) (*Notification, error) {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+	return &Notification{
+		URL:         endpoint,
+		Token:       token,
+		ContentType: "application/json",
+		Body:        data,
+	}, nil
+}
+
+// Form creates a notification with a form encoded body.
+func Form(endpoint string, values url.Values) *Notification {
+	return &Notification{
+		URL:         endpoint,
+		ContentType: "application/x-www-form-urlencoded",
+		Body:        []byte(values.Encode()),
+	}
+}
+
+// Queue schedules n for delivery once the current request has completed without being aborted.
+func Queue(ctx *gin.Context, n *Notification) {
+	var pending []*Notification
+	if v, ok := ctx.Get(pendingKey); ok {
+		pending = v.([]*Notification)
+	}
+	ctx.Set(pendingKey, append(pending, n))
+}
+
+// Flush sends the notifications queued so far, regardless of how the request ends.
+// It is used when a request is aborted after the changes the notifications are about have been committed.
+func Flush(ctx *gin.Context) {
+	if v, ok := ctx.Get(pendingKey); ok {
+		for _, n := range v.([]*Notification) {
+			go Send(n)
+		}
+	}
+	ctx.Set(pendingKey, []*Notification(nil))
+}
+
+// Middleware sends the notifications queued during a request that wasn't aborted.
+// It must be registered before gindb.MiddlewareTX with TxErrorHandler, so that notifications are only sent after the
+// transaction has been committed.
+func Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+		if c.IsAborted() {
+			return
+		}
+		if v, ok := c.Get(pendingKey); ok {
+			for _, n := range v.([]*Notification) {
+				go Send(n)
+			}
+		}
+	}
+}
+
+// TxErrorHandler is the error handler of gindb.MiddlewareTX. It aborts the request when its transaction can't be
+// started or committed, so that Middleware drops the notifications about changes that never made it.
+func TxErrorHandler(c *gin.Context, err error) {
+	slog.Error("Transaction failed", "path", c.Request.URL.Path, "error", err)
+	if c.Writer.Written() {
+		c.Abort()
+		return
+	}
+	c.AbortWithStatus(http.StatusInternalServerError)
+}
+
+// Send delivers n, retrying with exponential backoff until it succeeds or the retries are exhausted.
+func Send(n *Notification) {
+	retries := viper.GetInt("notification.retries")
+	backoff := viper.GetDuration("notification.backoff")
+	for attempt := 0; ; attempt++ {
+		err := n.post()
+		if err == nil {
+			slog.Info("Notification delivered", "url", n.URL, "attempt", attempt)
+			return
+		}
+		if attempt >= retries {
+			slog.Error("Notification failed", "url", n.URL, "attempt", attempt, "error", err)
+			return
+		}
+		slog.Warn("Notification failed, retrying", "url", n.URL, "attempt", attempt, "backoff", backoff, "error", err)
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+}
+
+func (n *Notification) post() error {
+	ctx, cancel := context.WithTimeout(context.Background(), viper.GetDuration("notification.timeout"))
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.URL, bytes.NewReader(n.Body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", n.ContentType)
+	if n.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+n.Token)
+	}
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = res.Body.Close()
+	}()
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return fmt.Errorf("unexpected status %s", strings.TrimSpace(res.Status))
+	}
+	return nil
+}
@@ -0,0 +1,66 @@
+package notify
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+	"uyulala/internal/db/dbtest"
+
+	"github.com/gin-gonic/gin"
+	"github.com/spf13/viper"
+	"gitlab.com/daedaluz/gindb"
+)
+
+func TestMiddleware(t *testing.T) {
+	viper.Set("notification.retries", 0)
+	viper.Set("notification.timeout", time.Second)
+	delivered := make(chan string, 10)
+	client := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		delivered <- r.URL.Path
+	}))
+	defer client.Close()
+
+	tests := []struct {
+		name    string
+		handler gin.HandlerFunc
+		status  int
+		sent    bool
+	}{
+		{"committed", func(c *gin.Context) {
+			c.Status(http.StatusNoContent)
+		}, http.StatusNoContent, true},
+		{"aborted", func(c *gin.Context) {
+			c.AbortWithStatus(http.StatusBadRequest)
+		}, http.StatusBadRequest, false},
+		{"commit failed", func(c *gin.Context) {
+			// The transaction is finished here, so the commit of the middleware fails.
+			_ = gindb.Rollback(c)
+		}, http.StatusInternalServerError, false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			engine := gin.New()
+			engine.Use(gindb.MiddlewareDB(dbtest.Open().DB), Middleware(), gindb.MiddlewareTX(TxErrorHandler))
+			engine.POST("/event", func(c *gin.Context) {
+				Queue(c, Form(client.URL+"/event", nil))
+				test.handler(c)
+			})
+			recorder := httptest.NewRecorder()
+			engine.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/event", nil))
+			if recorder.Code != test.status {
+				t.Errorf("status = %d, want %d", recorder.Code, test.status)
+			}
+			select {
+			case path := <-delivered:
+				if !test.sent {
+					t.Errorf("notification %s was sent", path)
+				}
+			case <-time.After(200 * time.Millisecond):
+				if test.sent {
+					t.Error("notification wasn't sent")
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,349 @@
+package scim
+
+import (
+	"encoding/json"
+	"errors"
+	"strconv"
+	"strings"
+)
+
+var ErrInvalidFilter = errors.New("invalid filter")
+
+// filter is a parsed SCIM filter expression (RFC 7644 3.4.2.2) matched against the JSON representation of a resource.
+type filter interface {
+	match(resource map[string]any) bool
+}
+
+type logicalFilter struct {
+	and         bool
+	left, right filter
+}
+
+func (f *logicalFilter) match(resource map[string]any) bool {
+	if f.and {
+		return f.left.match(resource) && f.right.match(resource)
+	}
+	return f.left.match(resource) || f.right.match(resource)
+}
+
+type notFilter struct {
+	filter filter
+}
+
+func (f *notFilter) match(resource map[string]any) bool {
+	return !f.filter.match(resource)
+}
+
+type attributeFilter struct {
+	path  string
+	op    string
+	value any
+}
+
+func (f *attributeFilter) match(resource map[string]any) bool {
+	values := lookup(resource, f.path)
+	if f.op == "pr" {
+		for _, value := range values {
+			if value != nil && value != "" {
+				return true
+			}
+		}
+		return false
+	}
+	if f.op == "ne" {
+		for _, value := range values {
+			if compare(value, "eq", f.value) {
+				return false
+			}
+		}
+		return true
+	}
+	for _, value := range values {
+		if compare(value, f.op, f.value) {
+			return true
+		}
+	}
+	return false
+}
+
+// valueFilter matches the elements of a multi-valued attribute, like members[value eq "id"].
+type valueFilter struct {
+	path   string
+	filter filter
+}
+
+func (f *valueFilter) match(resource map[string]any) bool {
+	for _, value := range lookup(resource, f.path) {
+		if element, ok := value.(map[string]any); ok && f.filter.match(element) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseFilter parses a filter, an empty filter matches every resource.
+func parseFilter(expr string) (filter, error) {
+	if strings.TrimSpace(expr) == "" {
+		return nil, nil
+	}
+	tokens, err := tokenize(expr)
+	if err != nil {
+		return nil, err
+	}
+	p := &filterParser{tokens: tokens}
+	f, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos != len(p.tokens) {
+		return nil, ErrInvalidFilter
+	}
+	return f, nil
+}
+
+type filterParser struct {
+	tokens []string
+	pos    int
+}
+
+func (p *filterParser) peek() string {
+	if p.pos < len(p.tokens) {
+		return p.tokens[p.pos]
+	}
+	return ""
+}
+
+func (p *filterParser) next() string {
+	token := p.peek()
+	p.pos++
+	return token
+}
+
+func (p *filterParser) parseOr() (filter, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for strings.EqualFold(p.peek(), "or") {
+		p.next()
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = &logicalFilter{left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *filterParser) parseAnd() (filter, error) {
+	left, err := p.parseFactor()
+	if err != nil {
+		return nil, err
+	}
+	for strings.EqualFold(p.peek(), "and") {
+		p.next()
+		right, err := p.parseFactor()
+		if err != nil {
+			return nil, err
+		}
+		left = &logicalFilter{and: true, left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *filterParser) parseFactor() (filter, error) {
+	token := p.next()
+	switch {
+	case strings.EqualFold(token, "not"):
+		f, err := p.parseFactor()
+		if err != nil {
+			return nil, err
+		}
+		return &notFilter{filter: f}, nil
+	case token == "(":
+		f, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if p.next() != ")" {
+			return nil, ErrInvalidFilter
+		}
+		return f, nil
+	case token == "" || strings.ContainsAny(token[:1], "()[]\""):
+		return nil, ErrInvalidFilter
+	}
+	path := attributePath(token)
+	if p.peek() == "[" {
+		p.next()
+		f, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if p.next() != "]" {
+			return nil, ErrInvalidFilter
+		}
+		return &valueFilter{path: path, filter: f}, nil
+	}
+	op := strings.ToLower(p.next())
+	switch op {
+	case "pr":
+		return &attributeFilter{path: path, op: op}, nil
+	case "eq", "ne", "co", "sw", "ew", "gt", "ge", "lt", "le":
+		value, err := parseValue(p.next())
+		if err != nil {
+			return nil, err
+		}
+		return &attributeFilter{path: path, op: op, value: value}, nil
+	}
+	return nil, ErrInvalidFilter
+}
+
+func parseValue(token string) (any, error) {
+	switch {
+	case strings.HasPrefix(token, `"`):
+		var value string
+		if err := json.Unmarshal([]byte(token), &value); err != nil {
+			return nil, ErrInvalidFilter
+		}
+		return value, nil
+	case token == "true":
+		return true, nil
+	case token == "false":
+		return false, nil
+	case token == "null":
+		return nil, nil
+	}
+	value, err := strconv.ParseFloat(token, 64)
+	if err != nil {
+		return nil, ErrInvalidFilter
+	}
+	return value, nil
+}
+
+// tokenize splits a filter into attribute paths, operators, values and brackets.
+func tokenize(expr string) ([]string, error) {
+	var tokens []string
+	for i := 0; i < len(expr); {
+		switch c := expr[i]; {
+		case c == ' ' || c == '\t':
+			i++
+		case strings.IndexByte("()[]", c) >= 0:
+			tokens = append(tokens, string(c))
+			i++
+		case c == '"':
+			end := i + 1
+			for end < len(expr) && expr[end] != '"' {
+				if expr[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			if end >= len(expr) {
+				return nil, ErrInvalidFilter
+			}
+			tokens = append(tokens, expr[i:end+1])
+			i = end + 1
+		default:
+			end := i
+			for end < len(expr) && strings.IndexByte(" \t()[]\"", expr[end]) < 0 {
+				end++
+			}
+			tokens = append(tokens, expr[i:end])
+			i = end
+		}
+	}
+	return tokens, nil
+}
+
+// attributePath removes the schema urn from a fully qualified attribute path.
+func attributePath(path string) string {
+	for _, schema := range []string{SchemaUser, SchemaGroup} {
+		if len(path) > len(schema) && strings.EqualFold(path[:len(schema)+1], schema+":") {
+			return path[len(schema)+1:]
+		}
+	}
+	return path
+}
+
+// lookup returns the values of a dotted attribute path, flattening multi-valued attributes. Attribute names are
+// case-insensitive.
+func lookup(resource map[string]any, path string) []any {
+	name, rest, _ := strings.Cut(path, ".")
+	value, ok := resource[key(resource, name)]
+	if !ok {
+		return nil
+	}
+	values := []any{value}
+	if list, ok := value.([]any); ok {
+		values = list
+	}
+	if rest == "" {
+		return values
+	}
+	var res []any
+	for _, value := range values {
+		if object, ok := value.(map[string]any); ok {
+			res = append(res, lookup(object, rest)...)
+		}
+	}
+	return res
+}
+
+// key returns the key of object matching name case-insensitively, or name if there is none.
+func key(object map[string]any, name string) string {
+	for k := range object {
+		if strings.EqualFold(k, name) {
+			return k
+		}
+	}
+	return name
+}
+
+func compare(actual any, op string, expected any) bool {
+	switch expected := expected.(type) {
+	case string:
+		value, ok := actual.(string)
+		if !ok {
+			return false
+		}
+		value, expected = strings.ToLower(value), strings.ToLower(expected)
+		switch op {
+		case "eq":
+			return value == expected
+		case "co":
+			return strings.Contains(value, expected)
+		case "sw":
+			return strings.HasPrefix(value, expected)
+		case "ew":
+			return strings.HasSuffix(value, expected)
+		case "gt":
+			return value > expected
+		case "ge":
+			return value >= expected
+		case "lt":
+			return value < expected
+		case "le":
+			return value <= expected
+		}
+	case float64:
+		value, ok := actual.(float64)
+		if !ok {
+			return false
+		}
+		switch op {
+		case "eq":
+			return value == expected
+		case "gt":
+			return value > expected
+		case "ge":
+			return value >= expected
+		case "lt":
+			return value < expected
+		case "le":
+			return value <= expected
+		}
+	default:
+		return op == "eq" && actual == expected
+	}
+	return false
+}
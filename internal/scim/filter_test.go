@@ -0,0 +1,147 @@
+package scim
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+const filterUser = `{
+	"schemas": ["urn:ietf:params:scim:schemas:core:2.0:User"],
+	"id": "2819c223",
+	"externalId": "E-1001",
+	"userName": "Bjensen",
+	"name": {"givenName": "Barbara", "familyName": "Jensen"},
+	"emails": [
+		{"value": "bjensen@example.com", "type": "work", "primary": true},
+		{"value": "babs@jensen.org", "type": "home"}
+	],
+	"active": true,
+	"meta": {"resourceType": "User", "lastModified": "2024-05-13T04:42:34Z"}
+}`
+
+func TestFilter(t *testing.T) {
+	var user map[string]any
+	if err := json.Unmarshal([]byte(filterUser), &user); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		expr string
+		want bool
+	}{
+		// Operators
+		{`userName eq "bjensen"`, true},
+		{`userName eq "bjensen2"`, false},
+		{`userName ne "other"`, true},
+		{`userName ne "BJENSEN"`, false},
+		{`userName co "jens"`, true},
+		{`userName sw "Bj"`, true},
+		{`userName sw "je"`, false},
+		{`userName ew "sen"`, true},
+		{`userName gt "a"`, true},
+		{`userName ge "bjensen"`, true},
+		{`userName lt "b"`, false},
+		{`userName le "bjensen"`, true},
+		{`externalId pr`, true},
+		{`nickName pr`, false},
+		{`active eq true`, true},
+		{`active eq false`, false},
+		{`meta.lastModified gt "2024-01-01T00:00:00Z"`, true},
+		{`meta.lastModified lt "2024-01-01T00:00:00Z"`, false},
+		// Attribute names are case-insensitive, and may be qualified by the schema.
+		{`USERNAME eq "bjensen"`, true},
+		{`name.FAMILYNAME eq "jensen"`, true},
+		{`urn:ietf:params:scim:schemas:core:2.0:User:userName eq "bjensen"`, true},
+		// Multi-valued attributes match if any value does.
+		{`emails.value ew "@jensen.org"`, true},
+		{`emails.type eq "mobile"`, false},
+		{`emails[type eq "work" and value co "@example.com"]`, true},
+		{`emails[type eq "home" and value co "@example.com"]`, false},
+		// Precedence: not binds tightest, then and, then or.
+		{`userName eq "x" and active eq true or externalId eq "E-1001"`, true},
+		{`userName eq "x" and (active eq true or externalId eq "E-1001")`, false},
+		{`externalId eq "E-1001" or userName eq "x" and active eq false`, true},
+		{`(externalId eq "E-1001" or userName eq "x") and active eq false`, false},
+		{`not (userName eq "x")`, true},
+		{`not (userName eq "bjensen") or active eq true`, true},
+		{`not (userName eq "bjensen" or active eq true)`, false},
+		{`NOT (userName EQ "bjensen") OR active Eq true`, true},
+	}
+	for _, test := range tests {
+		t.Run(test.expr, func(t *testing.T) {
+			f, err := parseFilter(test.expr)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := f.match(user); got != test.want {
+				t.Fatalf("got %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestInvalidFilter(t *testing.T) {
+	for _, expr := range []string{
+		`userName`,
+		`userName eq`,
+		`userName xx "a"`,
+		`userName eq "unterminated`,
+		`userName eq bjensen`,
+		`(userName eq "a"`,
+		`userName eq "a")`,
+		`emails[type eq "work"`,
+		`userName eq "a" and`,
+		`and userName eq "a"`,
+		// Sub-attributes after a value filter are only valid in PATCH paths.
+		`emails[primary eq true].value pr`,
+	} {
+		t.Run(expr, func(t *testing.T) {
+			if _, err := parseFilter(expr); !errors.Is(err, ErrInvalidFilter) {
+				t.Fatalf("got %v, want %v", err, ErrInvalidFilter)
+			}
+		})
+	}
+	if f, err := parseFilter(" "); f != nil || err != nil {
+		t.Fatalf("empty filter: got %v, %v", f, err)
+	}
+}
+
+func TestEqualities(t *testing.T) {
+	attributes := []string{"userName", "externalId"}
+	tests := []struct {
+		expr string
+		want map[string]string
+	}{
+		{``, map[string]string{}},
+		{`userName eq "bjensen"`, map[string]string{"userName": "bjensen"}},
+		{`USERNAME eq "bjensen" and externalId eq "E-1"`, map[string]string{"userName": "bjensen", "externalId": "E-1"}},
+		{`userName eq "a" and userName eq "A"`, map[string]string{"userName": "A"}},
+		{`userName eq "a" and userName eq "b"`, nil},
+		{`userName eq "a" or externalId eq "E-1"`, nil},
+		{`userName sw "a"`, nil},
+		{`userName eq ""`, nil},
+		{`active eq true`, nil},
+		{`not (userName eq "a")`, nil},
+		{`userName eq "a" and active eq true`, nil},
+	}
+	for _, test := range tests {
+		t.Run(test.expr, func(t *testing.T) {
+			f, err := parseFilter(test.expr)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, ok := equalities(f, attributes)
+			if ok != (test.want != nil) {
+				t.Fatalf("got %v, %v, want %v", got, ok, test.want)
+			}
+			if len(got) != len(test.want) {
+				t.Fatalf("got %v, want %v", got, test.want)
+			}
+			for attribute, value := range test.want {
+				if got[attribute] != value {
+					t.Fatalf("got %v, want %v", got, test.want)
+				}
+			}
+		})
+	}
+}
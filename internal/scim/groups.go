@@ -0,0 +1,177 @@
+package scim
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"slices"
+	"uyulala/internal/db"
+	"uyulala/internal/db/groupdb"
+
+	"github.com/gin-gonic/gin"
+)
+
+var groups = &lister[*Group]{
+	attributes: []string{"displayName", "externalId"},
+	count: func(ctx *gin.Context, query map[string]string) (int, error) {
+		return groupdb.Count(ctx, groupFilter(query))
+	},
+	list: func(ctx *gin.Context, query map[string]string, offset, count int) ([]*Group, error) {
+		groups, err := groupdb.List(ctx, groupFilter(query), offset, count)
+		if err != nil {
+			return nil, err
+		}
+		resources := make([]*Group, 0, len(groups))
+		for _, group := range groups {
+			resources = append(resources, groupResource(group))
+		}
+		return resources, nil
+	},
+}
+
+func groupFilter(query map[string]string) *groupdb.Filter {
+	return &groupdb.Filter{DisplayName: query["displayName"], ExternalID: query["externalId"]}
+}
+
+func listGroups(ctx *gin.Context) {
+	respondList(ctx, groups)
+}
+
+// loadGroup returns the group of the id path parameter and its resource, or aborts with 404.
+func loadGroup(ctx *gin.Context) (*groupdb.Group, *Group) {
+	group, err := groupdb.Get(ctx, ctx.Param("id"))
+	if errors.Is(err, sql.ErrNoRows) {
+		abortError(ctx, http.StatusNotFound, "", "No such group", nil)
+		return nil, nil
+	} else if err != nil {
+		abortError(ctx, http.StatusInternalServerError, "", "Failed to get group", err)
+		return nil, nil
+	}
+	return group, groupResource(group)
+}
+
+func getGroup(ctx *gin.Context) {
+	_, resource := loadGroup(ctx)
+	if resource == nil || notModified(ctx, resource.Meta.Version) {
+		return
+	}
+	respondResource(ctx, http.StatusOK, resource, resource.Meta.Version)
+}
+
+// members returns the distinct members of req.
+func members(req *Group) []groupdb.Member {
+	res := make([]groupdb.Member, 0, len(req.Members))
+	for _, member := range req.Members {
+		if !slices.ContainsFunc(res, func(m groupdb.Member) bool { return m.UserID == member.Value }) {
+			res = append(res, groupdb.Member{UserID: member.Value})
+		}
+	}
+	return res
+}
+
+// validGroup checks the display name of req and copies it with the members to group.
+func validGroup(ctx *gin.Context, group *groupdb.Group, req *Group) bool {
+	if req.DisplayName == "" {
+		abortError(ctx, http.StatusBadRequest, "invalidValue", "displayName is required", nil)
+		return false
+	}
+	group.DisplayName = req.DisplayName
+	group.ExternalID = nullString(req.ExternalID)
+	group.Members = members(req)
+	return true
+}
+
+// abortGroupError aborts a failed write of a group, with a conflict for a taken display name and a bad request for
+// members that aren't users.
+func abortGroupError(ctx *gin.Context, err error) {
+	switch {
+	case db.IsDuplicate(err):
+		abortError(ctx, http.StatusConflict, "uniqueness", "The displayName is already taken", err)
+	case db.IsMissingReference(err):
+		abortError(ctx, http.StatusBadRequest, "invalidValue", "A member is not a user", err)
+	default:
+		abortError(ctx, http.StatusInternalServerError, "", "Failed to save group", err)
+	}
+}
+
+func createGroup(ctx *gin.Context) {
+	req := &Group{}
+	if !bindResource(ctx, req) {
+		return
+	}
+	group := &groupdb.Group{}
+	if !validGroup(ctx, group, req) {
+		return
+	}
+	if err := groupdb.Create(ctx, group); err != nil {
+		abortGroupError(ctx, err)
+		return
+	}
+	respondGroup(ctx, http.StatusCreated, group.ID)
+}
+
+// respondGroup responds with the stored group, with its location for a created group.
+func respondGroup(ctx *gin.Context, code int, groupID string) {
+	group, err := groupdb.Get(ctx, groupID)
+	if err != nil {
+		abortError(ctx, http.StatusInternalServerError, "", "Failed to get group", err)
+		return
+	}
+	resource := groupResource(group)
+	if code == http.StatusCreated {
+		ctx.Header("Location", resource.Meta.Location)
+	}
+	respondResource(ctx, code, resource, resource.Meta.Version)
+}
+
+func saveGroup(ctx *gin.Context, group *groupdb.Group, req *Group) {
+	if !validGroup(ctx, group, req) {
+		return
+	}
+	if err := groupdb.Update(ctx, group); err != nil {
+		abortGroupError(ctx, err)
+		return
+	}
+	respondGroup(ctx, http.StatusOK, group.ID)
+}
+
+func replaceGroup(ctx *gin.Context) {
+	group, resource := loadGroup(ctx)
+	if group == nil || preconditionFailed(ctx, resource.Meta.Version) {
+		return
+	}
+	req := &Group{}
+	if !bindResource(ctx, req) {
+		return
+	}
+	saveGroup(ctx, group, req)
+}
+
+func patchGroup(ctx *gin.Context) {
+	group, resource := loadGroup(ctx)
+	if group == nil || preconditionFailed(ctx, resource.Meta.Version) {
+		return
+	}
+	object := patchResource(ctx, resource)
+	if object == nil {
+		return
+	}
+	req := &Group{}
+	if err := fromMap(object, req); err != nil {
+		abortError(ctx, http.StatusBadRequest, "invalidValue", "Invalid patched group", err)
+		return
+	}
+	saveGroup(ctx, group, req)
+}
+
+func deleteGroup(ctx *gin.Context) {
+	group, resource := loadGroup(ctx)
+	if group == nil || preconditionFailed(ctx, resource.Meta.Version) {
+		return
+	}
+	if err := groupdb.Delete(ctx, group.ID); err != nil {
+		abortError(ctx, http.StatusInternalServerError, "", "Failed to delete group", err)
+		return
+	}
+	ctx.Status(http.StatusNoContent)
+}
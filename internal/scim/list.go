@@ -0,0 +1,197 @@
+package scim
+
+import (
+	"encoding/json"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxResults is the largest page of a list response.
+const maxResults = 1000
+
+type listResponse struct {
+	Schemas      []string         `json:"schemas"`
+	TotalResults int              `json:"totalResults"`
+	StartIndex   int              `json:"startIndex"`
+	ItemsPerPage int              `json:"itemsPerPage"`
+	Resources    []map[string]any `json:"Resources"`
+}
+
+func toMap(resource any) (map[string]any, error) {
+	data, err := json.Marshal(resource)
+	if err != nil {
+		return nil, err
+	}
+	var res map[string]any
+	err = json.Unmarshal(data, &res)
+	return res, err
+}
+
+func fromMap(resource map[string]any, out any) error {
+	data, err := json.Marshal(resource)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, out)
+}
+
+// project applies the attributes and excludedAttributes query parameters to the top level attributes of a resource.
+// id and schemas are always returned.
+func project(ctx *gin.Context, resource map[string]any) map[string]any {
+	names := func(param string) []string {
+		var res []string
+		for _, name := range strings.Split(ctx.Query(param), ",") {
+			if name = strings.TrimSpace(attributePath(strings.TrimSpace(name))); name != "" {
+				name, _, _ = strings.Cut(name, ".")
+				res = append(res, strings.ToLower(name))
+			}
+		}
+		return res
+	}
+	included, excluded := names("attributes"), names("excludedAttributes")
+	for name := range resource {
+		lower := strings.ToLower(name)
+		if lower == "id" || lower == "schemas" {
+			continue
+		}
+		if len(included) > 0 && !slices.Contains(included, lower) || slices.Contains(excluded, lower) {
+			delete(resource, name)
+		}
+	}
+	return resource
+}
+
+// lister loads the resources of a type for a list response.
+type lister[T any] struct {
+	// attributes are the attributes the database can filter on by equality.
+	attributes []string
+	// count returns the number of resources with the attribute values of query.
+	count func(ctx *gin.Context, query map[string]string) (int, error)
+	// list returns at most count resources with the attribute values of query from offset.
+	list func(ctx *gin.Context, query map[string]string, offset, count int) ([]T, error)
+}
+
+// equalities returns the attribute values a filter requires, when it is nothing but eq comparisons of attributes
+// joined by and, so that the database can evaluate it.
+func equalities(f filter, attributes []string) (map[string]string, bool) {
+	switch f := f.(type) {
+	case nil:
+		return map[string]string{}, true
+	case *attributeFilter:
+		value, ok := f.value.(string)
+		if f.op != "eq" || !ok || value == "" {
+			return nil, false
+		}
+		for _, attribute := range attributes {
+			if strings.EqualFold(f.path, attribute) {
+				return map[string]string{attribute: value}, true
+			}
+		}
+	case *logicalFilter:
+		left, ok := equalities(f.left, attributes)
+		if !f.and || !ok {
+			return nil, false
+		}
+		right, ok := equalities(f.right, attributes)
+		if !ok {
+			return nil, false
+		}
+		for attribute, value := range right {
+			if other, ok := left[attribute]; ok && !strings.EqualFold(other, value) {
+				return nil, false
+			}
+			left[attribute] = value
+		}
+		return left, true
+	}
+	return nil, false
+}
+
+// respondList responds with the resources matching the filter query parameter, paginated by startIndex and count.
+// Filters the database can't evaluate are matched against the resources, loaded in batches.
+func respondList[T any](ctx *gin.Context, l *lister[T]) {
+	f, err := parseFilter(ctx.Query("filter"))
+	if err != nil {
+		abortError(ctx, http.StatusBadRequest, "invalidFilter", "Invalid filter", err)
+		return
+	}
+	startIndex, count := 1, maxResults
+	if value := ctx.Query("startIndex"); value != "" {
+		if startIndex, err = strconv.Atoi(value); err != nil {
+			abortError(ctx, http.StatusBadRequest, "invalidValue", "Invalid startIndex", err)
+			return
+		}
+		startIndex = max(startIndex, 1)
+	}
+	if value := ctx.Query("count"); value != "" {
+		if count, err = strconv.Atoi(value); err != nil {
+			abortError(ctx, http.StatusBadRequest, "invalidValue", "Invalid count", err)
+			return
+		}
+		count = min(max(count, 0), maxResults)
+	}
+
+	res := &listResponse{
+		Schemas:    []string{SchemaListResponse},
+		StartIndex: startIndex,
+		Resources:  []map[string]any{},
+	}
+	add := func(resource T) bool {
+		object, err := toMap(resource)
+		if err != nil {
+			abortError(ctx, http.StatusInternalServerError, "", "Unexpected error", err)
+			return false
+		}
+		res.Resources = append(res.Resources, project(ctx, object))
+		return true
+	}
+	if query, ok := equalities(f, l.attributes); ok {
+		if res.TotalResults, err = l.count(ctx, query); err != nil {
+			abortError(ctx, http.StatusInternalServerError, "", "Failed to count resources", err)
+			return
+		}
+		var resources []T
+		if count > 0 {
+			if resources, err = l.list(ctx, query, startIndex-1, count); err != nil {
+				abortError(ctx, http.StatusInternalServerError, "", "Failed to list resources", err)
+				return
+			}
+		}
+		for _, resource := range resources {
+			if !add(resource) {
+				return
+			}
+		}
+	} else {
+		for offset := 0; ; offset += maxResults {
+			resources, err := l.list(ctx, map[string]string{}, offset, maxResults)
+			if err != nil {
+				abortError(ctx, http.StatusInternalServerError, "", "Failed to list resources", err)
+				return
+			}
+			for _, resource := range resources {
+				object, err := toMap(resource)
+				if err != nil {
+					abortError(ctx, http.StatusInternalServerError, "", "Unexpected error", err)
+					return
+				}
+				if !f.match(object) {
+					continue
+				}
+				res.TotalResults++
+				if res.TotalResults >= startIndex && len(res.Resources) < count {
+					res.Resources = append(res.Resources, project(ctx, object))
+				}
+			}
+			if len(resources) < maxResults {
+				break
+			}
+		}
+	}
+	res.ItemsPerPage = len(res.Resources)
+	respond(ctx, http.StatusOK, res, "")
+}
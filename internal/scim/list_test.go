@@ -0,0 +1,113 @@
+package scim
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+type testGroup struct {
+	ID          string `json:"id"`
+	DisplayName string `json:"displayName"`
+}
+
+// call records the arguments of a list.
+type call struct {
+	query         map[string]string
+	offset, count int
+}
+
+// groupLister lists groups named by names, recording the calls to list.
+func groupLister(names []string, calls *[]call) *lister[testGroup] {
+	matching := func(query map[string]string) []testGroup {
+		var res []testGroup
+		for _, name := range names {
+			if value, ok := query["displayName"]; !ok || strings.EqualFold(value, name) {
+				res = append(res, testGroup{ID: name, DisplayName: name})
+			}
+		}
+		return res
+	}
+	return &lister[testGroup]{
+		attributes: []string{"displayName"},
+		count: func(_ *gin.Context, query map[string]string) (int, error) {
+			return len(matching(query)), nil
+		},
+		list: func(_ *gin.Context, query map[string]string, offset, count int) ([]testGroup, error) {
+			*calls = append(*calls, call{query, offset, count})
+			res := matching(query)
+			return res[min(offset, len(res)):min(offset+count, len(res))], nil
+		},
+	}
+}
+
+func TestRespondList(t *testing.T) {
+	names := []string{"Admins", "Tour Guides", "Travellers", "Ops"}
+	all := map[string]string{}
+	tests := []struct {
+		name  string
+		query url.Values
+		want  []string
+		total int
+		calls []call
+	}{
+		{"all", url.Values{}, names, 4, []call{{all, 0, maxResults}}},
+		{"page", url.Values{"startIndex": {"2"}, "count": {"2"}}, []string{"Tour Guides", "Travellers"}, 4,
+			[]call{{all, 1, 2}}},
+		{"count only", url.Values{"count": {"0"}}, nil, 4, nil},
+		{"equality in the database", url.Values{"filter": {`displayName eq "ops"`}}, []string{"Ops"}, 1,
+			[]call{{map[string]string{"displayName": "ops"}, 0, maxResults}}},
+		{"other filter", url.Values{"filter": {`displayName sw "t"`}, "count": {"1"}}, []string{"Tour Guides"}, 2,
+			[]call{{all, 0, maxResults}}},
+		{"page of other filter", url.Values{"filter": {`displayName sw "t"`}, "startIndex": {"2"}},
+			[]string{"Travellers"}, 2, []call{{all, 0, maxResults}}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var calls []call
+			recorder := httptest.NewRecorder()
+			ctx, _ := gin.CreateTestContext(recorder)
+			ctx.Request = httptest.NewRequest(http.MethodGet, "/scim/v2/Groups?"+test.query.Encode(), nil)
+			respondList(ctx, groupLister(names, &calls))
+			if recorder.Code != http.StatusOK {
+				t.Fatalf("status = %d, body %s", recorder.Code, recorder.Body)
+			}
+			var res listResponse
+			if err := json.Unmarshal(recorder.Body.Bytes(), &res); err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, resource := range res.Resources {
+				got = append(got, resource["displayName"].(string))
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("resources = %q, want %q", got, test.want)
+			}
+			if res.TotalResults != test.total || res.ItemsPerPage != len(test.want) {
+				t.Errorf("totalResults = %d, itemsPerPage = %d, want %d, %d",
+					res.TotalResults, res.ItemsPerPage, test.total, len(test.want))
+			}
+			if !reflect.DeepEqual(calls, test.calls) {
+				t.Errorf("calls = %v, want %v", calls, test.calls)
+			}
+		})
+	}
+}
+
+func TestRespondListInvalid(t *testing.T) {
+	for _, query := range []string{"filter=displayName+xx+%22a%22", "startIndex=a", "count=a"} {
+		recorder := httptest.NewRecorder()
+		ctx, _ := gin.CreateTestContext(recorder)
+		ctx.Request = httptest.NewRequest(http.MethodGet, "/scim/v2/Groups?"+query, nil)
+		respondList(ctx, groupLister(nil, new([]call)))
+		if recorder.Code != http.StatusBadRequest {
+			t.Errorf("%s: status = %d, want %d", query, recorder.Code, http.StatusBadRequest)
+		}
+	}
+}
@@ -0,0 +1,210 @@
+package scim
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+)
+
+var (
+	ErrInvalidPath  = errors.New("invalid patch path")
+	ErrNoTarget     = errors.New("the patch path matched no value")
+	ErrInvalidValue = errors.New("invalid patch value")
+	ErrInvalidPatch = errors.New("invalid patch operation")
+)
+
+type patchRequest struct {
+	Schemas    []string         `json:"schemas"`
+	Operations []patchOperation `json:"Operations"`
+}
+
+type patchOperation struct {
+	Op    string          `json:"op"`
+	Path  string          `json:"path"`
+	Value json.RawMessage `json:"value"`
+}
+
+// scimType returns the SCIM error type of a patch error.
+func scimType(err error) string {
+	switch {
+	case errors.Is(err, ErrInvalidPath):
+		return "invalidPath"
+	case errors.Is(err, ErrNoTarget):
+		return "noTarget"
+	case errors.Is(err, ErrInvalidFilter):
+		return "invalidFilter"
+	}
+	return "invalidValue"
+}
+
+// applyPatch applies the operations of a PATCH request (RFC 7644 3.5.2) to the JSON representation of a resource.
+func applyPatch(resource map[string]any, operations []patchOperation) error {
+	for _, operation := range operations {
+		op := strings.ToLower(operation.Op)
+		var value any
+		if len(operation.Value) > 0 {
+			if err := json.Unmarshal(operation.Value, &value); err != nil {
+				return ErrInvalidValue
+			}
+		}
+		switch {
+		case op != "add" && op != "replace" && op != "remove":
+			return ErrInvalidPatch
+		case operation.Path == "" && op == "remove":
+			return ErrNoTarget
+		case operation.Path == "":
+			// Without a path, the value holds the attributes to add or replace.
+			attributes, ok := value.(map[string]any)
+			if !ok {
+				return ErrInvalidValue
+			}
+			for name, value := range attributes {
+				if err := patchPath(resource, op, name, value); err != nil {
+					return err
+				}
+			}
+		default:
+			if err := patchPath(resource, op, operation.Path, value); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// patchPath applies an operation to path, either attr, attr.sub or attr[filter] and attr[filter].sub.
+func patchPath(resource map[string]any, op, path string, value any) error {
+	path = attributePath(path)
+	name, sub, expr := path, "", ""
+	if start := strings.IndexByte(path, '['); start >= 0 {
+		end := strings.LastIndexByte(path, ']')
+		if end < start {
+			return ErrInvalidPath
+		}
+		name, expr = path[:start], path[start+1:end]
+		if rest := path[end+1:]; rest != "" {
+			if !strings.HasPrefix(rest, ".") {
+				return ErrInvalidPath
+			}
+			sub = rest[1:]
+		}
+	} else {
+		name, sub, _ = strings.Cut(path, ".")
+	}
+	if name == "" {
+		return ErrInvalidPath
+	}
+	name = key(resource, name)
+
+	if expr != "" {
+		f, err := parseFilter(expr)
+		if err != nil {
+			return err
+		}
+		return patchElements(resource, op, name, sub, f, value)
+	}
+	if sub != "" {
+		object, ok := resource[name].(map[string]any)
+		if !ok {
+			if op == "remove" {
+				return nil
+			}
+			object = map[string]any{}
+			resource[name] = object
+		}
+		return patchPath(object, op, sub, value)
+	}
+
+	existing, exists := resource[name]
+	list, multiValued := existing.([]any)
+	switch op {
+	case "remove":
+		if values, ok := value.([]any); ok && multiValued {
+			// Some clients remove members by value instead of with a filter.
+			resource[name] = deleteValues(list, values)
+		} else {
+			delete(resource, name)
+		}
+	case "add":
+		if values, ok := value.([]any); ok && (multiValued || !exists) {
+			for _, value := range values {
+				if !containsValue(list, value) {
+					list = append(list, value)
+				}
+			}
+			resource[name] = list
+		} else {
+			resource[name] = value
+		}
+	case "replace":
+		resource[name] = value
+	}
+	return nil
+}
+
+// patchElements applies an operation to the elements of a multi-valued attribute matching f.
+func patchElements(resource map[string]any, op, name, sub string, f filter, value any) error {
+	list, _ := resource[name].([]any)
+	var res []any
+	matched := false
+	for _, element := range list {
+		object, ok := element.(map[string]any)
+		if !ok || !f.match(object) {
+			res = append(res, element)
+			continue
+		}
+		matched = true
+		switch {
+		case op == "remove" && sub == "":
+			continue
+		case op == "remove":
+			delete(object, key(object, sub))
+		case sub == "":
+			replacement, ok := value.(map[string]any)
+			if !ok {
+				return ErrInvalidValue
+			}
+			object = replacement
+		default:
+			object[key(object, sub)] = value
+		}
+		res = append(res, object)
+	}
+	if !matched && op != "remove" {
+		return ErrNoTarget
+	}
+	resource[name] = res
+	return nil
+}
+
+func containsValue(list []any, value any) bool {
+	for _, element := range list {
+		if sameValue(element, value) {
+			return true
+		}
+	}
+	return false
+}
+
+// sameValue compares elements of multi-valued attributes by their value sub-attribute when they have one.
+func sameValue(a, b any) bool {
+	objectA, okA := a.(map[string]any)
+	objectB, okB := b.(map[string]any)
+	if okA && okB {
+		if valueA, ok := objectA["value"]; ok {
+			return reflect.DeepEqual(valueA, objectB["value"])
+		}
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+func deleteValues(list, values []any) []any {
+	var res []any
+	for _, element := range list {
+		if !containsValue(values, element) {
+			res = append(res, element)
+		}
+	}
+	return res
+}
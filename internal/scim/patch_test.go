@@ -0,0 +1,144 @@
+package scim
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestApplyPatch(t *testing.T) {
+	const group = `{
+		"displayName": "Tour Guides",
+		"members": [{"value": "a", "display": "alice"}, {"value": "b", "display": "bob"}]
+	}`
+	const user = `{
+		"userName": "bjensen",
+		"name": {"givenName": "Barbara"},
+		"emails": [{"value": "bjensen@example.com", "type": "work", "primary": true}],
+		"active": true
+	}`
+	tests := []struct {
+		name       string
+		resource   string
+		operations string
+		want       string
+	}{
+		// Single-valued attributes
+		{"replace single", user, `[{"op": "replace", "path": "userName", "value": "babs"}]`,
+			`{"userName": "babs", "name": {"givenName": "Barbara"},
+			  "emails": [{"value": "bjensen@example.com", "type": "work", "primary": true}], "active": true}`},
+		{"add single", user, `[{"op": "add", "path": "displayName", "value": "Babs"}]`,
+			`{"userName": "bjensen", "displayName": "Babs", "name": {"givenName": "Barbara"},
+			  "emails": [{"value": "bjensen@example.com", "type": "work", "primary": true}], "active": true}`},
+		{"remove single", user, `[{"op": "remove", "path": "name"}]`,
+			`{"userName": "bjensen", "emails": [{"value": "bjensen@example.com", "type": "work", "primary": true}],
+			  "active": true}`},
+		{"op and path are case-insensitive", user, `[{"op": "Replace", "path": "ACTIVE", "value": false}]`,
+			`{"userName": "bjensen", "name": {"givenName": "Barbara"},
+			  "emails": [{"value": "bjensen@example.com", "type": "work", "primary": true}], "active": false}`},
+		{"sub-attribute", user, `[{"op": "add", "path": "name.familyName", "value": "Jensen"},
+			{"op": "remove", "path": "name.givenName"}]`,
+			`{"userName": "bjensen", "name": {"familyName": "Jensen"},
+			  "emails": [{"value": "bjensen@example.com", "type": "work", "primary": true}], "active": true}`},
+		{"schema qualified path", user,
+			`[{"op": "replace", "path": "urn:ietf:params:scim:schemas:core:2.0:User:name.givenName", "value": "Babs"}]`,
+			`{"userName": "bjensen", "name": {"givenName": "Babs"},
+			  "emails": [{"value": "bjensen@example.com", "type": "work", "primary": true}], "active": true}`},
+		{"without path", user, `[{"op": "replace", "value": {"userName": "babs", "active": false}}]`,
+			`{"userName": "babs", "name": {"givenName": "Barbara"},
+			  "emails": [{"value": "bjensen@example.com", "type": "work", "primary": true}], "active": false}`},
+		// Multi-valued attributes
+		{"add members", group, `[{"op": "add", "path": "members", "value": [{"value": "c"}, {"value": "a"}]}]`,
+			`{"displayName": "Tour Guides",
+			  "members": [{"value": "a", "display": "alice"}, {"value": "b", "display": "bob"}, {"value": "c"}]}`},
+		{"add to a missing attribute", `{"displayName": "Empty"}`, `[{"op": "add", "path": "members", "value": [{"value": "a"}]}]`,
+			`{"displayName": "Empty", "members": [{"value": "a"}]}`},
+		{"replace members", group, `[{"op": "replace", "path": "members", "value": [{"value": "c"}]}]`,
+			`{"displayName": "Tour Guides", "members": [{"value": "c"}]}`},
+		{"remove member by filter", group, `[{"op": "remove", "path": "members[value eq \"a\"]"}]`,
+			`{"displayName": "Tour Guides", "members": [{"value": "b", "display": "bob"}]}`},
+		{"remove members by value", group, `[{"op": "remove", "path": "members", "value": [{"value": "b"}]}]`,
+			`{"displayName": "Tour Guides", "members": [{"value": "a", "display": "alice"}]}`},
+		{"remove all members", group, `[{"op": "remove", "path": "members"}]`, `{"displayName": "Tour Guides"}`},
+		{"remove unmatched member", group, `[{"op": "remove", "path": "members[value eq \"x\"]"}]`,
+			`{"displayName": "Tour Guides",
+			  "members": [{"value": "a", "display": "alice"}, {"value": "b", "display": "bob"}]}`},
+		{"replace sub-attribute of matched element", user,
+			`[{"op": "replace", "path": "emails[type eq \"work\"].value", "value": "babs@example.com"}]`,
+			`{"userName": "bjensen", "name": {"givenName": "Barbara"},
+			  "emails": [{"value": "babs@example.com", "type": "work", "primary": true}], "active": true}`},
+		{"remove sub-attribute of matched element", user, `[{"op": "remove", "path": "emails[primary eq true].type"}]`,
+			`{"userName": "bjensen", "name": {"givenName": "Barbara"},
+			  "emails": [{"value": "bjensen@example.com", "primary": true}], "active": true}`},
+		{"replace matched element", group,
+			`[{"op": "replace", "path": "members[value eq \"b\"]", "value": {"value": "c", "display": "carol"}}]`,
+			`{"displayName": "Tour Guides",
+			  "members": [{"value": "a", "display": "alice"}, {"value": "c", "display": "carol"}]}`},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var resource, want map[string]any
+			var operations []patchOperation
+			for data, v := range map[string]any{test.resource: &resource, test.operations: &operations, test.want: &want} {
+				if err := json.Unmarshal([]byte(data), v); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if err := applyPatch(resource, operations); err != nil {
+				t.Fatal(err)
+			}
+			// An emptied multi-valued attribute is the same as a missing one.
+			if members, ok := resource["members"]; ok && members == nil {
+				delete(resource, "members")
+			}
+			if !reflect.DeepEqual(resource, want) {
+				got, _ := json.Marshal(resource)
+				t.Fatalf("got %s", got)
+			}
+		})
+	}
+}
+
+func TestApplyPatchErrors(t *testing.T) {
+	tests := []struct {
+		name       string
+		operations string
+		want       error
+		scimType   string
+	}{
+		{"unknown op", `[{"op": "move", "path": "userName"}]`, ErrInvalidPatch, "invalidValue"},
+		{"remove without path", `[{"op": "remove"}]`, ErrNoTarget, "noTarget"},
+		{"add without path or object", `[{"op": "add", "value": "x"}]`, ErrInvalidValue, "invalidValue"},
+		{"unterminated filter", `[{"op": "remove", "path": "members]value eq \"a\"["}]`, ErrInvalidPath, "invalidPath"},
+		{"text after filter", `[{"op": "remove", "path": "members[value eq \"a\"]value"}]`, ErrInvalidPath, "invalidPath"},
+		{"invalid filter", `[{"op": "remove", "path": "members[value xx \"a\"]"}]`, ErrInvalidFilter, "invalidFilter"},
+		{"replace unmatched element", `[{"op": "replace", "path": "members[value eq \"x\"].display", "value": "x"}]`,
+			ErrNoTarget, "noTarget"},
+		{"replace element with a non-object", `[{"op": "replace", "path": "members[value eq \"a\"]", "value": "x"}]`,
+			ErrInvalidValue, "invalidValue"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			resource := map[string]any{"userName": "bjensen", "members": []any{map[string]any{"value": "a"}}}
+			var operations []patchOperation
+			if err := json.Unmarshal([]byte(test.operations), &operations); err != nil {
+				t.Fatal(err)
+			}
+			err := applyPatch(resource, operations)
+			if !errors.Is(err, test.want) {
+				t.Fatalf("got %v, want %v", err, test.want)
+			}
+			if got := scimType(err); got != test.scimType {
+				t.Fatalf("scimType %q, want %q", got, test.scimType)
+			}
+		})
+	}
+}
+
+func TestApplyPatchInvalidJSON(t *testing.T) {
+	err := applyPatch(map[string]any{}, []patchOperation{{Op: "add", Path: "userName", Value: json.RawMessage("nope")}})
+	if !errors.Is(err, ErrInvalidValue) {
+		t.Fatalf("got %v, want %v", err, ErrInvalidValue)
+	}
+}
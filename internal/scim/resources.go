@@ -0,0 +1,173 @@
+package scim
+
+import (
+	"database/sql"
+	"time"
+	"uyulala/internal/db/groupdb"
+	"uyulala/internal/db/userdb"
+
+	"github.com/gin-gonic/gin"
+	"github.com/spf13/viper"
+)
+
+type Meta struct {
+	ResourceType string    `json:"resourceType"`
+	Created      time.Time `json:"created"`
+	LastModified time.Time `json:"lastModified"`
+	Location     string    `json:"location"`
+	Version      string    `json:"version,omitempty"`
+}
+
+type Name struct {
+	Formatted  string `json:"formatted,omitempty"`
+	GivenName  string `json:"givenName,omitempty"`
+	FamilyName string `json:"familyName,omitempty"`
+}
+
+type Email struct {
+	Value   string `json:"value"`
+	Type    string `json:"type,omitempty"`
+	Primary bool   `json:"primary,omitempty"`
+}
+
+// Reference is a member of a group or a group of a user.
+type Reference struct {
+	Value   string `json:"value"`
+	Ref     string `json:"$ref,omitempty"`
+	Display string `json:"display,omitempty"`
+}
+
+// User is the SCIM representation of a user (RFC 7643 4.1). The profile is stored as the user attributes: displayName
+// (or name.formatted) as name, name.givenName as given_name, name.familyName as family_name, the primary email as
+// email and locale as locale.
+type User struct {
+	Schemas     []string    `json:"schemas"`
+	ID          string      `json:"id,omitempty"`
+	ExternalID  string      `json:"externalId,omitempty"`
+	UserName    string      `json:"userName"`
+	Name        *Name       `json:"name,omitempty"`
+	DisplayName string      `json:"displayName,omitempty"`
+	Locale      string      `json:"locale,omitempty"`
+	Emails      []Email     `json:"emails,omitempty"`
+	Active      *bool       `json:"active,omitempty"`
+	Groups      []Reference `json:"groups,omitempty"`
+	Meta        *Meta       `json:"meta,omitempty"`
+}
+
+// Group is the SCIM representation of a group (RFC 7643 4.2).
+type Group struct {
+	Schemas     []string    `json:"schemas"`
+	ID          string      `json:"id,omitempty"`
+	ExternalID  string      `json:"externalId,omitempty"`
+	DisplayName string      `json:"displayName"`
+	Members     []Reference `json:"members,omitempty"`
+	Meta        *Meta       `json:"meta,omitempty"`
+}
+
+func location(resourceType, id string) string {
+	return viper.GetString("issuer") + "/scim/v2/" + resourceType + "/" + id
+}
+
+func nullString(value string) sql.NullString {
+	return sql.NullString{String: value, Valid: value != ""}
+}
+
+func attributeString(attributes map[string]any, name string) string {
+	value, _ := attributes[name].(string)
+	return value
+}
+
+func userResource(ctx *gin.Context, user *userdb.ProvisionedUser) (*User, error) {
+	attributes, err := userdb.GetAttributes(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+	groups, err := groupdb.ListForUser(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+	active := user.Active
+	res := &User{
+		Schemas:     []string{SchemaUser},
+		ID:          user.ID,
+		ExternalID:  user.ExternalID.String,
+		UserName:    user.UserName.String,
+		DisplayName: attributeString(attributes, "name"),
+		Locale:      attributeString(attributes, "locale"),
+		Active:      &active,
+		Meta: &Meta{
+			ResourceType: "User",
+			Created:      user.Created,
+			LastModified: user.Modified,
+			Location:     location("Users", user.ID),
+		},
+	}
+	name := &Name{
+		Formatted:  res.DisplayName,
+		GivenName:  attributeString(attributes, "given_name"),
+		FamilyName: attributeString(attributes, "family_name"),
+	}
+	if *name != (Name{}) {
+		res.Name = name
+	}
+	if email := attributeString(attributes, "email"); email != "" {
+		res.Emails = []Email{{Value: email, Primary: true}}
+	}
+	for _, group := range groups {
+		res.Groups = append(res.Groups, Reference{Value: group.ID, Ref: location("Groups", group.ID), Display: group.DisplayName})
+	}
+	res.Meta.Version = version(res)
+	return res, nil
+}
+
+// profile returns the user attributes of a SCIM user, unset ones as nil to remove them.
+func (u *User) profile() map[string]any {
+	attributes := map[string]any{"name": nil, "given_name": nil, "family_name": nil, "email": nil, "locale": nil}
+	set := func(name, value string) {
+		if value != "" {
+			attributes[name] = value
+		}
+	}
+	set("name", u.DisplayName)
+	if u.Name != nil {
+		if u.DisplayName == "" {
+			set("name", u.Name.Formatted)
+		}
+		set("given_name", u.Name.GivenName)
+		set("family_name", u.Name.FamilyName)
+	}
+	for i, email := range u.Emails {
+		if email.Primary || i == 0 {
+			set("email", email.Value)
+		}
+		if email.Primary {
+			break
+		}
+	}
+	set("locale", u.Locale)
+	return attributes
+}
+
+func groupResource(group *groupdb.Group) *Group {
+	res := &Group{
+		Schemas:     []string{SchemaGroup},
+		ID:          group.ID,
+		ExternalID:  group.ExternalID.String,
+		DisplayName: group.DisplayName,
+		Meta: &Meta{
+			ResourceType: "Group",
+			Created:      group.Created,
+			LastModified: group.Modified,
+			Location:     location("Groups", group.ID),
+		},
+	}
+	for _, member := range group.Members {
+		res.Members = append(res.Members, Reference{
+			Value:   member.UserID,
+			Ref:     location("Users", member.UserID),
+			Display: member.UserName.String,
+		})
+	}
+	res.Meta.Version = version(res)
+	return res
+}
@@ -0,0 +1,99 @@
+package scim
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	ContentType = "application/scim+json"
+
+	SchemaUser                  = "urn:ietf:params:scim:schemas:core:2.0:User"
+	SchemaGroup                 = "urn:ietf:params:scim:schemas:core:2.0:Group"
+	SchemaListResponse          = "urn:ietf:params:scim:api:messages:2.0:ListResponse"
+	SchemaPatchOp               = "urn:ietf:params:scim:api:messages:2.0:PatchOp"
+	SchemaError                 = "urn:ietf:params:scim:api:messages:2.0:Error"
+	SchemaServiceProviderConfig = "urn:ietf:params:scim:schemas:core:2.0:ServiceProviderConfig"
+	SchemaResourceType          = "urn:ietf:params:scim:schemas:core:2.0:ResourceType"
+)
+
+// Error is a SCIM error response (RFC 7644 3.12).
+type Error struct {
+	Schemas  []string `json:"schemas"`
+	Status   string   `json:"status"`
+	ScimType string   `json:"scimType,omitempty"`
+	Detail   string   `json:"detail,omitempty"`
+}
+
+// abortError aborts the request with a SCIM error, scimType is one of the error types of RFC 7644 3.12 or empty.
+func abortError(ctx *gin.Context, code int, scimType, detail string, err error) {
+	if err != nil {
+		slog.Info("SCIM error", "status", code, "scimType", scimType, "error", err)
+	}
+	data, _ := json.Marshal(&Error{
+		Schemas:  []string{SchemaError},
+		Status:   strconv.Itoa(code),
+		ScimType: scimType,
+		Detail:   detail,
+	})
+	ctx.Abort()
+	ctx.Data(code, ContentType, data)
+}
+
+// respond writes resource as a SCIM response, with etag as ETag unless empty.
+func respond(ctx *gin.Context, code int, resource any, etag string) {
+	data, err := json.Marshal(resource)
+	if err != nil {
+		abortError(ctx, http.StatusInternalServerError, "", "Unexpected error", err)
+		return
+	}
+	if etag != "" {
+		ctx.Header("ETag", etag)
+	}
+	ctx.Data(code, ContentType, data)
+}
+
+// version returns the weak ETag of a resource, a hash of its representation without meta.version.
+func version(resource any) string {
+	data, _ := json.Marshal(resource)
+	sum := sha256.Sum256(data)
+	return `W/"` + hex.EncodeToString(sum[:8]) + `"`
+}
+
+// notModified reports whether the If-None-Match header of a GET matches etag, and responds 304 if it does.
+func notModified(ctx *gin.Context, etag string) bool {
+	if matchesETag(ctx.GetHeader("If-None-Match"), etag) {
+		ctx.Header("ETag", etag)
+		ctx.Status(http.StatusNotModified)
+		return true
+	}
+	return false
+}
+
+// preconditionFailed reports whether the If-Match header of a modifying request doesn't match etag, and aborts
+// with 412 if so.
+func preconditionFailed(ctx *gin.Context, etag string) bool {
+	header := ctx.GetHeader("If-Match")
+	if header == "" || matchesETag(header, etag) {
+		return false
+	}
+	abortError(ctx, http.StatusPreconditionFailed, "", "The resource has been modified", nil)
+	return true
+}
+
+func matchesETag(header, etag string) bool {
+	for _, value := range strings.Split(header, ",") {
+		value = strings.TrimSpace(value)
+		if value == "*" || strings.TrimPrefix(value, "W/") == strings.TrimPrefix(etag, "W/") {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,93 @@
+package scim
+
+import (
+	"net/http"
+	"uyulala/internal/api/application"
+
+	"github.com/gin-contrib/cors"
+	"github.com/gin-gonic/gin"
+	"github.com/spf13/viper"
+)
+
+// AddRoutes adds the SCIM 2.0 endpoints (RFC 7644), authenticated with the credentials of an admin application.
+func AddRoutes(g *gin.RouterGroup) {
+	g.Use(
+		cors.New(cors.Config{
+			AllowAllOrigins:  true,
+			AllowMethods:     []string{http.MethodGet, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete},
+			AllowHeaders:     []string{"Authorization", "*"},
+			ExposeHeaders:    []string{"ETag", "Location"},
+			AllowCredentials: true,
+		}),
+		application.ClientMiddleware(),
+		application.AdminMiddleware(),
+	)
+
+	g.GET("/ServiceProviderConfig", serviceProviderConfig)
+	g.GET("/ResourceTypes", resourceTypes)
+
+	g.GET("/Users", listUsers)
+	g.POST("/Users", createUser)
+	g.GET("/Users/:id", getUser)
+	g.PUT("/Users/:id", replaceUser)
+	g.PATCH("/Users/:id", patchUser)
+	g.DELETE("/Users/:id", deleteUser)
+
+	g.GET("/Groups", listGroups)
+	g.POST("/Groups", createGroup)
+	g.GET("/Groups/:id", getGroup)
+	g.PUT("/Groups/:id", replaceGroup)
+	g.PATCH("/Groups/:id", patchGroup)
+	g.DELETE("/Groups/:id", deleteGroup)
+}
+
+func serviceProviderConfig(ctx *gin.Context) {
+	supported := func(supported bool) map[string]any {
+		return map[string]any{"supported": supported}
+	}
+	respond(ctx, http.StatusOK, map[string]any{
+		"schemas":        []string{SchemaServiceProviderConfig},
+		"patch":          supported(true),
+		"bulk":           map[string]any{"supported": false, "maxOperations": 0, "maxPayloadSize": 0},
+		"filter":         map[string]any{"supported": true, "maxResults": maxResults},
+		"changePassword": supported(false),
+		"sort":           supported(false),
+		"etag":           supported(true),
+		"authenticationSchemes": []map[string]any{{
+			"type":        "httpbasic",
+			"name":        "HTTP Basic",
+			"description": "The client id and secret of an admin application",
+		}},
+		"meta": map[string]any{
+			"resourceType": "ServiceProviderConfig",
+			"location":     viper.GetString("issuer") + "/scim/v2/ServiceProviderConfig",
+		},
+	}, "")
+}
+
+func resourceTypes(ctx *gin.Context) {
+	resourceType := func(name, endpoint, schema string) map[string]any {
+		return map[string]any{
+			"schemas":  []string{SchemaResourceType},
+			"id":       name,
+			"name":     name,
+			"endpoint": endpoint,
+			"schema":   schema,
+			"meta": map[string]any{
+				"resourceType": "ResourceType",
+				"location":     viper.GetString("issuer") + "/scim/v2/ResourceTypes/" + name,
+			},
+		}
+	}
+	types := []map[string]any{
+		resourceType("User", "/Users", SchemaUser),
+		resourceType("Group", "/Groups", SchemaGroup),
+	}
+	respond(ctx, http.StatusOK, &listResponse{
+		Schemas:      []string{SchemaListResponse},
+		TotalResults: len(types),
+		StartIndex:   1,
+		ItemsPerPage: len(types),
+		Resources:    types,
+	}, "")
+}
@@ -0,0 +1,306 @@
+package scim
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"uyulala/internal/api/token"
+	"uyulala/internal/db"
+	"uyulala/internal/db/userdb"
+
+	"github.com/gin-gonic/gin"
+)
+
+var users = &lister[*User]{
+	attributes: []string{"userName", "externalId"},
+	count: func(ctx *gin.Context, query map[string]string) (int, error) {
+		return userdb.CountProvisionedUsers(ctx, userFilter(query))
+	},
+	list: func(ctx *gin.Context, query map[string]string, offset, count int) ([]*User, error) {
+		users, err := userdb.ListProvisionedUsers(ctx, userFilter(query), offset, count)
+		if err != nil {
+			return nil, err
+		}
+		resources := make([]*User, 0, len(users))
+		for _, user := range users {
+			resource, err := userResource(ctx, user)
+			if err != nil {
+				return nil, err
+			}
+			resources = append(resources, resource)
+		}
+		return resources, nil
+	},
+}
+
+func userFilter(query map[string]string) *userdb.ProvisionedUserFilter {
+	return &userdb.ProvisionedUserFilter{UserName: query["userName"], ExternalID: query["externalId"]}
+}
+
+func listUsers(ctx *gin.Context) {
+	respondList(ctx, users)
+}
+
+// getProvisionedUser returns the user of the id path parameter, or aborts with 404.
+func getProvisionedUser(ctx *gin.Context) *userdb.ProvisionedUser {
+	user, err := userdb.GetProvisionedUser(ctx, ctx.Param("id"))
+	if errors.Is(err, sql.ErrNoRows) {
+		abortError(ctx, http.StatusNotFound, "", "No such user", nil)
+		return nil
+	} else if err != nil {
+		abortError(ctx, http.StatusInternalServerError, "", "Failed to get user", err)
+		return nil
+	}
+	return user
+}
+
+func getUser(ctx *gin.Context) {
+	user := getProvisionedUser(ctx)
+	if user == nil {
+		return
+	}
+	resource, err := userResource(ctx, user)
+	if err != nil {
+		abortError(ctx, http.StatusInternalServerError, "", "Failed to get user", err)
+		return
+	}
+	if notModified(ctx, resource.Meta.Version) {
+		return
+	}
+	respondResource(ctx, http.StatusOK, resource, resource.Meta.Version)
+}
+
+// respondResource responds with a single resource, projected by the attributes query parameters.
+func respondResource(ctx *gin.Context, code int, resource any, etag string) {
+	object, err := toMap(resource)
+	if err != nil {
+		abortError(ctx, http.StatusInternalServerError, "", "Unexpected error", err)
+		return
+	}
+	respond(ctx, code, project(ctx, object), etag)
+}
+
+// bindResource decodes the request body into resource, accepting booleans sent as strings the way some
+// provisioning clients do.
+func bindResource(ctx *gin.Context, resource any) bool {
+	var object map[string]any
+	if err := json.NewDecoder(ctx.Request.Body).Decode(&object); err != nil {
+		abortError(ctx, http.StatusBadRequest, "invalidSyntax", "Invalid request body", err)
+		return false
+	}
+	if err := fromMap(normalize(object), resource); err != nil {
+		abortError(ctx, http.StatusBadRequest, "invalidValue", "Invalid request body", err)
+		return false
+	}
+	return true
+}
+
+// normalize converts the top level attributes active and primary of the emails of a resource from strings to
+// booleans.
+func normalize(object map[string]any) map[string]any {
+	toBool := func(object map[string]any, name string) {
+		k := key(object, name)
+		if value, ok := object[k].(string); ok {
+			object[k] = strings.EqualFold(value, "true")
+		}
+	}
+	toBool(object, "active")
+	if emails, ok := object[key(object, "emails")].([]any); ok {
+		for _, email := range emails {
+			if email, ok := email.(map[string]any); ok {
+				toBool(email, "primary")
+			}
+		}
+	}
+	return object
+}
+
+func createUser(ctx *gin.Context) {
+	req := &User{}
+	if !bindResource(ctx, req) {
+		return
+	}
+	if req.UserName == "" {
+		abortError(ctx, http.StatusBadRequest, "invalidValue", "userName is required", nil)
+		return
+	}
+	attributes := req.profile()
+	if err := token.ValidateAttributes(attributes); err != nil {
+		abortError(ctx, http.StatusBadRequest, "invalidValue", "Invalid user attributes", err)
+		return
+	}
+	user := &userdb.ProvisionedUser{
+		ExternalID: nullString(req.ExternalID),
+		UserName:   nullString(req.UserName),
+		Active:     req.Active == nil || *req.Active,
+	}
+	if err := userdb.CreateProvisionedUser(ctx, user); db.IsDuplicate(err) {
+		abortError(ctx, http.StatusConflict, "uniqueness", "The userName is already taken", err)
+		return
+	} else if err != nil {
+		abortError(ctx, http.StatusInternalServerError, "", "Failed to create user", err)
+		return
+	}
+	if err := userdb.SetAttributes(ctx, user.ID, attributes); err != nil {
+		abortError(ctx, http.StatusInternalServerError, "", "Failed to set user attributes", err)
+		return
+	}
+	respondUser(ctx, http.StatusCreated, user.ID)
+}
+
+// respondUser responds with the stored user, with its location for a created user.
+func respondUser(ctx *gin.Context, code int, userID string) {
+	user, err := userdb.GetProvisionedUser(ctx, userID)
+	if err != nil {
+		abortError(ctx, http.StatusInternalServerError, "", "Failed to get user", err)
+		return
+	}
+	resource, err := userResource(ctx, user)
+	if err != nil {
+		abortError(ctx, http.StatusInternalServerError, "", "Failed to get user", err)
+		return
+	}
+	if code == http.StatusCreated {
+		ctx.Header("Location", resource.Meta.Location)
+	}
+	respondResource(ctx, code, resource, resource.Meta.Version)
+}
+
+// saveUser replaces a user with req. The email is no longer verified when it changes, and deactivating a user
+// ends its sessions.
+func saveUser(ctx *gin.Context, user *userdb.ProvisionedUser, req *User) {
+	if req.UserName == "" {
+		abortError(ctx, http.StatusBadRequest, "invalidValue", "userName is required", nil)
+		return
+	}
+	attributes := req.profile()
+	if err := token.ValidateAttributes(attributes); err != nil {
+		abortError(ctx, http.StatusBadRequest, "invalidValue", "Invalid user attributes", err)
+		return
+	}
+	current, err := userdb.GetAttributes(ctx, user.ID)
+	if err != nil {
+		abortError(ctx, http.StatusInternalServerError, "", "Failed to get user attributes", err)
+		return
+	}
+	if attributes["email"] != current["email"] {
+		attributes["email_verified"] = nil
+	}
+
+	wasActive := user.Active
+	user.ExternalID = nullString(req.ExternalID)
+	user.UserName = nullString(req.UserName)
+	if req.Active != nil {
+		user.Active = *req.Active
+	}
+	if err := userdb.UpdateProvisionedUser(ctx, user); db.IsDuplicate(err) {
+		abortError(ctx, http.StatusConflict, "uniqueness", "The userName is already taken", err)
+		return
+	} else if err != nil {
+		abortError(ctx, http.StatusInternalServerError, "", "Failed to update user", err)
+		return
+	}
+	if err := userdb.SetAttributes(ctx, user.ID, attributes); err != nil {
+		abortError(ctx, http.StatusInternalServerError, "", "Failed to set user attributes", err)
+		return
+	}
+	if wasActive && !user.Active {
+		if err := token.EndUserSessions(ctx, user.ID); err != nil {
+			abortError(ctx, http.StatusInternalServerError, "", "Failed to end user sessions", err)
+			return
+		}
+	}
+	respondUser(ctx, http.StatusOK, user.ID)
+}
+
+func replaceUser(ctx *gin.Context) {
+	user := getProvisionedUser(ctx)
+	if user == nil {
+		return
+	}
+	resource, err := userResource(ctx, user)
+	if err != nil {
+		abortError(ctx, http.StatusInternalServerError, "", "Failed to get user", err)
+		return
+	}
+	if preconditionFailed(ctx, resource.Meta.Version) {
+		return
+	}
+	req := &User{}
+	if !bindResource(ctx, req) {
+		return
+	}
+	saveUser(ctx, user, req)
+}
+
+func patchUser(ctx *gin.Context) {
+	user := getProvisionedUser(ctx)
+	if user == nil {
+		return
+	}
+	resource, err := userResource(ctx, user)
+	if err != nil {
+		abortError(ctx, http.StatusInternalServerError, "", "Failed to get user", err)
+		return
+	}
+	if preconditionFailed(ctx, resource.Meta.Version) {
+		return
+	}
+	object := patchResource(ctx, resource)
+	if object == nil {
+		return
+	}
+	req := &User{}
+	if err := fromMap(normalize(object), req); err != nil {
+		abortError(ctx, http.StatusBadRequest, "invalidValue", "Invalid patched user", err)
+		return
+	}
+	saveUser(ctx, user, req)
+}
+
+// patchResource applies the PATCH request to the JSON representation of resource.
+func patchResource(ctx *gin.Context, resource any) map[string]any {
+	req := &patchRequest{}
+	if err := json.NewDecoder(ctx.Request.Body).Decode(req); err != nil {
+		abortError(ctx, http.StatusBadRequest, "invalidSyntax", "Invalid request body", err)
+		return nil
+	}
+	object, err := toMap(resource)
+	if err != nil {
+		abortError(ctx, http.StatusInternalServerError, "", "Unexpected error", err)
+		return nil
+	}
+	if err := applyPatch(object, req.Operations); err != nil {
+		abortError(ctx, http.StatusBadRequest, scimType(err), "Failed to apply patch", err)
+		return nil
+	}
+	return object
+}
+
+// deleteUser deprovisions a user. Its sessions are ended first, the keys, consents, attributes and group
+// memberships are removed with it.
+func deleteUser(ctx *gin.Context) {
+	user := getProvisionedUser(ctx)
+	if user == nil {
+		return
+	}
+	resource, err := userResource(ctx, user)
+	if err != nil {
+		abortError(ctx, http.StatusInternalServerError, "", "Failed to get user", err)
+		return
+	}
+	if preconditionFailed(ctx, resource.Meta.Version) {
+		return
+	}
+	if err := token.EndUserSessions(ctx, user.ID); err != nil {
+		abortError(ctx, http.StatusInternalServerError, "", "Failed to end user sessions", err)
+		return
+	}
+	if err := userdb.DeleteUser(ctx, user.ID); err != nil {
+		abortError(ctx, http.StatusInternalServerError, "", "Failed to delete user", err)
+		return
+	}
+	ctx.Status(http.StatusNoContent)
+}
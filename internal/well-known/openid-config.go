@@ -4,7 +4,12 @@ import (
 	"fmt"
 	"log/slog"
 	"net/http"
+	"slices"
+	"uyulala/internal/api"
+	"uyulala/internal/api/token"
 	"uyulala/internal/db/keydb"
+	"uyulala/internal/db/scopedb"
+	"uyulala/internal/mtls"
 	"uyulala/openid/discovery"
 
 	"github.com/gin-gonic/gin"
@@ -26,19 +31,24 @@ func OpenIDConfigurationHandler(c *gin.Context) {
 		TokenEndpoint:                          fmt.Sprintf("%s/api/v1/collect", issuer),
 		JWKSURI:                                fmt.Sprintf("%s/api/v1/oidc/jwkset.json", issuer),
 		ResponseTypesSupported:                 []string{discovery.ResponseTypeCode},
-		GrantTypesSupported:                    []string{discovery.GrantTypeAuthorizationCode, discovery.GrantTypeCIBA},
-		ScopesSupported:                        []string{"openid", "offline_access"},
+		GrantTypesSupported:                    []string{discovery.GrantTypeAuthorizationCode, discovery.GrantTypeCIBA, discovery.GrantTypeClientCredentials, discovery.GrantTypeDeviceCode, discovery.GrantTypeImplicit},
+		ScopesSupported:                        append([]string{"openid", "offline_access"}, api.StandardScopes...),
 		BackChannelAuthenticationEndpoint:      fmt.Sprintf("%s/api/v1/sign", issuer),
 		BackChannelTokenDeliveryModesSupported: []string{"poll", "ping", "push"},
 		BackChannelAuthenticationQREndpoint:    fmt.Sprintf("%s/authenticator", issuer),
 	}
 	opt := &discovery.Optional{
-		ACRValuesSupported: []string{
-			discovery.ACRUserPresence,
-			discovery.ACRPreferUserVerification,
-			discovery.ACRUserVerification,
-		},
-		CodeChallengeMethodsSupported: []string{"plain", "S256"},
+		ACRValuesSupported:                    token.ACRValues,
+		CodeChallengeMethodsSupported:         []string{"plain", "S256"},
+		IntrospectionEndpoint:                 fmt.Sprintf("%s/api/v1/introspect", issuer),
+		RevocationEndpoint:                    fmt.Sprintf("%s/api/v1/revoke", issuer),
+		EndSessionEndpoint:                    fmt.Sprintf("%s/api/v1/logout", issuer),
+		DeviceAuthorizationEndpoint:           fmt.Sprintf("%s/api/v1/device_authorization", issuer),
+		PushedAuthorizationRequestEndpoint:    fmt.Sprintf("%s/api/v1/par", issuer),
+		RegistrationEndpoint:                  fmt.Sprintf("%s/api/v1/register", issuer),
+		BackChannelLogoutSupported:            true,
+		BackChannelLogoutSessionSupported:     true,
+		TLSClientCertificateBoundAccessTokens: viper.GetBool("tls.clientAuth"),
 	}
 	cfg := discovery.NewConfig(req, opt)
 	userinfoEndpoint := viper.GetString("userInfo.endpoint")
@@ -46,8 +56,38 @@ func OpenIDConfigurationHandler(c *gin.Context) {
 		userinfoEndpoint = fmt.Sprintf("%s/api/v1/oidc/userinfo", issuer)
 	}
 	cfg.UserInfoEndpoint = userinfoEndpoint
-	cfg.ResponseModesSupported = []string{discovery.ResponseModeQuery}
-	cfg.TokenEndpointAuthMethodsSupported = []string{discovery.TokenAuthClientSecretPost, discovery.TokenAuthClientSecretBasic}
+	cfg.ResponseModesSupported = api.ResponseModes
+	cfg.ResponseTypesSupported = api.ResponseTypes
+	cfg.AuthorizationResponseIssParameterSupported = true
+	cfg.AuthorizationDetailsTypesSupported = api.AuthorizationDetailsTypes()
+	cfg.SubjectTypesSupported = []string{discovery.SubjectTypePublic, discovery.SubjectTypePairwise}
+	cfg.TokenEndpointAuthMethodsSupported = []string{discovery.TokenAuthClientSecretPost, discovery.TokenAuthClientSecretBasic,
+		discovery.TokenAuthClientSecretJWT, discovery.TokenAuthPrivateKeyJWT}
+	if viper.GetBool("tls.clientAuth") {
+		cfg.TokenEndpointAuthMethodsSupported = append(cfg.TokenEndpointAuthMethodsSupported,
+			discovery.TokenAuthTLSClient, discovery.TokenAuthSelfSignedTLSClient)
+	}
+	if issuer := mtls.Issuer(); issuer != "" {
+		cfg.MTLSEndpointAliases = map[string]string{
+			"token_endpoint":                        fmt.Sprintf("%s/api/v1/collect", issuer),
+			"introspection_endpoint":                fmt.Sprintf("%s/api/v1/introspect", issuer),
+			"revocation_endpoint":                   fmt.Sprintf("%s/api/v1/revoke", issuer),
+			"device_authorization_endpoint":         fmt.Sprintf("%s/api/v1/device_authorization", issuer),
+			"pushed_authorization_request_endpoint": fmt.Sprintf("%s/api/v1/par", issuer),
+			"backchannel_authentication_endpoint":   fmt.Sprintf("%s/api/v1/sign", issuer),
+		}
+		if viper.GetString("userInfo.endpoint") == "" {
+			cfg.MTLSEndpointAliases["userinfo_endpoint"] = fmt.Sprintf("%s/api/v1/oidc/userinfo", issuer)
+		}
+	}
+	cfg.TokenEndpointAuthSigningAlgValuesSupported = token.ClientAssertionAlgorithms
+	cfg.RequestParameterSupported = true
+	cfg.DPoPSigningAlgValuesSupported = token.RequestObjectAlgorithms
+	cfg.RequestObjectSigningAlgValuesSupported = token.RequestObjectAlgorithms
+	cfg.IDTokenEncryptionAlgValuesSupported = token.EncryptionAlgorithms
+	cfg.IDTokenEncryptionEncValuesSupported = token.EncryptionEncodings
+	cfg.UserInfoEncryptionAlgValuesSupported = token.EncryptionAlgorithms
+	cfg.UserInfoEncryptionEncValuesSupported = token.EncryptionEncodings
 	algs, err := keydb.GetAvailableAlgorithms(c)
 	if err != nil {
 		_ = c.AbortWithError(http.StatusInternalServerError, err)
@@ -55,5 +95,27 @@ func OpenIDConfigurationHandler(c *gin.Context) {
 		return
 	}
 	cfg.IDTokenSigningAlgValuesSupported = algs
+	scopes, err := scopedb.List(c)
+	if err != nil {
+		_ = c.AbortWithError(http.StatusInternalServerError, err)
+		slog.Warn("Failed to list scopes", "err", err)
+		return
+	}
+	cfg.ClaimsParameterSupported = true
+	cfg.ClaimsSupported = []string{"sub"}
+	for _, scope := range api.StandardScopes {
+		cfg.ClaimsSupported = append(cfg.ClaimsSupported, api.StandardClaims[scope]...)
+	}
+	for _, scope := range scopes {
+		if !slices.Contains(cfg.ScopesSupported, scope.Name) {
+			cfg.ScopesSupported = append(cfg.ScopesSupported, scope.Name)
+		}
+		for _, claim := range scope.Claims {
+			if !slices.Contains(cfg.ClaimsSupported, claim) {
+				cfg.ClaimsSupported = append(cfg.ClaimsSupported, claim)
+			}
+		}
+	}
+	cfg.AuthorizationSigningAlgValuesSupported = algs
 	c.JSON(http.StatusOK, cfg)
 }
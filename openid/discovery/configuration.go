@@ -21,18 +21,27 @@ const (
 	GrantTypeCIBA              = "urn:openid:params:grant-type:ciba"
 	GrantTypeRefresh           = "refresh"
 	GrantTypeImplicit          = "implicit"
+	GrantTypeClientCredentials = "client_credentials"
+	GrantTypeDeviceCode        = "urn:ietf:params:oauth:grant-type:device_code"
 )
 
 const (
-	TokenAuthClientSecretBasic = "client_secret_basic"
-	TokenAuthClientSecretPost  = "client_secret_post"
-	TokenAuthClientSecretJWT   = "client_secret_jwt"
-	TokenAuthPrivateKeyJWT     = "private_key_jwt" //nolint:gosec
+	TokenAuthClientSecretBasic   = "client_secret_basic"
+	TokenAuthClientSecretPost    = "client_secret_post"
+	TokenAuthClientSecretJWT     = "client_secret_jwt"
+	TokenAuthPrivateKeyJWT       = "private_key_jwt" //nolint:gosec
+	TokenAuthTLSClient           = "tls_client_auth"
+	TokenAuthSelfSignedTLSClient = "self_signed_tls_client_auth"
 )
 
 const (
-	ResponseModeQuery    = "query"
-	ResponseModeFragment = "fragment"
+	ResponseModeQuery       = "query"
+	ResponseModeFragment    = "fragment"
+	ResponseModeFormPost    = "form_post"
+	ResponseModeJWT         = "jwt"
+	ResponseModeQueryJWT    = "query.jwt"
+	ResponseModeFragmentJWT = "fragment.jwt"
+	ResponseModeFormPostJWT = "form_post.jwt"
 )
 
 const (
@@ -226,6 +235,45 @@ type Optional struct {
 
 	// JSON array containing a list of PKCE code_challenge methods supported
 	CodeChallengeMethodsSupported []string `json:"code_challenge_methods_supported,omitempty"`
+
+	// URL of the OP's OAuth 2.0 Token Introspection Endpoint (RFC 7662).
+	IntrospectionEndpoint string `json:"introspection_endpoint,omitempty"`
+
+	// URL of the OP's OAuth 2.0 Token Revocation Endpoint (RFC 7009).
+	RevocationEndpoint string `json:"revocation_endpoint,omitempty"`
+
+	// URL at the OP to which an RP can perform a redirect to request that the End-User be logged out at the OP.
+	EndSessionEndpoint string `json:"end_session_endpoint,omitempty"`
+
+	// Boolean value specifying whether the OP supports back-channel logout, with true indicating support.
+	BackChannelLogoutSupported bool `json:"backchannel_logout_supported,omitempty"`
+
+	// Boolean value specifying whether the OP can pass a sid (session ID) Claim in the Logout Token to identify the RP session with the OP.
+	BackChannelLogoutSessionSupported bool `json:"backchannel_logout_session_supported,omitempty"`
+
+	// URL of the OP's OAuth 2.0 Device Authorization Endpoint (RFC 8628).
+	DeviceAuthorizationEndpoint string `json:"device_authorization_endpoint,omitempty"`
+
+	// URL of the OP's Pushed Authorization Request Endpoint (RFC 9126).
+	PushedAuthorizationRequestEndpoint string `json:"pushed_authorization_request_endpoint,omitempty"`
+
+	// Whether the OP supports mutual-TLS client certificate-bound access tokens (RFC 8705).
+	TLSClientCertificateBoundAccessTokens bool `json:"tls_client_certificate_bound_access_tokens,omitempty"`
+
+	// Endpoints on the host accepting mutual-TLS connections, keyed by their metadata name (RFC 8705 section 5).
+	MTLSEndpointAliases map[string]string `json:"mtls_endpoint_aliases,omitempty"`
+
+	// JSON array containing a list of the JWS alg values supported for DPoP proof JWTs (RFC 9449).
+	DPoPSigningAlgValuesSupported []string `json:"dpop_signing_alg_values_supported,omitempty"`
+
+	// Whether the OP includes the iss parameter in authorization responses (RFC 9207).
+	AuthorizationResponseIssParameterSupported bool `json:"authorization_response_iss_parameter_supported,omitempty"`
+
+	// JSON array containing a list of the JWS alg values supported for signing JWT-secured authorization responses (JARM).
+	AuthorizationSigningAlgValuesSupported []string `json:"authorization_signing_alg_values_supported,omitempty"`
+
+	// JSON array containing the authorization details types the OP supports (RFC 9396).
+	AuthorizationDetailsTypesSupported []string `json:"authorization_details_types_supported,omitempty"`
 }
 
 type Full struct {
@@ -389,6 +437,45 @@ type Full struct {
 
 	// JSON array containing a list of PKCE code_challenge methods supported
 	CodeChallengeMethodsSupported []string `json:"code_challenge_methods_supported,omitempty"`
+
+	// URL of the OP's OAuth 2.0 Token Introspection Endpoint (RFC 7662).
+	IntrospectionEndpoint string `json:"introspection_endpoint,omitempty"`
+
+	// URL of the OP's OAuth 2.0 Token Revocation Endpoint (RFC 7009).
+	RevocationEndpoint string `json:"revocation_endpoint,omitempty"`
+
+	// URL at the OP to which an RP can perform a redirect to request that the End-User be logged out at the OP.
+	EndSessionEndpoint string `json:"end_session_endpoint,omitempty"`
+
+	// Boolean value specifying whether the OP supports back-channel logout, with true indicating support.
+	BackChannelLogoutSupported bool `json:"backchannel_logout_supported,omitempty"`
+
+	// Boolean value specifying whether the OP can pass a sid (session ID) Claim in the Logout Token to identify the RP session with the OP.
+	BackChannelLogoutSessionSupported bool `json:"backchannel_logout_session_supported,omitempty"`
+
+	// URL of the OP's OAuth 2.0 Device Authorization Endpoint (RFC 8628).
+	DeviceAuthorizationEndpoint string `json:"device_authorization_endpoint,omitempty"`
+
+	// URL of the OP's Pushed Authorization Request Endpoint (RFC 9126).
+	PushedAuthorizationRequestEndpoint string `json:"pushed_authorization_request_endpoint,omitempty"`
+
+	// Whether the OP supports mutual-TLS client certificate-bound access tokens (RFC 8705).
+	TLSClientCertificateBoundAccessTokens bool `json:"tls_client_certificate_bound_access_tokens,omitempty"`
+
+	// Endpoints on the host accepting mutual-TLS connections, keyed by their metadata name (RFC 8705 section 5).
+	MTLSEndpointAliases map[string]string `json:"mtls_endpoint_aliases,omitempty"`
+
+	// JSON array containing a list of the JWS alg values supported for DPoP proof JWTs (RFC 9449).
+	DPoPSigningAlgValuesSupported []string `json:"dpop_signing_alg_values_supported,omitempty"`
+
+	// Whether the OP includes the iss parameter in authorization responses (RFC 9207).
+	AuthorizationResponseIssParameterSupported bool `json:"authorization_response_iss_parameter_supported,omitempty"`
+
+	// JSON array containing a list of the JWS alg values supported for signing JWT-secured authorization responses (JARM).
+	AuthorizationSigningAlgValuesSupported []string `json:"authorization_signing_alg_values_supported,omitempty"`
+
+	// JSON array containing the authorization details types the OP supports (RFC 9396).
+	AuthorizationDetailsTypesSupported []string `json:"authorization_details_types_supported,omitempty"`
 }
 
 func (f *Full) AddSupportedIDTokenSigningAlg(alg string) {